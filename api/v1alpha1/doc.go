@@ -0,0 +1,25 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 contains API Schema definitions for the security v1alpha1 API group.
+//
+// The markers are read from this file by client-gen, which ignores the
+// comments of the other files of the package.
+//
+// +kubebuilder:object:generate=true
+// +groupName=podlock.kubewarden.io
+// +groupGoName=Podlock
+package v1alpha1
@@ -32,6 +32,110 @@ type ProfileByBinary map[string]Profile
 type LandlockProfileSpec struct {
 	// +optional
 	ProfilesByContainer map[string]ProfileByBinary `json:"profilesByContainer,omitempty"`
+
+	// rollout configures how the workloads using the profile are restarted
+	// when the profile changes. By default they are not restarted.
+	// +optional
+	Rollout *Rollout `json:"rollout,omitempty"`
+
+	// revisionHistoryLimit is the number of LandlockProfileRevisions kept,
+	// including the current one. The revisions pinned by pods are never
+	// removed.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=10
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// updateStrategy defines how the changes of the profiles are delivered
+	// to the new pods. By default all the new pods use the current profiles.
+	// +optional
+	UpdateStrategy *UpdateStrategy `json:"updateStrategy,omitempty"`
+}
+
+// Rollout configures the restart of the workloads whose pods run an outdated
+// version of the profile.
+type Rollout struct {
+	// automatic enables the rolling restart of the Deployments, StatefulSets
+	// and DaemonSets owning pods that run an outdated version of the profile.
+	// Pods not owned by any of these workloads are never restarted.
+	// +optional
+	Automatic bool `json:"automatic,omitempty"`
+
+	// maxConcurrent is the maximum number of workloads restarted at the
+	// same time.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	MaxConcurrent int32 `json:"maxConcurrent,omitempty"`
+}
+
+// UpdateStrategyType is the strategy used to deliver the changes of the
+// profiles to the pods.
+// +kubebuilder:validation:Enum=Immediate;Canary
+type UpdateStrategyType string
+
+const (
+	// ImmediateUpdateStrategyType makes all the new pods use the current
+	// profiles.
+	ImmediateUpdateStrategyType UpdateStrategyType = "Immediate"
+
+	// CanaryUpdateStrategyType makes only the pods selected for the canary
+	// use the current profiles, the other ones keep using the stable
+	// revision until the canary is promoted.
+	CanaryUpdateStrategyType UpdateStrategyType = "Canary"
+)
+
+// UpdateStrategy defines how the changes of the profiles are delivered to the
+// new pods.
+type UpdateStrategy struct {
+	// type is the update strategy, either "Immediate" or "Canary".
+	// +kubebuilder:default=Immediate
+	// +optional
+	Type UpdateStrategyType `json:"type,omitempty"`
+
+	// canary configures the Canary update strategy. It is required when
+	// type is "Canary".
+	// +optional
+	Canary *CanaryStrategy `json:"canary,omitempty"`
+}
+
+// CanaryStrategy selects the pods testing a new revision of the profiles and
+// defines when the new revision is promoted or rolled back.
+//
+// A pod is selected for the canary when it matches the selector, or when it
+// falls into the percentage of pods. The choice is based on the UID of the
+// pod, hence it does not change when the pod is restarted in place.
+type CanaryStrategy struct {
+	// percentage is the percentage of the new pods using the new revision.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	Percentage int32 `json:"percentage,omitempty"`
+
+	// selector selects the pods using the new revision, regardless of the
+	// percentage.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// minReadyPods is the number of canary pods that must be ready before
+	// the new revision is promoted.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	MinReadyPods int32 `json:"minReadyPods,omitempty"`
+
+	// analysisPeriod is the minimum time the new revision is tested before
+	// being promoted.
+	// +kubebuilder:default="5m"
+	// +optional
+	AnalysisPeriod *metav1.Duration `json:"analysisPeriod,omitempty"`
+
+	// progressDeadline is the maximum time given to the canary pods to
+	// become ready. The new revision is rolled back when not enough canary
+	// pods are ready by then.
+	// +kubebuilder:default="15m"
+	// +optional
+	ProgressDeadline *metav1.Duration `json:"progressDeadline,omitempty"`
 }
 
 type Profile struct {
@@ -39,23 +143,209 @@ type Profile struct {
 	ReadWrite     []string `json:"readWrite,omitempty"`
 	ReadExec      []string `json:"readExec,omitempty"`
 	ReadWriteExec []string `json:"readWriteExec,omitempty"`
+
+	// sha256 is the expected hex encoded sha256 digest of the binary.
+	// When set, the binary is not started if its digest does not match.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[a-f0-9]{64}$`
+	SHA256 string `json:"sha256,omitempty"`
+
+	// capabilities defines the Linux capabilities to remove from the process
+	// before the binary is started.
+	// +optional
+	Capabilities *Capabilities `json:"capabilities,omitempty"`
+
+	// rlimits defines the resource limits to set on the process before the
+	// binary is started.
+	// +optional
+	Rlimits []Rlimit `json:"rlimits,omitempty"`
+
+	// seccomp defines the seccomp filter to load before the binary is started.
+	// +optional
+	Seccomp *Seccomp `json:"seccomp,omitempty"`
+
+	// environment defines the environment variables passed to the binary.
+	// +optional
+	Environment *EnvironmentPolicy `json:"environment,omitempty"`
+}
+
+// Capabilities defines how the Linux capabilities of a process are reduced.
+type Capabilities struct {
+	// drop is the list of capabilities removed from the bounding, effective,
+	// permitted, inheritable and ambient sets. Capability names can be specified
+	// with or without the "CAP_" prefix. The special value "ALL" drops all
+	// the capabilities.
+	// +optional
+	Drop []string `json:"drop,omitempty"`
+
+	// clearAmbient removes all the capabilities from the ambient set.
+	// +optional
+	ClearAmbient bool `json:"clearAmbient,omitempty"`
+}
+
+// RlimitUnlimited is the value of a resource limit meaning no limit, i.e.
+// RLIM_INFINITY.
+const RlimitUnlimited int64 = -1
+
+// Rlimit defines a resource limit.
+type Rlimit struct {
+	// type of the resource limit, e.g. RLIMIT_NOFILE.
+	// +kubebuilder:validation:Enum=RLIMIT_AS;RLIMIT_CORE;RLIMIT_CPU;RLIMIT_DATA;RLIMIT_FSIZE;RLIMIT_MEMLOCK;RLIMIT_NOFILE;RLIMIT_NPROC;RLIMIT_STACK
+	Type string `json:"type"`
+
+	// soft is the value of the soft limit, -1 means unlimited.
+	// +kubebuilder:validation:Minimum=-1
+	Soft int64 `json:"soft"`
+
+	// hard is the value of the hard limit, -1 means unlimited.
+	// +kubebuilder:validation:Minimum=-1
+	Hard int64 `json:"hard"`
+}
+
+// Seccomp defines the system calls a binary is allowed to perform.
+// The allow list cannot be used together with the preset and the deny list.
+type Seccomp struct {
+	// preset is the name of a predefined list of system calls to deny.
+	// The "baseline" preset denies system calls that are not needed by
+	// regular workloads, like ptrace, bpf, keyctl or mount.
+	// +kubebuilder:validation:Enum=baseline
+	// +optional
+	Preset string `json:"preset,omitempty"`
+
+	// allow is the list of the only system calls the binary is allowed to
+	// perform. All the other system calls are denied.
+	// +optional
+	Allow []string `json:"allow,omitempty"`
+
+	// deny is the list of system calls the binary is not allowed to perform.
+	// All the other system calls are allowed.
+	// +optional
+	Deny []string `json:"deny,omitempty"`
+
+	// action is taken when a denied system call is performed. "errno" makes
+	// the system call fail with EPERM, "kill" terminates the process.
+	// Defaults to "errno".
+	// +kubebuilder:validation:Enum=errno;kill
+	// +optional
+	Action string `json:"action,omitempty"`
+}
+
+// EnvironmentPolicy defines how the environment of a binary is filtered.
+// Names ending with "*" match all the environment variables starting with
+// the given prefix.
+type EnvironmentPolicy struct {
+	// allow is the list of the only environment variables passed to the
+	// binary. When empty, all the environment variables are allowed.
+	// +optional
+	Allow []string `json:"allow,omitempty"`
+
+	// deny is the list of environment variables removed from the environment
+	// of the binary, e.g. LD_PRELOAD.
+	// +optional
+	Deny []string `json:"deny,omitempty"`
+
+	// set defines environment variables that are always passed to the binary
+	// with the given values. They are set after allow and deny are evaluated.
+	// +optional
+	Set map[string]string `json:"set,omitempty"`
+}
+
+// Condition types of LandlockProfile.
+const (
+	// LandlockProfileConditionValid reports whether the spec of the profile
+	// passes validation.
+	LandlockProfileConditionValid = "Valid"
+
+	// LandlockProfileConditionInUse reports whether the profile is used by
+	// at least one pod.
+	LandlockProfileConditionInUse = "InUse"
+
+	// LandlockProfileConditionDeletionBlocked reports whether the deletion of
+	// the profile is blocked by the pods still using it.
+	LandlockProfileConditionDeletionBlocked = "DeletionBlocked"
+
+	// LandlockProfileConditionDrifted reports whether some pods are running
+	// an outdated version of the profile.
+	LandlockProfileConditionDrifted = "Drifted"
+
+	// LandlockProfileConditionCanary reports whether a new revision of the
+	// profile is being tested by the Canary update strategy, and the outcome
+	// of the last canary.
+	LandlockProfileConditionCanary = "Canary"
+)
+
+// CanaryStatus reports the progress of the canary of a revision.
+type CanaryStatus struct {
+	// revision is the revision being tested.
+	// +required
+	Revision int64 `json:"revision"`
+
+	// startTime is the time the canary started.
+	// +required
+	StartTime metav1.Time `json:"startTime"`
+
+	// pods is the number of pods using the revision being tested.
+	// +optional
+	Pods int32 `json:"pods,omitempty"`
+
+	// readyPods is the number of ready pods using the revision being tested.
+	// +optional
+	ReadyPods int32 `json:"readyPods,omitempty"`
 }
 
 // LandlockProfileStatus defines the observed state of LandlockProfile.
 type LandlockProfileStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// observedGeneration is the generation of the spec the status refers to.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 
-	// For Kubernetes API conventions, see:
-	// https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md#typical-status-properties
+	// currentRevision is the number of the LandlockProfileRevision matching
+	// the current profiles.
+	// +optional
+	CurrentRevision int64 `json:"currentRevision,omitempty"`
+
+	// stableRevision is the revision used by the pods not selected for the
+	// canary when the Canary update strategy is used. It matches the current
+	// revision once the canary is promoted.
+	// +optional
+	StableRevision int64 `json:"stableRevision,omitempty"`
+
+	// canary reports the progress of the canary of the current revision.
+	// It is not set when no canary is in progress.
+	// +optional
+	Canary *CanaryStatus `json:"canary,omitempty"`
+
+	// podCount is the number of pods using the profile.
+	// +optional
+	PodCount int32 `json:"podCount,omitempty"`
+
+	// pods is a sample of the names of the pods using the profile, sorted
+	// alphabetically.
+	// +kubebuilder:validation:MaxItems=10
+	// +optional
+	Pods []string `json:"pods,omitempty"`
+
+	// stalePods is a sample of the names of the pods running an outdated
+	// version of the profile, sorted alphabetically. These pods must be
+	// restarted to be restricted by the current version of the profile.
+	// +kubebuilder:validation:MaxItems=10
+	// +optional
+	StalePods []string `json:"stalePods,omitempty"`
+
+	// minimumLandlockABI is the minimum version of the Landlock ABI the node
+	// kernel must support to enforce the profile.
+	// +optional
+	MinimumLandlockABI int32 `json:"minimumLandlockABI,omitempty"`
 
 	// conditions represent the current state of the LandlockProfile resource.
 	// Each condition has a unique type and reflects the status of a specific aspect of the resource.
 	//
-	// Standard condition types include:
-	// - "Available": the resource is fully functional
-	// - "Progressing": the resource is being created or updated
-	// - "Degraded": the resource failed to reach or maintain its desired state
+	// The condition types are:
+	// - "Valid": the spec of the profile passes validation
+	// - "InUse": the profile is used by at least one pod
+	// - "DeletionBlocked": the profile is being deleted, but it is still used by pods
+	// - "Drifted": some pods are running an outdated version of the profile
+	// - "Canary": a new revision of the profile is being tested by a canary
 	//
 	// The status of each condition is one of True, False, or Unknown.
 	// +listType=map
@@ -64,8 +354,17 @@ type LandlockProfileStatus struct {
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// +genclient
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Valid",type=string,JSONPath=`.status.conditions[?(@.type=="Valid")].status`
+// +kubebuilder:printcolumn:name="Revision",type=integer,JSONPath=`.status.currentRevision`
+// +kubebuilder:printcolumn:name="Pods",type=integer,JSONPath=`.status.podCount`
+// +kubebuilder:printcolumn:name="Stable",type=integer,JSONPath=`.status.stableRevision`,priority=1
+// +kubebuilder:printcolumn:name="Drifted",type=string,JSONPath=`.status.conditions[?(@.type=="Drifted")].status`
+// +kubebuilder:printcolumn:name="Min ABI",type=integer,JSONPath=`.status.minimumLandlockABI`
+// +kubebuilder:printcolumn:name="Deletion Blocked",type=string,JSONPath=`.status.conditions[?(@.type=="DeletionBlocked")].status`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // LandlockProfile is the Schema for the landlockprofiles API
 type LandlockProfile struct {
@@ -93,7 +392,3 @@ type LandlockProfileList struct {
 
 	Items []LandlockProfile `json:"items"`
 }
-
-func init() {
-	SchemeBuilder.Register(&LandlockProfile{}, &LandlockProfileList{})
-}
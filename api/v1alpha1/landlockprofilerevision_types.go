@@ -0,0 +1,59 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Profile",type=string,JSONPath=`.metadata.labels.podlock\.kubewarden\.io/landlock-profile`
+// +kubebuilder:printcolumn:name="Revision",type=integer,JSONPath=`.revision`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// LandlockProfileRevision is an immutable snapshot of the profiles of a
+// LandlockProfile. A revision is created by the controller each time the
+// profiles of the LandlockProfile change. Its name is "<profile>-<revision>".
+type LandlockProfileRevision struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitzero"`
+
+	// revision is the number of the revision. It is increased each time the
+	// profiles of the LandlockProfile change.
+	// +kubebuilder:validation:Minimum=1
+	// +required
+	Revision int64 `json:"revision"`
+
+	// profilesByContainer is a copy of the profilesByContainer field of the
+	// LandlockProfile.
+	// +optional
+	ProfilesByContainer map[string]ProfileByBinary `json:"profilesByContainer,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// LandlockProfileRevisionList contains a list of LandlockProfileRevision
+type LandlockProfileRevisionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitzero"`
+
+	Items []LandlockProfileRevision `json:"items"`
+}
@@ -179,8 +179,10 @@ func main() {
 	}
 
 	if err = (&controller.LandlockProfileReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorder("podlock-controller"),
+		APIReader: mgr.GetAPIReader(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "LandlockProfile")
 		os.Exit(1)
@@ -191,6 +193,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = webhookv1alpha1.SetupLandlockProfileRevisionWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "LandlockProfileRevision")
+		os.Exit(1)
+	}
+
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+// informerRetryInterval is the delay between two attempts to create the
+// informers, which fails while the API server is unreachable.
+const informerRetryInterval = 5 * time.Second
+
+// cacheSyncTracker reports whether the informers of the objects read by the
+// NRI plugin are synced.
+type cacheSyncTracker struct {
+	synced atomic.Bool
+}
+
+func (t *cacheSyncTracker) Synced() bool {
+	return t.synced.Load()
+}
+
+// trackCacheSync creates the informers of the objects read by the NRI plugin
+// in the background, since the API server may be unreachable when the plugin
+// starts, then waits for them to be synced.
+func trackCacheSync(ctx context.Context, k8sCache cache.Cache, logger *slog.Logger) *cacheSyncTracker {
+	tracker := &cacheSyncTracker{}
+
+	go func() {
+		objects := []client.Object{
+			&podlockv1alpha1.LandlockProfile{},
+			&podlockv1alpha1.LandlockProfileRevision{},
+			&v1.Namespace{},
+		}
+
+		informers := make([]cache.Informer, 0, len(objects))
+		for _, obj := range objects {
+			err := wait.PollUntilContextCancel(ctx, informerRetryInterval, true, func(ctx context.Context) (bool, error) {
+				informer, err := k8sCache.GetInformer(ctx, obj, cache.BlockUntilSynced(false))
+				if err != nil {
+					logger.WarnContext(ctx, "failed to create informer, retrying", slog.Any("err", err))
+					return false, nil
+				}
+				informers = append(informers, informer)
+				return true, nil
+			})
+			if err != nil {
+				return
+			}
+		}
+
+		err := wait.PollUntilContextCancel(ctx, time.Second, true, func(context.Context) (bool, error) {
+			for _, informer := range informers {
+				if !informer.HasSynced() {
+					return false, nil
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			return
+		}
+
+		tracker.synced.Store(true)
+		logger.InfoContext(ctx, "cache synced with the API server")
+	}()
+
+	return tracker
+}
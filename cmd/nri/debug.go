@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/flavio/podlock/internal/nri"
+)
+
+// debugCommandName is the name of the subcommand querying the debug API.
+const debugCommandName = "debug"
+
+// debugRequestTimeout is the timeout of the requests to the debug API.
+const debugRequestTimeout = 10 * time.Second
+
+// runDebugCommand runs the debug subcommand, returning its exit code.
+func runDebugCommand(args []string, stdout, stderr io.Writer) int {
+	var (
+		socket string
+		podID  string
+		output string
+		config bool
+	)
+
+	flagSet := flag.NewFlagSet(debugCommandName, flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	flagSet.Usage = func() {
+		fmt.Fprintf(flagSet.Output(), `Usage: nri debug [options]
+
+List the pods and the containers sealed by the PodLock NRI plugin running on
+this node, or its active configuration, by querying its debug API.
+
+Options:
+`)
+		flagSet.PrintDefaults()
+	}
+	flagSet.StringVar(&socket, "socket", nri.PodLockDebugSocket, "The unix socket the debug API is served on.")
+	flagSet.StringVar(&podID, "pod", "", "Show only the pod with the given ID.")
+	flagSet.StringVar(&output, "output", "table",
+		"Output format: table or json. The json output includes the profiles of the containers.")
+	flagSet.BoolVar(&config, "config", false, "Show the active configuration of the plugin, as JSON, instead of the pods.")
+	if err := flagSet.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if output != "table" && output != "json" {
+		fmt.Fprintf(stderr, "invalid output format '%s', must be table or json\n", output)
+		return 2
+	}
+
+	if config {
+		var cfg nri.Config
+		if err := queryDebugAPI(socket, "/config", &cfg); err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return 1
+		}
+		if err := printJSON(stdout, cfg); err != nil {
+			fmt.Fprintf(stderr, "failed to print the configuration: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	pods, err := queryDebugPods(socket, podID)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+
+	if output == "json" {
+		err = printJSON(stdout, pods)
+	} else {
+		err = printDebugTable(stdout, pods)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to print the pods: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// queryDebugPods returns the pods listed by the debug API, or the given pod
+// only when podID is not empty.
+func queryDebugPods(socket, podID string) ([]nri.DebugPod, error) {
+	if podID != "" {
+		var pod nri.DebugPod
+		if err := queryDebugAPI(socket, "/pods/"+url.PathEscape(podID), &pod); err != nil {
+			return nil, err
+		}
+		return []nri.DebugPod{pod}, nil
+	}
+
+	var pods []nri.DebugPod
+	if err := queryDebugAPI(socket, "/pods", &pods); err != nil {
+		return nil, err
+	}
+	return pods, nil
+}
+
+// queryDebugAPI gets the path from the debug API, decoding the response into
+// value.
+func queryDebugAPI(socket, path string, value any) error {
+	httpClient := &http.Client{
+		Timeout: debugRequestTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), debugRequestTimeout)
+	defer cancel()
+	// The host is ignored, the requests are sent over the socket
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://podlock"+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query the debug API on '%s': %w", socket, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("debug API returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(value); err != nil {
+		return fmt.Errorf("failed to decode the response of '%s': %w", path, err)
+	}
+	return nil
+}
+
+func printJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// printDebugTable prints one row per container. The containers without
+// profile are listed too, e.g. the ones whose files are about to be
+// garbage collected.
+func printDebugTable(w io.Writer, pods []nri.DebugPod) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "POD ID\tNAMESPACE\tPOD\tLIVE\tCONTAINER\tINSTANCES\tPROFILE\tGENERATION\tREVISION\tHASH\tBINARIES\tHOOK")
+
+	for _, pod := range pods {
+		for _, ctr := range pod.Containers {
+			profile, generation, revision, hash := "-", "-", "-", "-"
+			if ctr.Profile != nil {
+				profile = ctr.Profile.Source.Name
+				generation = strconv.FormatInt(ctr.Profile.Generation, 10)
+				if ctr.Profile.Revision > 0 {
+					revision = strconv.FormatInt(ctr.Profile.Revision, 10)
+				}
+				hash = shorten(ctr.ProfileHash)
+			}
+			binaries := "-"
+			if len(ctr.SwappedBinaries) > 0 {
+				binaries = strings.Join(ctr.SwappedBinaries, ",")
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				shorten(pod.ID), valueOrDash(pod.Namespace), valueOrDash(pod.Name), pod.Live,
+				ctr.Name, len(ctr.Instances), profile, generation, revision, hash, binaries, ctr.HookStatus)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// shorten truncates the IDs and the hashes, like the container runtimes do.
+func shorten(value string) string {
+	const length = 12
+	if len(value) > length {
+		return value[:length]
+	}
+	return value
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
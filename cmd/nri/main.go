@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -18,9 +21,12 @@ import (
 
 	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
 	"github.com/flavio/podlock/internal/cmdutil"
+	"github.com/flavio/podlock/internal/nri"
 )
 
-func setupLogger(logLevel string) *slog.Logger {
+// setupLogger initializes the logger, its level can be changed later on
+// through the returned variable.
+func setupLogger(logLevel string) (*slog.Logger, *slog.LevelVar) {
 	slogLevel, err := cmdutil.ParseLogLevel(logLevel)
 	if err != nil {
 		//nolint:sloglint // Use the global logger since the logger is not yet initialized
@@ -31,8 +37,10 @@ func setupLogger(logLevel string) *slog.Logger {
 		)
 		os.Exit(1)
 	}
+	level := &slog.LevelVar{}
+	level.Set(slogLevel)
 	opts := slog.HandlerOptions{
-		Level: slogLevel,
+		Level: level,
 	}
 	slogHandler := slog.NewJSONHandler(os.Stdout, &opts)
 	slogger := slog.New(slogHandler).With("component", "nri")
@@ -40,29 +48,30 @@ func setupLogger(logLevel string) *slog.Logger {
 	logger := logr.FromSlogHandler(slogHandler).WithValues("component", "nri")
 	ctrl.SetLogger(logger)
 
-	return slogger
+	return slogger, level
 }
 
-func setupKubeClient(ctx context.Context, logger slog.Logger) (client.Client, error) {
+func setupKubeClient(ctx context.Context, restConfig *rest.Config, logger slog.Logger) (client.Client, cache.Cache, error) {
 	scheme := runtime.NewScheme()
 	err := podlockv1alpha1.AddToScheme(scheme)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add podlockv1alpha1 to scheme: %w", err)
+		return nil, nil, fmt.Errorf("failed to add podlockv1alpha1 to scheme: %w", err)
 	}
 
 	// register core types:
 	if err = v1.AddToScheme(scheme); err != nil {
-		return nil, fmt.Errorf("failed to add corev1 to scheme: %w", err)
+		return nil, nil, fmt.Errorf("failed to add corev1 to scheme: %w", err)
+	}
+	if err = eventsv1.AddToScheme(scheme); err != nil {
+		return nil, nil, fmt.Errorf("failed to add eventsv1 to scheme: %w", err)
 	}
-
-	restConfig := config.GetConfigOrDie()
 
 	// Create and start the cache
 	k8sCache, err := cache.New(restConfig, cache.Options{
 		Scheme: scheme,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create cache: %w", err)
+		return nil, nil, fmt.Errorf("failed to create cache: %w", err)
 	}
 
 	go func() {
@@ -73,7 +82,7 @@ func setupKubeClient(ctx context.Context, logger slog.Logger) (client.Client, er
 	}()
 	// Wait for cache to sync
 	if synced := k8sCache.WaitForCacheSync(ctx); !synced {
-		return nil, errors.New("cache did not sync")
+		return nil, nil, errors.New("cache did not sync")
 	}
 
 	// Use the cache as the kubeClient's reader
@@ -82,33 +91,90 @@ func setupKubeClient(ctx context.Context, logger slog.Logger) (client.Client, er
 		Cache:  &client.CacheOptions{Reader: k8sCache},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("cannot create kube client: %w", err)
+		return nil, nil, fmt.Errorf("cannot create kube client: %w", err)
 	}
 
-	return kubeClient, nil
+	return kubeClient, k8sCache, nil
 }
 
 func main() {
+	// The debug subcommand queries the debug API of the plugin running on
+	// the node, e.g. through "kubectl exec"
+	if len(os.Args) > 1 && os.Args[1] == debugCommandName {
+		os.Exit(runDebugCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	var (
 		pluginName string
 		pluginIdx  string
 		err        error
-		logLevel   string
 		initMode   bool
+		gcInterval time.Duration
+		probeAddr  string
+		configPath string
+
+		failurePolicy          string
+		swapVerificationPolicy string
 	)
+	// The configuration file overrides the flags
+	cfg := nri.DefaultConfig()
 
 	flag.StringVar(&pluginName, "name", "", "plugin name to register to NRI")
 	flag.StringVar(&pluginIdx, "idx", "", "plugin index to register to NRI")
-	flag.StringVar(&logLevel, "log-level", slog.LevelInfo.String(), "Log level.")
+	flag.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "Log level.")
 	flag.BoolVar(&initMode, "init-mode", false, "Run in init mode to detect kernel features.")
+	flag.DurationVar(&gcInterval, "gc-interval", nri.DefaultGCInterval, "Interval between two sweeps of the stale runtime files.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&configPath, "config", "",
+		"Path to the YAML configuration file, reloaded when it changes. Its settings override the flags.")
+	flag.StringVar(&cfg.HostPaths.DebugSocket, "debug-socket", cfg.HostPaths.DebugSocket,
+		"The unix socket the debug API is served on. Leave empty to disable the debug API.")
+	flag.StringVar(&cfg.Metrics.BindAddress, "metrics-bind-address", cfg.Metrics.BindAddress,
+		"The address the metrics endpoint binds to. "+
+			"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
+	flag.BoolVar(&cfg.Metrics.Secure, "metrics-secure", cfg.Metrics.Secure,
+		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
+	flag.StringVar(&cfg.Metrics.CertPath, "metrics-cert-path", cfg.Metrics.CertPath,
+		"The directory that contains the metrics server certificate.")
+	flag.StringVar(&cfg.Metrics.CertName, "metrics-cert-name", cfg.Metrics.CertName,
+		"The name of the metrics server certificate file.")
+	flag.StringVar(&cfg.Metrics.CertKey, "metrics-cert-key", cfg.Metrics.CertKey, "The name of the metrics server key file.")
+	flag.BoolVar(&cfg.Metrics.EnableHTTP2, "enable-http2", cfg.Metrics.EnableHTTP2,
+		"If set, HTTP/2 will be enabled for the metrics server")
+	flag.StringVar(&failurePolicy, "failure-policy", string(cfg.FailurePolicy),
+		"What happens to a container whose LandlockProfile cannot be retrieved: Deny, Allow or Retry.")
+	flag.DurationVar(&cfg.FailureRetryTimeout.Duration, "failure-retry-timeout", cfg.FailureRetryTimeout.Duration,
+		"How long to retry retrieving the LandlockProfile with the Retry failure policy.")
+	flag.StringVar(&swapVerificationPolicy, "swap-verification-policy", string(cfg.SwapVerificationPolicy),
+		"What happens to a container whose binaries have not been swapped with seal: Deny, Warn or Disabled.")
+	flag.BoolVar(&cfg.RecordAppliedProfiles, "record-applied-profiles", cfg.RecordAppliedProfiles,
+		"Annotate the pods with the profiles applied to their containers, which requires the permission to patch the pods.")
 	flag.Parse()
 
-	logger := setupLogger(logLevel)
+	cfg.FailurePolicy = nri.FailurePolicy(failurePolicy)
+	cfg.SwapVerificationPolicy = nri.SwapVerificationPolicy(swapVerificationPolicy)
+	// seal logs like the plugin, unless configured otherwise
+	cfg.SealLogLevel = cfg.LogLevel
+
+	logger, level := setupLogger(cfg.LogLevel)
 	logger.Info("Starting NRI plugin")
 
+	if err = cfg.Validate(); err != nil {
+		logger.Error("invalid flags", slog.Any("err", err))
+		os.Exit(1)
+	}
+	base := cfg
+	if configPath != "" {
+		if cfg, err = nri.LoadConfig(configPath, base); err != nil {
+			logger.Error("failed to load configuration", slog.Any("err", err))
+			os.Exit(1)
+		}
+	}
+
 	ctx := context.Background()
+	restConfig := config.GetConfigOrDie()
 
-	kubeClient, err := setupKubeClient(ctx, *logger)
+	kubeClient, k8sCache, err := setupKubeClient(ctx, restConfig, *logger)
 	if err != nil {
 		logger.Error("failed to setup Kubernetes client", slog.Any("err", err))
 		os.Exit(1)
@@ -117,6 +183,15 @@ func main() {
 	if initMode {
 		startInitMode(ctx, kubeClient, logger)
 	} else {
-		startPluginMode(ctx, kubeClient, logger, pluginName, pluginIdx, logLevel)
+		startPluginMode(ctx, restConfig, kubeClient, k8sCache, logger, pluginOptions{
+			name:       pluginName,
+			idx:        pluginIdx,
+			gcInterval: gcInterval,
+			probeAddr:  probeAddr,
+			level:      level,
+			config:     cfg,
+			baseConfig: base,
+			configPath: configPath,
+		})
 	}
 }
@@ -6,21 +6,61 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/containerd/nri/pkg/stub"
 	"github.com/flavio/podlock/internal/nri"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// pluginOptions configures the NRI plugin mode.
+type pluginOptions struct {
+	name       string
+	idx        string
+	gcInterval time.Duration
+	probeAddr  string
+	level      *slog.LevelVar
+
+	// config is the configuration loaded when the plugin starts, baseConfig
+	// the one given by the flags, which configPath overrides
+	config     nri.Config
+	baseConfig nri.Config
+	configPath string
+}
+
 // startPluginMode runs the NRI plugin mode.
-func startPluginMode(ctx context.Context, client client.Client, logger *slog.Logger, pluginName, pluginIdx, logLevel string) {
+func startPluginMode(
+	ctx context.Context,
+	restConfig *rest.Config,
+	client client.Client,
+	k8sCache cache.Cache,
+	logger *slog.Logger,
+	options pluginOptions,
+) {
 	plugin := &nri.Plugin{
-		LogLevel: logLevel,
-		Logger:   logger,
-		Client:   client,
+		LogLevel:    options.config.LogLevel,
+		Logger:      logger,
+		Client:      client,
+		NodeName:    os.Getenv(NodeNameEvar),
+		RunDir:      options.config.HostPaths.RunDir,
+		Level:       options.level,
+		CacheSynced: trackCacheSync(ctx, k8sCache, logger).Synced,
 	}
+	plugin.ApplyConfig(options.config)
 	var err error
 
+	// The plugin can run without offline cache, it is needed only when the
+	// API server is unreachable
+	if plugin.OfflineCache, err = nri.NewOfflineCache(options.config.HostPaths.OfflineCacheDir); err != nil {
+		logger.ErrorContext(ctx, "failed to load the offline cache", slog.Any("err", err))
+	}
+	if err = plugin.RegisterCacheSyncedMetric(); err != nil {
+		logger.ErrorContext(ctx, "failed to register metrics", slog.Any("err", err))
+		os.Exit(1)
+	}
+
 	if err = copyBinaries(logger); err != nil {
 		logger.ErrorContext(ctx, "failed to copy required binaries to host filesystem", slog.Any("err", err))
 		os.Exit(1)
@@ -29,11 +69,11 @@ func startPluginMode(ctx context.Context, client client.Client, logger *slog.Log
 	opts := []stub.Option{
 		stub.WithOnClose(plugin.OnClose),
 	}
-	if pluginName != "" {
-		opts = append(opts, stub.WithPluginName(pluginName))
+	if options.name != "" {
+		opts = append(opts, stub.WithPluginName(options.name))
 	}
-	if pluginIdx != "" {
-		opts = append(opts, stub.WithPluginIdx(pluginIdx))
+	if options.idx != "" {
+		opts = append(opts, stub.WithPluginIdx(options.idx))
 	}
 
 	if plugin.Stub, err = stub.New(plugin, opts...); err != nil {
@@ -41,7 +81,23 @@ func startPluginMode(ctx context.Context, client client.Client, logger *slog.Log
 		os.Exit(1)
 	}
 
-	if err = plugin.Stub.Run(ctx); err != nil {
+	startHTTPServer(ctx, logger, "health probes", options.probeAddr, newProbeHandler(plugin))
+	if err = startMetricsServer(ctx, restConfig, logger, metricsServerOptions(options.config.Metrics, logger)); err != nil {
+		logger.ErrorContext(ctx, "failed to start metrics server", slog.Any("err", err))
+		os.Exit(1)
+	}
+	// The plugin can run without debug API
+	if socket := options.config.HostPaths.DebugSocket; socket != "" {
+		if err = startUnixServer(ctx, logger, "debug API", socket, plugin.DebugHandler()); err != nil {
+			logger.ErrorContext(ctx, "failed to start debug API server", slog.Any("err", err))
+		}
+	}
+	go plugin.RunGarbageCollection(ctx, options.gcInterval)
+	if options.configPath != "" {
+		go plugin.RunConfigReload(ctx, options.configPath, options.baseConfig, nri.DefaultConfigReloadInterval)
+	}
+
+	if err = plugin.Run(ctx); err != nil {
 		logger.ErrorContext(ctx, "plugin exited", slog.Any("err", err))
 		os.Exit(1)
 	}
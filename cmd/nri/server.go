@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/flavio/podlock/internal/nri"
+)
+
+// newProbeHandler returns the handler of the health probes. The plugin is
+// ready only while it is connected to the runtime.
+func newProbeHandler(plugin *nri.Plugin) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !plugin.Connected() {
+			http.Error(w, "not connected to the runtime", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	return mux
+}
+
+// metricsServerOptions returns the options of the metrics server, secured
+// like the one of the controller.
+func metricsServerOptions(cfg nri.MetricsConfig, logger *slog.Logger) metricsserver.Options {
+	var tlsOpts []func(*tls.Config)
+	// if http/2 is not enabled (the default), it should be disabled due to
+	// its vulnerabilities, see cmd/controller/main.go
+	if !cfg.EnableHTTP2 {
+		tlsOpts = append(tlsOpts, func(c *tls.Config) {
+			logger.Info("disabling http/2")
+			c.NextProtos = []string{"http/1.1"}
+		})
+	}
+
+	options := metricsserver.Options{
+		BindAddress:   cfg.BindAddress,
+		SecureServing: cfg.Secure,
+		TLSOpts:       tlsOpts,
+	}
+	if cfg.Secure {
+		// Only the users and service accounts allowed to get /metrics can
+		// access the metrics endpoint
+		options.FilterProvider = filters.WithAuthenticationAndAuthorization
+	}
+	// Self-signed certificates are generated when the certificate is not specified
+	if len(cfg.CertPath) > 0 {
+		logger.Info("Initializing metrics certificate watcher using provided certificates",
+			slog.String("metrics-cert-path", cfg.CertPath),
+			slog.String("metrics-cert-name", cfg.CertName),
+			slog.String("metrics-cert-key", cfg.CertKey),
+		)
+
+		options.CertDir = cfg.CertPath
+		options.CertName = cfg.CertName
+		options.KeyName = cfg.CertKey
+	}
+
+	return options
+}
+
+// startMetricsServer serves the Prometheus metrics until the context is done.
+// The server is the one of controller-runtime, so that the endpoint is
+// secured like the one of the controller.
+func startMetricsServer(
+	ctx context.Context,
+	restConfig *rest.Config,
+	logger *slog.Logger,
+	options metricsserver.Options,
+) error {
+	httpClient, err := rest.HTTPClientFor(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+
+	server, err := metricsserver.NewServer(options, restConfig, httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics server: %w", err)
+	}
+	// The metrics are disabled
+	if server == nil {
+		return nil
+	}
+
+	go func() {
+		if err := server.Start(ctx); err != nil {
+			logger.ErrorContext(ctx, "metrics server exited", slog.Any("err", err))
+		}
+	}()
+
+	return nil
+}
+
+// startHTTPServer serves the handler until the context is done.
+func startHTTPServer(ctx context.Context, logger *slog.Logger, name, addr string, handler http.Handler) {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	go func() {
+		logger.InfoContext(ctx, "serving "+name, slog.String("addr", addr))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.ErrorContext(ctx, name+" server exited", slog.Any("err", err))
+		}
+	}()
+}
+
+// startUnixServer serves the handler over a unix socket until the context is
+// done. The socket is accessible only to root, like the runtime files.
+func startUnixServer(ctx context.Context, logger *slog.Logger, name, path string, handler http.Handler) error {
+	// The socket left by the previous run of the plugin
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket '%s': %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create dir of socket '%s': %w", path, err)
+	}
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on socket '%s': %w", path, err)
+	}
+	if err = os.Chmod(path, 0o600); err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("failed to set permissions of socket '%s': %w", path, err)
+	}
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	go func() {
+		logger.InfoContext(ctx, "serving "+name, slog.String("socket", path))
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.ErrorContext(ctx, name+" server exited", slog.Any("err", err))
+		}
+	}()
+
+	return nil
+}
@@ -48,7 +48,7 @@ func setupLogger(logLevel string, logFormat LogFormat) *slog.Logger {
 			"error",
 			err,
 		)
-		os.Exit(1)
+		os.Exit(exitCodeConfig)
 	}
 
 	switch logFormat {
@@ -108,6 +108,18 @@ func wrapperMoode(binary string, binaryArgs []string) (*config, error) {
 
 	addLinkedLibraries := os.Getenv(seal.AddLinkedLibrariesEnvVar) != ""
 
+	// The policy is never inherited from the PodLock domain marker, which can
+	// be forged by the workload
+	nestingPolicy, err := seal.ParseNestingPolicy(os.Getenv(seal.NestingPolicyEnvVar))
+	if err != nil {
+		return nil, err
+	}
+
+	rulesetCacheDir := os.Getenv(seal.RulesetCacheDirEnvVar)
+	if rulesetCacheDir == "" {
+		rulesetCacheDir = nri.PodLockContainerRulesetsDir
+	}
+
 	binaryToRun := nri.SwappedBinaryPathInsideContainer(binary)
 
 	return &config{
@@ -118,6 +130,11 @@ func wrapperMoode(binary string, binaryArgs []string) (*config, error) {
 		binaryToRun:        binaryToRun,
 		binaryArgs:         binaryArgs,
 		addLinkedLibraries: addLinkedLibraries,
+		nestingPolicy:      nestingPolicy,
+		rulesetCacheDir:    rulesetCacheDir,
+		// The rulesets dir is mounted read-only, the NRI plugin compiles
+		// the rulesets before the container is started
+		rulesetCacheReadOnly: true,
 	}, nil
 }
 
@@ -136,6 +153,8 @@ func nativeMode(args []string) (*config, error) {
 		binary             string
 		binaryArgs         []string
 		addLinkedLibraries bool
+		nestingPolicy      string
+		sha256             string
 	)
 
 	// Distinguish between flag arguments of `seal` and the binary (plus its args)
@@ -181,6 +200,9 @@ Example:
 	flagSet.StringVar(&logLevel, "log-level", "info", "log level: debug, info, warn, error")
 	flagSet.Var((*LogFormatFlag)(&logFormat), "log-format", "Log format: json or text.")
 	flagSet.BoolVar(&addLinkedLibraries, "ldd", false, "Automatically add linked libraries of the target binary to the profile.")
+	flagSet.StringVar(&nestingPolicy, "nesting-policy", "",
+		"What to do when already running inside of a PodLock domain: stack, fail. Defaults to stack.")
+	flagSet.StringVar(&sha256, "sha256", "", "Expected sha256 digest of the binary, the binary is not started when it does not match.")
 
 	if err := flagSet.Parse(flagArgs); err != nil {
 		return nil, fmt.Errorf("could not parse flags: %w", err)
@@ -204,9 +226,17 @@ Example:
 	if logFormatEnv := os.Getenv(seal.LogFormatEnvVar); logFormatEnv != "" {
 		logFormat = LogFormat(logFormatEnv)
 	}
+	if nestingPolicyEnv := os.Getenv(seal.NestingPolicyEnvVar); nestingPolicyEnv != "" {
+		nestingPolicy = nestingPolicyEnv
+	}
+
+	if (len(roPaths) > 0 || len(rxPaths) > 0 || len(rwPaths) > 0 || len(rwxPaths) > 0 || sha256 != "") && profilePath != "" {
+		return nil, errors.New("cannot use --profile together with --ro, --rx, --rw, --rwx, or --sha256")
+	}
 
-	if (len(roPaths) > 0 || len(rxPaths) > 0 || len(rwPaths) > 0 || len(rwxPaths) > 0) && profilePath != "" {
-		return nil, errors.New("cannot use --profile together with --ro, --rx, --rw, or --rwx")
+	policy, err := seal.ParseNestingPolicy(nestingPolicy)
+	if err != nil {
+		return nil, err
 	}
 
 	if binary == "" {
@@ -230,6 +260,9 @@ Example:
 		binaryToRun:        binaryAbsolutePath,
 		binaryArgs:         binaryArgs,
 		addLinkedLibraries: addLinkedLibraries,
+		nestingPolicy:      policy,
+		sha256:             sha256,
+		rulesetCacheDir:    os.Getenv(seal.RulesetCacheDirEnvVar),
 	}, nil
 }
 
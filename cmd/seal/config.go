@@ -1,14 +1,19 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 
 	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
 )
 
+// errProfileNotFound is returned when the profile file does not define
+// a profile for the binary.
+var errProfileNotFound = errors.New("profile not found")
+
 type LogFormat string
 
 const (
@@ -51,6 +56,12 @@ type config struct {
 	rxPaths            []string
 	rwPaths            []string
 	rwxPaths           []string
+	nestingPolicy      seal.NestingPolicy
+	sha256             string
+	rulesetCacheDir    string
+	// rulesetCacheReadOnly is set when the compiled rulesets are stored by
+	// the PodLock NRI plugin
+	rulesetCacheReadOnly bool
 }
 
 // buildProfile builds the podlock profile based on the config.
@@ -64,28 +75,39 @@ func (c *config) buildProfile() (*podlockv1alpha1.Profile, error) {
 		ReadExec:      c.rxPaths,
 		ReadWrite:     c.rwPaths,
 		ReadWriteExec: c.rwxPaths,
+		SHA256:        c.sha256,
 	}, nil
 }
 
+// rulesetCache returns the cache of the compiled rulesets, or nil when no
+// cache directory is available.
+func (c *config) rulesetCache() *seal.RulesetCache {
+	if c.rulesetCacheDir == "" {
+		return nil
+	}
+	if info, err := os.Stat(c.rulesetCacheDir); err != nil || !info.IsDir() {
+		return nil
+	}
+	return &seal.RulesetCache{Dir: c.rulesetCacheDir, ReadOnly: c.rulesetCacheReadOnly}
+}
+
 // profileFromPath reads the profile file at the given path and returns
 // the profile for the specified binary.
 func profileFromPath(path, binary string) (*podlockv1alpha1.Profile, error) {
-	profilesByBinary := &podlockv1alpha1.ProfileByBinary{}
-
-	// Read and unmarshal the JSON profile
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open profile '%s': %w", path, err)
 	}
 	defer file.Close()
 
-	if err := json.NewDecoder(file).Decode(profilesByBinary); err != nil {
+	profileFile, err := seal.ParseProfileFile(file)
+	if err != nil {
 		return nil, fmt.Errorf("cannot unmarshal contents of profile file '%s': %w", path, err)
 	}
 
-	profile, found := (*profilesByBinary)[binary]
+	profile, found := profileFile.Profiles[binary]
 	if !found {
-		return nil, fmt.Errorf("cannot find profile for '%s'", binary)
+		return nil, fmt.Errorf("%w: cannot find profile for '%s'", errProfileNotFound, binary)
 	}
 
 	return &profile, nil
@@ -105,5 +127,9 @@ func (c *config) LogValue() slog.Value {
 		slog.Any("rxPaths", c.rxPaths),
 		slog.Any("rwPaths", c.rwPaths),
 		slog.Any("rwxPaths", c.rwxPaths),
+		slog.String("nestingPolicy", string(c.nestingPolicy)),
+		slog.String("sha256", c.sha256),
+		slog.String("rulesetCacheDir", c.rulesetCacheDir),
+		slog.Bool("rulesetCacheReadOnly", c.rulesetCacheReadOnly),
 	)
 }
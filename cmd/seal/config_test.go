@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -79,6 +80,20 @@ func TestProfileFromPath(t *testing.T) {
 	err = os.WriteFile(profileFile, profileData, 0o644)
 	require.NoError(t, err)
 
+	versionedProfileFile := filepath.Join(tmpDir, "versioned-profile.json")
+	versionedProfileData, err := json.Marshal(&seal.ProfileFile{
+		APIVersion: seal.ProfileFileAPIVersion(),
+		Generation: 3,
+		Profiles:   profiles,
+	})
+	require.NoError(t, err)
+	err = os.WriteFile(versionedProfileFile, versionedProfileData, 0o644)
+	require.NoError(t, err)
+
+	futureProfileFile := filepath.Join(tmpDir, "future-profile.json")
+	err = os.WriteFile(futureProfileFile, []byte(`{"apiVersion": "profile.podlock.kubewarden.io/v2.0", "profiles": []}`), 0o644)
+	require.NoError(t, err)
+
 	tests := []struct {
 		name    string
 		path    string
@@ -105,6 +120,22 @@ func TestProfileFromPath(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name:   "versioned profile file",
+			path:   versionedProfileFile,
+			binary: "/bin/cat",
+			want: &podlockv1alpha1.Profile{
+				ReadOnly: []string{"/foo"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "unsupported profile file version",
+			path:    futureProfileFile,
+			binary:  "/bin/cat",
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name:    "missing file",
 			path:    filepath.Join(tmpDir, "doesnotexist.json"),
@@ -27,3 +27,19 @@ func sealedProcessEnv() []string {
 	}
 	return filtered
 }
+
+// withDomain returns a copy of the given environment where the PodLock domain
+// marker is set to describe the given domain.
+func withDomain(env []string, domain *seal.Domain) []string {
+	prefix := seal.DomainEnvVar + "="
+
+	result := make([]string, 0, len(env)+1)
+	for _, kv := range env {
+		if strings.HasPrefix(kv, prefix) {
+			continue
+		}
+		result = append(result, kv)
+	}
+
+	return append(result, prefix+domain.String())
+}
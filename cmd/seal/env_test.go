@@ -95,3 +95,34 @@ func splitOnce(s, sep string) []string {
 	}
 	return []string{s[:i], s[i+1:]}
 }
+
+func TestWithDomain(t *testing.T) {
+	domain := &seal.Domain{
+		Depth:       2,
+		Policy:      seal.NestingPolicyStack,
+		Fingerprint: "abcd",
+	}
+
+	tests := []struct {
+		name string
+		env  []string
+		want []string
+	}{
+		{
+			name: "adds marker",
+			env:  []string{"FOO=bar"},
+			want: []string{"FOO=bar", seal.DomainEnvVar + "=2:stack:abcd"},
+		},
+		{
+			name: "replaces existing marker",
+			env:  []string{seal.DomainEnvVar + "=1:stack:ffff", "FOO=bar"},
+			want: []string{"FOO=bar", seal.DomainEnvVar + "=2:stack:abcd"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, withDomain(tt.env, domain))
+		})
+	}
+}
@@ -2,21 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"runtime"
 	"syscall"
 
 	"github.com/landlock-lsm/go-landlock/landlock"
 
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
 	"github.com/flavio/podlock/internal/seal"
 )
 
 func main() {
+	termLog := openTerminationLog(isContainerEntrypoint(os.Getpid(), os.Getppid()))
+
 	cfg, err := parseFlags()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error parsing flags: %v\n", err)
-		os.Exit(1)
+		termLog.exit(exitCodeConfig, "invalid seal configuration: %v", err)
 	}
 
 	logger := setupLogger(cfg.logLevel, cfg.logFormat)
@@ -25,31 +30,51 @@ func main() {
 	profile, err := cfg.buildProfile()
 	if err != nil {
 		logger.Error("Could not build profile", slog.Any("error", err))
-		os.Exit(1)
+		if errors.Is(err, errProfileNotFound) {
+			termLog.exit(exitCodeProfile, "profile for %s not found", cfg.binary)
+		}
+		termLog.exit(exitCodeProfile, "cannot load profile for %s: %v", cfg.binary, err)
 	}
 
-	// Build rules defined inside of the profile
-	rules := seal.ProfileToLandlockRules(profile, logger)
-
-	ctx := context.Background()
+	// The binary is opened before Landlock is enforced and it is later
+	// started through the verified file descriptor
+	execPath := cfg.binaryToRun
+	var verifiedBinary *os.File
+	if profile.SHA256 != "" {
+		verifiedBinary, err = seal.OpenVerifiedBinary(cfg.binaryToRun, profile.SHA256)
+		if err != nil {
+			logger.Error("Could not verify the binary to run", slog.Any("error", err))
+			if errors.Is(err, seal.ErrDigestMismatch) {
+				termLog.exit(exitCodeIntegrity, "%s does not match the sha256 digest of its profile", cfg.binary)
+			}
+			termLog.exit(exitCodeIntegrity, "cannot verify %s: %v", cfg.binary, err)
+		}
+		logger.Info("binary digest verified",
+			slog.String("binary", cfg.binaryToRun),
+			slog.String("sha256", profile.SHA256),
+		)
+		execPath = seal.ExecPath(verifiedBinary)
+	}
 
-	// Build rules for the binary to run
-	binaryRules, err := seal.RulesForBinaryToRun(ctx, cfg.binaryToRun, cfg.addLinkedLibraries, logger, seal.DiscoverLinkedLibraries)
+	domain, err := enterDomain(cfg, profile, logger)
 	if err != nil {
-		logger.Error("Could not build Landlock rules for the binary to run", slog.Any("error", err))
-		os.Exit(1)
+		logger.Error("Could not enter PodLock domain", slog.Any("error", err))
+		if errors.Is(err, seal.ErrNestingRefused) || errors.Is(err, seal.ErrTooManyDomains) {
+			termLog.exit(exitCodeNesting, "cannot run %s: %v", cfg.binary, err)
+		}
+		termLog.exit(exitCodeLandlock, "cannot apply Landlock profile for %s: %v", cfg.binary, err)
 	}
-	rules = append(rules, binaryRules...)
-	seal.DebugRules(rules, *logger)
 
-	// Apply Landlock rules
-	if err = landlock.V3.RestrictPaths(rules...); err != nil {
-		logger.Error("Could not enable Landlock", slog.Any("error", err))
-		os.Exit(1)
+	if err = seal.ApplyProcessHardening(profile, logger); err != nil {
+		logger.Error("Could not apply process hardening", slog.Any("error", err))
+		termLog.exit(exitCodeHardening, "cannot apply process hardening for %s: %v", cfg.binary, err)
 	}
-	logger.Info("landlock profile applied")
 
-	newEnv := sealedProcessEnv()
+	newEnv, removedEnv := seal.ApplyEnvironmentPolicy(sealedProcessEnv(), profile.Environment)
+	if len(removedEnv) > 0 {
+		logger.Info("environment variables removed by the profile", slog.Any("variables", removedEnv))
+	}
+	newEnv = withDomain(newEnv, domain)
 	args := append([]string{cfg.binary}, cfg.binaryArgs...)
 
 	logger.Debug("About to start sealed process",
@@ -58,8 +83,18 @@ func main() {
 		slog.Any("env", newEnv),
 	)
 
+	// The seccomp filter is loaded last, since it could deny the system calls
+	// performed by the previous steps
+	if profile.Seccomp != nil {
+		if err = seal.LoadSeccompFilter(profile.Seccomp, logger); err != nil {
+			logger.Error("Could not load seccomp filter", slog.Any("error", err))
+			termLog.exit(exitCodeSeccomp, "cannot load seccomp filter for %s: %v", cfg.binary, err)
+		}
+	}
+
 	//nolint: gosec // We really need to pass all the args we got from the user to Exec
-	err = syscall.Exec(cfg.binaryToRun, args, newEnv)
+	err = syscall.Exec(execPath, args, newEnv)
+	runtime.KeepAlive(verifiedBinary)
 	if err != nil {
 		logger.Error("Could not execve the target binary",
 			slog.Any("error", err),
@@ -67,9 +102,63 @@ func main() {
 			slog.Any("args", args),
 			slog.Any("env", newEnv),
 		)
-		os.Exit(1)
+		termLog.exit(exitCodeExec, "cannot execute %s: %v", cfg.binary, err)
 	}
 
 	// This point should never be reached because syscall.Exec replaces the current process.
 	panic("execve: unexpected return")
 }
+
+// enterDomain applies the Landlock rules of the profile to the current
+// process, taking into account the PodLock domain seal might already be
+// running inside of.
+// It returns the domain the sealed process is going to run inside of.
+func enterDomain(cfg *config, profile *podlockv1alpha1.Profile, logger *slog.Logger) (*seal.Domain, error) {
+	current, err := seal.ParseDomain(os.Getenv(seal.DomainEnvVar))
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint, err := seal.ProfileFingerprint(profile, cfg.binaryToRun, cfg.addLinkedLibraries)
+	if err != nil {
+		return nil, err
+	}
+
+	domain, err := seal.DecideNesting(current, fingerprint, cfg.nestingPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("cannot nest PodLock domain (depth %d, nesting policy %s): %w",
+			current.Depth, cfg.nestingPolicy, err)
+	}
+	if current != nil {
+		logger.Info("already running inside of a PodLock domain, stacking a new Landlock domain",
+			slog.Int("depth", domain.Depth),
+			slog.String("previousFingerprint", current.Fingerprint),
+			slog.String("fingerprint", domain.Fingerprint),
+		)
+	}
+
+	// Build the rules defined inside of the profile and the ones for the
+	// binary to run, reusing the compiled ruleset when available
+	rules, err := seal.LandlockRulesFromCache(
+		context.Background(),
+		cfg.rulesetCache(),
+		profile,
+		cfg.binaryToRun,
+		cfg.addLinkedLibraries,
+		fingerprint,
+		logger,
+		seal.DiscoverLinkedLibraries,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not build Landlock rules: %w", err)
+	}
+	seal.DebugRules(rules, *logger)
+
+	// Apply Landlock rules
+	if err = landlock.V3.RestrictPaths(rules...); err != nil {
+		return nil, fmt.Errorf("could not enable Landlock: %w", err)
+	}
+	logger.Info("landlock profile applied", slog.Int("depth", domain.Depth))
+
+	return domain, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	ll "github.com/landlock-lsm/go-landlock/landlock/syscall"
+	"github.com/stretchr/testify/require"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
+)
+
+// runSealEnvVar makes the test binary run seal, with the arguments given to
+// the test binary.
+const runSealEnvVar = "PODLOCK_TEST_RUN_SEAL"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runSealEnvVar) != "" {
+		os.Args[0] = "seal"
+		main()
+	}
+	os.Exit(m.Run())
+}
+
+// TestSealUnderMinimalSeccompAllowList checks that seal survives until the
+// binary is executed when the allow list does not include the system calls
+// of the Go runtime. The binary cannot be executed, so that seal reports the
+// failure and exits: the filter would kill it otherwise.
+func TestSealUnderMinimalSeccompAllowList(t *testing.T) {
+	if abi, err := ll.LandlockGetABIVersion(); err != nil || abi < 3 {
+		t.Skip("Landlock ABI v3 is not available")
+	}
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skip("seccomp filters are not supported on " + runtime.GOARCH)
+	}
+
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "not-executable")
+	require.NoError(t, os.WriteFile(binary, []byte("data"), 0o600))
+
+	profileFile := &seal.ProfileFile{
+		APIVersion: seal.ProfileFileAPIVersion(),
+		Profiles: podlockv1alpha1.ProfileByBinary{
+			binary: {
+				ReadOnly: []string{dir},
+				Seccomp: &podlockv1alpha1.Seccomp{
+					Allow:  []string{"read"},
+					Action: seal.SeccompActionKill,
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(profileFile)
+	require.NoError(t, err)
+	profilePath := filepath.Join(dir, "profile.json")
+	require.NoError(t, os.WriteFile(profilePath, data, 0o600))
+
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	cmd := exec.Command(executable, "--", binary)
+	cmd.Env = append(os.Environ(), runSealEnvVar+"=1", seal.ProfileEnvVar+"="+profilePath)
+	output, err := cmd.CombinedOutput()
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr, string(output))
+	require.Equal(t, exitCodeExec, exitErr.ExitCode(), "seal did not report the failed execution: %s, %s",
+		exitErr.ProcessState.String(), string(output))
+}
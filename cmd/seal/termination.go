@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/flavio/podlock/internal/seal"
+)
+
+// Exit codes used by seal, one for each class of failure. They are reported
+// by Kubernetes inside of the status of the container.
+const (
+	exitCodeConfig    = 110
+	exitCodeProfile   = 111
+	exitCodeIntegrity = 112
+	exitCodeNesting   = 113
+	exitCodeLandlock  = 114
+	exitCodeHardening = 115
+	exitCodeSeccomp   = 116
+	exitCodeExec      = 117
+)
+
+// maxTerminationMessageLength is the maximum size of the termination message
+// read by the kubelet.
+const maxTerminationMessageLength = 4096
+
+const terminationMessagePrefix = "podlock: "
+
+// terminationLog is the file where the reason of a failure is written. The
+// contents of the file are shown by Kubernetes inside of the status of the
+// container.
+type terminationLog struct {
+	file *os.File
+}
+
+// openTerminationLog opens the termination log of the container. The file
+// is opened before Landlock is enforced, since the profile might not grant
+// write access to it.
+//
+// Nothing is reported when seal is not the entrypoint of the container, e.g.
+// when a sealed process fails to execute one of its children: the container
+// keeps running and the termination log is left to the entrypoint. Nothing is
+// reported either when the file does not exist, e.g. when seal is not running
+// inside of a Kubernetes container.
+func openTerminationLog(entrypoint bool) *terminationLog {
+	if !entrypoint {
+		return &terminationLog{}
+	}
+
+	path := os.Getenv(seal.TerminationLogEnvVar)
+	if path == "" {
+		path = seal.DefaultTerminationLogPath
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return &terminationLog{}
+	}
+
+	return &terminationLog{file: file}
+}
+
+// isContainerEntrypoint tells whether the process with the given PID and
+// parent PID is the entrypoint of the container. The entrypoint is either
+// the init process of the PID namespace or, when the pod shares its PID
+// namespace, a process whose parent, the runtime, is outside of it.
+func isContainerEntrypoint(pid, ppid int) bool {
+	return pid == 1 || ppid == 0
+}
+
+// terminationMessage returns the message written to the termination log for
+// the given reason.
+func terminationMessage(reason string) string {
+	message := terminationMessagePrefix + reason
+	if len(message) > maxTerminationMessageLength {
+		message = message[:maxTerminationMessageLength]
+	}
+	return message
+}
+
+// write replaces the contents of the termination log with the given reason.
+func (t *terminationLog) write(reason string) {
+	if t.file == nil {
+		return
+	}
+
+	// Errors are ignored, the failure has already been logged
+	_ = t.file.Truncate(0)
+	_, _ = t.file.WriteAt([]byte(terminationMessage(reason)), 0)
+	_ = t.file.Close()
+	t.file = nil
+}
+
+// exit writes the reason of the failure to the termination log, then
+// terminates the process with the given exit code.
+func (t *terminationLog) exit(code int, format string, args ...any) {
+	t.write(fmt.Sprintf(format, args...))
+	os.Exit(code)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/flavio/podlock/internal/seal"
+)
+
+func TestTerminationLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "termination-log")
+	require.NoError(t, os.WriteFile(path, []byte("stale message from a previous run"), 0o600))
+	t.Setenv(seal.TerminationLogEnvVar, path)
+
+	termLog := openTerminationLog(true)
+	require.NotNil(t, termLog.file)
+
+	termLog.write("profile for /usr/sbin/nginx not found")
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "podlock: profile for /usr/sbin/nginx not found", string(contents))
+}
+
+func TestTerminationLogMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing")
+	t.Setenv(seal.TerminationLogEnvVar, path)
+
+	termLog := openTerminationLog(true)
+	assert.Nil(t, termLog.file)
+
+	// Writing must not create the file
+	termLog.write("failure")
+	assert.NoFileExists(t, path)
+}
+
+func TestTerminationLogNotEntrypoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "termination-log")
+	require.NoError(t, os.WriteFile(path, []byte("message of the entrypoint"), 0o600))
+	t.Setenv(seal.TerminationLogEnvVar, path)
+
+	termLog := openTerminationLog(false)
+	assert.Nil(t, termLog.file)
+
+	termLog.write("failure of a child process")
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "message of the entrypoint", string(contents))
+}
+
+func TestIsContainerEntrypoint(t *testing.T) {
+	assert.True(t, isContainerEntrypoint(1, 0))
+	assert.True(t, isContainerEntrypoint(42, 0), "shared PID namespace")
+	assert.False(t, isContainerEntrypoint(42, 1))
+	assert.False(t, isContainerEntrypoint(43, 42))
+}
+
+func TestTerminationMessageTruncated(t *testing.T) {
+	message := terminationMessage(strings.Repeat("a", 2*maxTerminationMessageLength))
+	assert.Len(t, message, maxTerminationMessageLength)
+	assert.True(t, strings.HasPrefix(message, terminationMessagePrefix))
+}
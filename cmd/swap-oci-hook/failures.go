@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// failureRecorder records the failures of the hook inside of a directory on
+// the host, where the NRI plugin reports them. The directory is opened before
+// changing root to the container root filesystem, so that it can still be
+// reached afterwards.
+//
+// Recording is best effort: the failures are logged by the OCI runtime too.
+type failureRecorder struct {
+	dirFd int
+}
+
+// openFailureRecorder opens the directory where the failures are recorded.
+// Nothing is recorded when the directory is empty or cannot be opened.
+func openFailureRecorder(dir string) *failureRecorder {
+	recorder := &failureRecorder{dirFd: -1}
+	if dir == "" {
+		return recorder
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return recorder
+	}
+	fd, err := unix.Open(dir, unix.O_DIRECTORY|unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return recorder
+	}
+	recorder.dirFd = fd
+
+	return recorder
+}
+
+// record writes the message of the failure to a new file.
+func (r *failureRecorder) record(message string) {
+	if r.dirFd < 0 {
+		return
+	}
+
+	name := fmt.Sprintf("%d-%d", time.Now().UnixNano(), os.Getpid())
+	fd, err := unix.Openat(r.dirFd, name, unix.O_CREAT|unix.O_EXCL|unix.O_WRONLY|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return
+	}
+	defer unix.Close(fd)
+
+	_, _ = unix.Write(fd, []byte(message))
+}
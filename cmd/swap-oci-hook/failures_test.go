@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureRecorder(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "hook-failures")
+
+	recorder := openFailureRecorder(dir)
+	recorder.record("failed to swap '/bin/ls'")
+	recorder.record("failed to swap '/bin/cat'")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	messages := []string{}
+	for _, entry := range entries {
+		message, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+		messages = append(messages, string(message))
+	}
+	assert.ElementsMatch(t, []string{"failed to swap '/bin/ls'", "failed to swap '/bin/cat'"}, messages)
+}
+
+func TestFailureRecorder_NoDir(t *testing.T) {
+	recorder := openFailureRecorder("")
+	// Nothing to record into, it must not panic
+	recorder.record("failed to swap '/bin/ls'")
+}
@@ -32,6 +32,7 @@ import (
 func main() {
 	target := flag.String("target", "", "Path to the target file")
 	backup := flag.String("backup", "", "Path to the backup file")
+	failuresDir := flag.String("failures-dir", "", "Directory on the host where the failures are recorded")
 	flag.Parse()
 
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
@@ -42,15 +43,19 @@ func main() {
 		os.Exit(1)
 	}
 
+	failures := openFailureRecorder(*failuresDir)
+
 	// Change root to the container root filesystem, this is required to setup
 	// all the overmounts correctly.
 	if err := unix.Chroot("."); err != nil {
 		logger.Error("failed to chroot to container root", slog.Any("error", err))
+		failures.record(fmt.Sprintf("failed to chroot to container root: %v", err))
 		os.Exit(1)
 	}
 
 	if err := performOverMounts(*target, *backup); err != nil {
 		logger.Error("failed to perform swap", slog.Any("error", err))
+		failures.record(fmt.Sprintf("failed to swap '%s': %v", *target, err))
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,133 @@
+// gen-profile-schema generates the JSON schema of the profile files written by
+// the NRI plugin and read by seal.
+//
+// The schema of the profiles is taken from the LandlockProfile CRD, so that
+// the two never diverge.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/flavio/podlock/internal/seal"
+)
+
+func main() {
+	crdPath := flag.String("crd", "", "Path to the LandlockProfile CRD")
+	output := flag.String("output", "", "Path of the generated JSON schema")
+	flag.Parse()
+
+	if *crdPath == "" || *output == "" {
+		fmt.Fprintf(os.Stderr, "usage: %s -crd <crd> -output <schema>\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	if err := run(*crdPath, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(crdPath, output string) error {
+	data, err := os.ReadFile(crdPath)
+	if err != nil {
+		return fmt.Errorf("cannot read CRD: %w", err)
+	}
+
+	var crd map[string]any
+	if err = yaml.Unmarshal(data, &crd); err != nil {
+		return fmt.Errorf("cannot parse CRD: %w", err)
+	}
+
+	profileSchema, err := lookup(crd,
+		"spec", "versions", 0, "schema", "openAPIV3Schema", "properties", "spec",
+		"properties", "profilesByContainer", "additionalProperties", "additionalProperties")
+	if err != nil {
+		return err
+	}
+
+	schema := map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "PodLock profile file",
+		"description": "Profile file written by the PodLock NRI plugin for each container and read by seal.",
+		"type":        "object",
+		"required":    []string{"apiVersion", "generation", "source", "profiles"},
+		"properties": map[string]any{
+			"apiVersion": map[string]any{
+				"description": "Version of the format of the file. Seal rejects files with an unknown major version.",
+				"type":        "string",
+				"pattern": fmt.Sprintf("^%s/v%d\\.[0-9]+$",
+					regexp.QuoteMeta(seal.ProfileFileGroup), seal.ProfileFileMajorVersion),
+			},
+			"generation": map[string]any{
+				"description": "Generation of the LandlockProfile the file has been created from.",
+				"type":        "integer",
+				"minimum":     0,
+			},
+			"revision": map[string]any{
+				"description": "Revision of the LandlockProfile the file has been created from, set when the pod pins a revision.",
+				"type":        "integer",
+				"minimum":     1,
+			},
+			"source": map[string]any{
+				"description": "LandlockProfile the file has been created from.",
+				"type":        "object",
+				"properties": map[string]any{
+					"namespace":       map[string]any{"type": "string"},
+					"name":            map[string]any{"type": "string"},
+					"uid":             map[string]any{"type": "string"},
+					"resourceVersion": map[string]any{"type": "string"},
+					"container":       map[string]any{"type": "string"},
+				},
+			},
+			"profiles": map[string]any{
+				"description":          "Profiles of the binaries of the container, keyed by the absolute path of the binary.",
+				"type":                 "object",
+				"additionalProperties": profileSchema,
+			},
+		},
+	}
+
+	result, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal schema: %w", err)
+	}
+
+	//nolint:gosec // the schema is a public document
+	if err = os.WriteFile(output, append(result, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cannot write schema: %w", err)
+	}
+
+	return nil
+}
+
+// lookup returns the value found by following the given map keys and slice
+// indexes.
+func lookup(value any, path ...any) (any, error) {
+	for _, step := range path {
+		switch key := step.(type) {
+		case string:
+			m, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("cannot find '%s' inside of the CRD", key)
+			}
+			value, ok = m[key]
+			if !ok {
+				return nil, fmt.Errorf("cannot find '%s' inside of the CRD", key)
+			}
+		case int:
+			s, ok := value.([]any)
+			if !ok || key >= len(s) {
+				return nil, fmt.Errorf("cannot find index %d inside of the CRD", key)
+			}
+			value = s[key]
+		}
+	}
+
+	return value, nil
+}
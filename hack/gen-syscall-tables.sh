@@ -0,0 +1,30 @@
+#!/usr/bin/env bash
+# Generates the syscall tables used by seal to build seccomp filters.
+# The tables are built from the syscall numbers defined by golang.org/x/sys.
+set -euo pipefail
+
+ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+XSYS_DIR="$(cd "${ROOT_DIR}" && go list -m -f '{{.Dir}}' golang.org/x/sys)"
+OUTPUT="${ROOT_DIR}/internal/seal/syscalls.go"
+
+table() {
+	local arch="$1"
+	local var="$2"
+
+	echo ""
+	echo "//nolint:gochecknoglobals // lookup table"
+	echo "var ${var} = map[string]uint32{"
+	awk '/^\tSYS_[A-Z0-9_]+[ \t]+= [0-9]+$/ { name = tolower(substr($1, 5)); printf "\t\"%s\": %s,\n", name, $3 }' \
+		"${XSYS_DIR}/unix/zsysnum_linux_${arch}.go"
+	echo "}"
+}
+
+{
+	echo "// Code generated by hack/gen-syscall-tables.sh; DO NOT EDIT."
+	echo ""
+	echo "package seal"
+	table amd64 syscallsAMD64
+	table arm64 syscallsARM64
+} > "${OUTPUT}"
+
+gofmt -w "${OUTPUT}"
@@ -0,0 +1,20 @@
+#!/usr/bin/env bash
+# Generates the typed clientset, listers, informers and apply configurations
+# of the podlock.kubewarden.io API group under pkg/client.
+set -euo pipefail
+
+ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+CODE_GENERATOR_VERSION="${CODE_GENERATOR_VERSION:-v0.36.2}"
+CODEGEN_PKG="${CODEGEN_PKG:-$(go mod download -json "k8s.io/code-generator@${CODE_GENERATOR_VERSION}" | jq -r .Dir)}"
+
+# shellcheck source=/dev/null
+source "${CODEGEN_PKG}/kube_codegen.sh"
+
+kube::codegen::gen_client \
+	--with-watch \
+	--with-applyconfig \
+	--output-dir "${ROOT_DIR}/pkg/client" \
+	--output-pkg "github.com/flavio/podlock/pkg/client" \
+	--boilerplate "${ROOT_DIR}/hack/boilerplate.go.txt" \
+	--one-input-api "api" \
+	"${ROOT_DIR}"
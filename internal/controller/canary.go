@@ -0,0 +1,265 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/profileref"
+	"github.com/flavio/podlock/internal/seal"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+const (
+	// canaryRequeueInterval is how often a canary in progress is evaluated.
+	canaryRequeueInterval = 30 * time.Second
+
+	defaultCanaryAnalysisPeriod   = 5 * time.Minute
+	defaultCanaryProgressDeadline = 15 * time.Minute
+)
+
+// canaryDecision is the outcome of the evaluation of a canary.
+type canaryDecision int
+
+const (
+	// canaryIdle means no canary is in progress.
+	canaryIdle canaryDecision = iota
+	canaryProgressing
+	canaryPromoted
+	canaryRolledBack
+)
+
+// decideCanary evaluates the canary of a revision at the given time. The
+// returned message explains the decision.
+func decideCanary(
+	canary *v1alpha1.CanaryStrategy,
+	status *v1alpha1.CanaryStatus,
+	now time.Time,
+) (canaryDecision, string) {
+	analysisPeriod := defaultCanaryAnalysisPeriod
+	if canary.AnalysisPeriod != nil {
+		analysisPeriod = canary.AnalysisPeriod.Duration
+	}
+	progressDeadline := defaultCanaryProgressDeadline
+	if canary.ProgressDeadline != nil {
+		progressDeadline = canary.ProgressDeadline.Duration
+	}
+	minReadyPods := max(canary.MinReadyPods, 1)
+	elapsed := now.Sub(status.StartTime.Time)
+
+	switch {
+	case status.ReadyPods >= minReadyPods && elapsed >= analysisPeriod:
+		return canaryPromoted, fmt.Sprintf(
+			"%d pod(s) using revision %d have been ready for %s",
+			status.ReadyPods, status.Revision, analysisPeriod)
+	case status.ReadyPods < minReadyPods && elapsed >= progressDeadline:
+		return canaryRolledBack, fmt.Sprintf(
+			"%d of the %d required pod(s) using revision %d are ready after %s",
+			status.ReadyPods, minReadyPods, status.Revision, progressDeadline)
+	default:
+		return canaryProgressing, fmt.Sprintf(
+			"Revision %d is used by %d pod(s), %d ready",
+			status.Revision, status.Pods, status.ReadyPods)
+	}
+}
+
+// progressCanary drives the canary of the current revision when the profile
+// uses the Canary update strategy. It sets the stable revision, the canary
+// status and the canary condition of the observed state.
+//
+// The stable revision is returned while the canary is in progress or when it
+// must be rolled back, nil otherwise.
+func (r *LandlockProfileReconciler) progressCanary(
+	ctx context.Context,
+	profile *v1alpha1.LandlockProfile,
+	revisions []v1alpha1.LandlockProfileRevision,
+	pods []metav1.PartialObjectMetadata,
+	state *observedState,
+) (*v1alpha1.LandlockProfileRevision, canaryDecision, error) {
+	canary := profileref.CanaryStrategy(profile)
+
+	var stable *v1alpha1.LandlockProfileRevision
+	for i := range revisions {
+		if revisions[i].Revision == profile.Status.StableRevision {
+			stable = &revisions[i]
+		}
+	}
+
+	// Without a previous stable revision there is nothing to compare the
+	// current one with, hence it becomes the stable one
+	if canary == nil || stable == nil || stable.Revision == state.currentRevision ||
+		equality.Semantic.DeepEqual(stable.ProfilesByContainer, profile.Spec.ProfilesByContainer) {
+		if profile.Status.Canary != nil {
+			state.canaryCondition = &metav1.Condition{
+				Type:    v1alpha1.LandlockProfileConditionCanary,
+				Status:  metav1.ConditionFalse,
+				Reason:  "Stopped",
+				Message: fmt.Sprintf("The canary of revision %d has been stopped", profile.Status.Canary.Revision),
+			}
+		}
+		state.stableRevision = state.currentRevision
+		state.canary = nil
+		return nil, canaryIdle, nil
+	}
+
+	status := profile.Status.Canary.DeepCopy()
+	if status == nil || status.Revision != state.currentRevision {
+		status = &v1alpha1.CanaryStatus{
+			Revision:  state.currentRevision,
+			StartTime: metav1.Now(),
+		}
+		log.FromContext(ctx).Info("Started canary", "profile", profile.Name,
+			"revision", status.Revision, "stableRevision", stable.Revision)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(profile, nil, corev1.EventTypeNormal, "CanaryStarted", "Canary",
+				"Testing revision %d on the selected pods, the other pods keep using revision %d",
+				status.Revision, stable.Revision)
+		}
+	}
+
+	canaryPods, err := findCanaryPods(profile, canary, pods)
+	if err != nil {
+		return nil, canaryIdle, err
+	}
+	//nolint:gosec // the number of pods inside of a namespace fits into an int32
+	status.Pods = int32(len(canaryPods))
+	if status.ReadyPods, err = r.countReadyPods(ctx, profile.Namespace, canaryPods); err != nil {
+		return nil, canaryIdle, err
+	}
+
+	decision, message := decideCanary(canary, status, time.Now())
+	condition := &metav1.Condition{
+		Type:    v1alpha1.LandlockProfileConditionCanary,
+		Message: message,
+	}
+
+	switch decision {
+	case canaryPromoted:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Promoted"
+		state.stableRevision = state.currentRevision
+		state.canary = nil
+		stable = nil
+		log.FromContext(ctx).Info("Promoted canary", "profile", profile.Name, "revision", status.Revision)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(profile, nil, corev1.EventTypeNormal, "CanaryPromoted", "Canary",
+				"Promoted revision %d: %s", status.Revision, message)
+		}
+	case canaryRolledBack:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "RolledBack"
+		state.stableRevision = stable.Revision
+		state.canary = nil
+		log.FromContext(ctx).Info("Rolling back canary", "profile", profile.Name,
+			"revision", status.Revision, "reason", message)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(profile, nil, corev1.EventTypeWarning, "CanaryRolledBack", "Canary",
+				"Rolled back revision %d to revision %d: %s", status.Revision, stable.Revision, message)
+		}
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Progressing"
+		state.stableRevision = stable.Revision
+		state.canary = status
+	}
+	state.canaryCondition = condition
+
+	return stable, decision, nil
+}
+
+// findCanaryPods returns the UIDs of the pods selected for the canary that
+// are using the current profiles.
+func findCanaryPods(
+	profile *v1alpha1.LandlockProfile,
+	canary *v1alpha1.CanaryStrategy,
+	pods []metav1.PartialObjectMetadata,
+) (map[types.UID]bool, error) {
+	current := newProfileHashes(profile.Spec.ProfilesByContainer)
+
+	canaryPods := map[types.UID]bool{}
+	for _, pod := range pods {
+		if ref, _, err := profileref.FromPod(pod.Labels, pod.Annotations); err != nil || ref.Pinned() {
+			continue
+		}
+		if len(seal.AppliedProfilesFromAnnotations(pod.Annotations)) == 0 {
+			continue
+		}
+
+		selected, err := profileref.InCanary(canary, string(pod.UID), pod.Labels)
+		if err != nil {
+			return nil, err
+		}
+		if !selected {
+			continue
+		}
+
+		outdated, err := current.outdatedContainers(pod.Annotations)
+		if err != nil {
+			return nil, err
+		}
+		if len(outdated) == 0 {
+			canaryPods[pod.UID] = true
+		}
+	}
+
+	return canaryPods, nil
+}
+
+// countReadyPods returns how many of the given pods are ready. The pods are
+// read without cache, since only their metadata is cached.
+func (r *LandlockProfileReconciler) countReadyPods(
+	ctx context.Context,
+	namespace string,
+	uids map[types.UID]bool,
+) (int32, error) {
+	if len(uids) == 0 {
+		return 0, nil
+	}
+
+	podList := &corev1.PodList{}
+	if err := r.reader().List(ctx, podList,
+		client.InNamespace(namespace),
+		client.HasLabels{constants.PodProfileLabel},
+	); err != nil {
+		return 0, fmt.Errorf("failed to list pods in namespace '%s': %w", namespace, err)
+	}
+
+	var ready int32
+	for _, pod := range podList.Items {
+		if !uids[pod.UID] {
+			continue
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				ready++
+			}
+		}
+	}
+
+	return ready, nil
+}
+
+// restoreStableRevision restores the profiles of the stable revision after
+// the canary of the current revision failed. The restored profiles are
+// snapshotted into a new revision, which matches the stable one.
+func (r *LandlockProfileReconciler) restoreStableRevision(
+	ctx context.Context,
+	profile *v1alpha1.LandlockProfile,
+	stable *v1alpha1.LandlockProfileRevision,
+) error {
+	profile.Spec.ProfilesByContainer = stable.DeepCopy().ProfilesByContainer
+	if err := r.Update(ctx, profile); err != nil {
+		return fmt.Errorf("failed to roll back LandlockProfile '%s/%s' to revision %d: %w",
+			profile.Namespace, profile.Name, stable.Revision, err)
+	}
+
+	return nil
+}
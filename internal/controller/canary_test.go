@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/profileref"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+var _ = Describe("Canary decision", func() {
+	var (
+		canary *v1alpha1.CanaryStrategy
+		status *v1alpha1.CanaryStatus
+		start  time.Time
+	)
+
+	BeforeEach(func() {
+		start = time.Now()
+		canary = &v1alpha1.CanaryStrategy{
+			Percentage:       10,
+			MinReadyPods:     2,
+			AnalysisPeriod:   &metav1.Duration{Duration: 5 * time.Minute},
+			ProgressDeadline: &metav1.Duration{Duration: 10 * time.Minute},
+		}
+		status = &v1alpha1.CanaryStatus{Revision: 2, StartTime: metav1.NewTime(start)}
+	})
+
+	It("Should keep testing the revision during the analysis period", func() {
+		status.ReadyPods = 2
+		decision, _ := decideCanary(canary, status, start.Add(time.Minute))
+		Expect(decision).To(Equal(canaryProgressing))
+	})
+
+	It("Should promote the revision once enough pods have been ready for the analysis period", func() {
+		status.ReadyPods = 2
+		decision, _ := decideCanary(canary, status, start.Add(5*time.Minute))
+		Expect(decision).To(Equal(canaryPromoted))
+	})
+
+	It("Should roll back the revision when not enough pods are ready by the deadline", func() {
+		status.ReadyPods = 1
+		decision, _ := decideCanary(canary, status, start.Add(9*time.Minute))
+		Expect(decision).To(Equal(canaryProgressing))
+
+		decision, message := decideCanary(canary, status, start.Add(10*time.Minute))
+		Expect(decision).To(Equal(canaryRolledBack))
+		Expect(message).To(ContainSubstring("1 of the 2 required pod(s)"))
+	})
+})
+
+var _ = Describe("Canary update strategy", func() {
+	const testNamespace = "default"
+
+	var (
+		ctx        context.Context
+		fakeClient client.Client
+		reconciler *LandlockProfileReconciler
+		profile    *v1alpha1.LandlockProfile
+		canaryPod  *corev1.Pod
+	)
+
+	stableProfiles := map[string]v1alpha1.ProfileByBinary{
+		"nginx": {"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx", "/var/www"}}},
+	}
+
+	reconcileProfile := func() {
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(profile)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(profile), profile)).To(Succeed())
+	}
+
+	canaryCondition := func() *metav1.Condition {
+		return meta.FindStatusCondition(profile.Status.Conditions, v1alpha1.LandlockProfileConditionCanary)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(v1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		profile = &v1alpha1.LandlockProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: testNamespace, UID: "profile-uid"},
+			Spec: v1alpha1.LandlockProfileSpec{
+				// The new revision removes /var/www
+				ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+					"nginx": {"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}}},
+				},
+				UpdateStrategy: &v1alpha1.UpdateStrategy{
+					Type: v1alpha1.CanaryUpdateStrategyType,
+					Canary: &v1alpha1.CanaryStrategy{
+						Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"track": "canary"}},
+						MinReadyPods:   1,
+						AnalysisPeriod: &metav1.Duration{},
+					},
+				},
+			},
+			Status: v1alpha1.LandlockProfileStatus{CurrentRevision: 1, StableRevision: 1},
+		}
+
+		stable := &v1alpha1.LandlockProfileRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      profileref.RevisionName("nginx", "profile-uid", 1),
+				Namespace: testNamespace,
+				Labels:    map[string]string{constants.RevisionProfileLabel: "nginx"},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: v1alpha1.GroupVersion.String(),
+					Kind:       "LandlockProfile",
+					Name:       "nginx",
+					UID:        "profile-uid",
+					Controller: ptr.To(true),
+				}},
+			},
+			Revision:            1,
+			ProfilesByContainer: stableProfiles,
+		}
+
+		canaryPod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "canary",
+				Namespace:   testNamespace,
+				UID:         "canary-uid",
+				Labels:      map[string]string{constants.PodProfileLabel: "nginx", "track": "canary"},
+				Annotations: appliedProfileAnnotations(profile, "nginx"),
+			},
+		}
+		stablePod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "stable",
+				Namespace: testNamespace,
+				UID:       "stable-uid",
+				Labels:    map[string]string{constants.PodProfileLabel: "nginx"},
+				Annotations: appliedProfileAnnotations(&v1alpha1.LandlockProfile{
+					Spec: v1alpha1.LandlockProfileSpec{ProfilesByContainer: stableProfiles},
+				}, "nginx"),
+			},
+		}
+
+		fakeClient = fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(profile, stable, canaryPod, stablePod).
+			WithStatusSubresource(profile).
+			Build()
+		reconciler = &LandlockProfileReconciler{Client: fakeClient, Scheme: scheme}
+	})
+
+	It("Should promote the new revision once the canary pods are ready", func() {
+		By("Testing the new revision while the canary pod is not ready")
+		reconcileProfile()
+		Expect(profile.Status.CurrentRevision).To(Equal(int64(2)))
+		Expect(profile.Status.StableRevision).To(Equal(int64(1)))
+		Expect(profile.Status.Canary).NotTo(BeNil())
+		Expect(profile.Status.Canary.Revision).To(Equal(int64(2)))
+		Expect(profile.Status.Canary.Pods).To(Equal(int32(1)))
+		Expect(profile.Status.Canary.ReadyPods).To(BeZero())
+		Expect(canaryCondition().Status).To(Equal(metav1.ConditionTrue))
+		Expect(profile.Status.StalePods).To(BeEmpty(), "the pods outside of the canary use the stable revision")
+
+		By("Promoting the new revision once the canary pod is ready")
+		canaryPod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		Expect(fakeClient.Status().Update(ctx, canaryPod)).To(Succeed())
+		reconcileProfile()
+		Expect(profile.Status.StableRevision).To(Equal(int64(2)))
+		Expect(profile.Status.Canary).To(BeNil())
+		Expect(canaryCondition().Reason).To(Equal("Promoted"))
+		Expect(profile.Status.StalePods).To(ConsistOf("stable"))
+	})
+
+	It("Should roll back the new revision when the canary pods are not ready by the deadline", func() {
+		Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(profile), profile)).To(Succeed())
+		profile.Spec.UpdateStrategy.Canary.ProgressDeadline = &metav1.Duration{}
+		Expect(fakeClient.Update(ctx, profile)).To(Succeed())
+
+		reconcileProfile()
+		Expect(profile.Spec.ProfilesByContainer).To(Equal(stableProfiles))
+		Expect(profile.Status.Canary).To(BeNil())
+		Expect(profile.Status.StableRevision).To(Equal(int64(1)))
+		Expect(canaryCondition().Reason).To(Equal("RolledBack"))
+
+		By("Snapshotting the restored profiles into the new stable revision")
+		reconcileProfile()
+		Expect(profile.Status.CurrentRevision).To(Equal(int64(3)))
+		Expect(profile.Status.StableRevision).To(Equal(int64(3)))
+		Expect(canaryCondition().Reason).To(Equal("RolledBack"))
+	})
+})
@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/profileref"
+	"github.com/flavio/podlock/internal/seal"
+)
+
+// stalePod is a pod running an outdated version of its profile.
+type stalePod struct {
+	name string
+	uid  types.UID
+	// containers are the names of the containers running an outdated
+	// version of the profile, sorted alphabetically
+	containers []string
+	// owner is the controller of the pod, nil for bare pods
+	owner *metav1.OwnerReference
+}
+
+// findStalePods returns the pods running an outdated version of the profile
+// in at least one of their containers, preserving the order of the given pods.
+//
+// The profile applied to each container is recorded by the NRI plugin inside
+// of the pod annotations. The containers without such annotation are not
+// reported, e.g. the ones created before PodLock started recording it. The
+// pods pinning a revision of the profile are never stale.
+//
+// While a canary is in progress, stable is the revision expected for the pods
+// not selected for the canary. It is nil otherwise.
+func findStalePods(
+	profile *v1alpha1.LandlockProfile,
+	pods []metav1.PartialObjectMetadata,
+	stable *v1alpha1.LandlockProfileRevision,
+) ([]stalePod, error) {
+	current := newProfileHashes(profile.Spec.ProfilesByContainer)
+	var stableHashes *profileHashes
+	canary := profileref.CanaryStrategy(profile)
+	if stable != nil && canary != nil {
+		stableHashes = newProfileHashes(stable.ProfilesByContainer)
+	}
+
+	var stale []stalePod
+	for _, pod := range pods {
+		if ref, _, err := profileref.FromPod(pod.Labels, pod.Annotations); err != nil || ref.Pinned() {
+			continue
+		}
+
+		expected := current
+		if stableHashes != nil {
+			selected, err := profileref.InCanary(canary, string(pod.UID), pod.Labels)
+			if err != nil {
+				return nil, err
+			}
+			if !selected {
+				expected = stableHashes
+			}
+		}
+
+		containers, err := expected.outdatedContainers(pod.Annotations)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(containers) > 0 {
+			stale = append(stale, stalePod{
+				name:       pod.Name,
+				uid:        pod.UID,
+				containers: containers,
+				owner:      metav1.GetControllerOf(&pod),
+			})
+		}
+	}
+
+	return stale, nil
+}
+
+// profileHashes computes the hashes of the profiles of each container once.
+type profileHashes struct {
+	profiles map[string]v1alpha1.ProfileByBinary
+	hashes   map[string]string
+}
+
+func newProfileHashes(profiles map[string]v1alpha1.ProfileByBinary) *profileHashes {
+	return &profileHashes{profiles: profiles, hashes: map[string]string{}}
+}
+
+func (h *profileHashes) hash(containerName string) (string, error) {
+	if hash, found := h.hashes[containerName]; found {
+		return hash, nil
+	}
+
+	hash, err := seal.ContainerProfileHash(h.profiles[containerName])
+	if err != nil {
+		return "", err
+	}
+	h.hashes[containerName] = hash
+
+	return hash, nil
+}
+
+// outdatedContainers returns the containers whose applied profile, recorded
+// inside of the given pod annotations, differs from the expected one. The
+// names of the containers are sorted alphabetically.
+func (h *profileHashes) outdatedContainers(annotations map[string]string) ([]string, error) {
+	var containers []string
+	for containerName, applied := range seal.AppliedProfilesFromAnnotations(annotations) {
+		hash, err := h.hash(containerName)
+		if err != nil {
+			return nil, err
+		}
+		if applied.Hash != hash {
+			containers = append(containers, containerName)
+		}
+	}
+	slices.Sort(containers)
+
+	return containers, nil
+}
+
+// driftNotifications keeps track of the Events emitted for the stale pods,
+// so that each pod is notified once per generation of its profile instead of
+// at every reconciliation.
+type driftNotifications struct {
+	mu sync.Mutex
+	// notified maps the pods of each profile to the generation of the
+	// profile they have been notified about
+	notified map[types.NamespacedName]map[types.UID]int64
+}
+
+// update records the stale pods of the profile and returns the ones that
+// have not been notified about the current generation of the profile yet.
+// The pods that are no longer stale are forgotten.
+func (d *driftNotifications) update(profile *v1alpha1.LandlockProfile, stale []stalePod) []stalePod {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := types.NamespacedName{Namespace: profile.Namespace, Name: profile.Name}
+	previous := d.notified[key]
+	current := make(map[types.UID]int64, len(stale))
+
+	var toNotify []stalePod
+	for _, pod := range stale {
+		if generation, found := previous[pod.uid]; !found || generation != profile.Generation {
+			toNotify = append(toNotify, pod)
+		}
+		current[pod.uid] = profile.Generation
+	}
+
+	if d.notified == nil {
+		d.notified = map[types.NamespacedName]map[types.UID]int64{}
+	}
+	if len(current) > 0 {
+		d.notified[key] = current
+	} else {
+		delete(d.notified, key)
+	}
+
+	return toNotify
+}
+
+// forget removes all the notifications of the given profile.
+func (d *driftNotifications) forget(key types.NamespacedName) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.notified, key)
+}
+
+// notifyStalePods emits an Event on each pod running an outdated version of
+// the profile, unless it has already been notified about the current
+// generation of the profile.
+func (r *LandlockProfileReconciler) notifyStalePods(ctx context.Context, profile *v1alpha1.LandlockProfile, stale []stalePod) {
+	toNotify := r.drift.update(profile, stale)
+	if r.Recorder == nil {
+		return
+	}
+
+	logger := log.FromContext(ctx)
+	for _, pod := range toNotify {
+		logger.Info("Pod is running an outdated version of the profile",
+			"profile", profile.Name,
+			"pod", pod.name,
+			"containers", pod.containers)
+
+		regarding := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.name,
+				Namespace: profile.Namespace,
+				UID:       pod.uid,
+			},
+		}
+		r.Recorder.Eventf(regarding, profile, corev1.EventTypeWarning, "ProfileDrifted", "Drift",
+			"Containers %s are running an outdated version of LandlockProfile '%s', restart the pod to apply generation %d",
+			strings.Join(pod.containers, ", "), profile.Name, profile.Generation)
+	}
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+func appliedProfileAnnotations(profile *v1alpha1.LandlockProfile, containerNames ...string) map[string]string {
+	annotations := map[string]string{}
+	for _, containerName := range containerNames {
+		applied, err := seal.NewAppliedProfile(profile, containerName)
+		Expect(err).NotTo(HaveOccurred())
+		value, err := json.Marshal(applied)
+		Expect(err).NotTo(HaveOccurred())
+		annotations[seal.AppliedProfileAnnotation(containerName)] = string(value)
+	}
+	return annotations
+}
+
+var _ = Describe("Drift detection", func() {
+	var profile *v1alpha1.LandlockProfile
+
+	BeforeEach(func() {
+		profile = &v1alpha1.LandlockProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default", Generation: 1},
+			Spec: v1alpha1.LandlockProfileSpec{
+				ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+					"main":    {"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}}},
+					"sidecar": {"/usr/bin/envoy": {ReadOnly: []string{"/etc/envoy"}}},
+				},
+			},
+		}
+	})
+
+	It("Should find the pods running an outdated version of the profile", func() {
+		oldProfile := profile.DeepCopy()
+		pods := []metav1.PartialObjectMetadata{
+			{ObjectMeta: metav1.ObjectMeta{Name: "current", UID: "uid-current",
+				Annotations: appliedProfileAnnotations(profile, "main", "sidecar")}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "not-recorded", UID: "uid-not-recorded"}},
+		}
+
+		By("Changing the profile of the main container")
+		profile.Spec.ProfilesByContainer["main"]["/usr/sbin/nginx"] = v1alpha1.Profile{
+			ReadOnly: []string{"/etc/nginx", "/var/www"},
+		}
+		profile.Generation = 2
+		pods = append(pods, metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{Name: "outdated", UID: "uid-outdated",
+				Annotations: appliedProfileAnnotations(oldProfile, "main", "sidecar")},
+		})
+
+		stale, err := findStalePods(profile, pods, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stale).To(Equal([]stalePod{
+			{name: "current", uid: "uid-current", containers: []string{"main"}},
+			{name: "outdated", uid: "uid-outdated", containers: []string{"main"}},
+		}))
+
+		By("Removing the profile of the sidecar container")
+		delete(profile.Spec.ProfilesByContainer, "sidecar")
+		stale, err = findStalePods(profile, pods, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stale).To(Equal([]stalePod{
+			{name: "current", uid: "uid-current", containers: []string{"main", "sidecar"}},
+			{name: "outdated", uid: "uid-outdated", containers: []string{"main", "sidecar"}},
+		}))
+	})
+
+	It("Should not report the pods running the current version of the profile", func() {
+		pods := []metav1.PartialObjectMetadata{
+			{ObjectMeta: metav1.ObjectMeta{Name: "current",
+				Annotations: appliedProfileAnnotations(profile, "main", "sidecar")}},
+		}
+
+		By("Bumping the generation without changing the profiles of the containers")
+		profile.Generation = 2
+		profile.Spec.ProfilesByContainer["unused"] = v1alpha1.ProfileByBinary{}
+
+		stale, err := findStalePods(profile, pods, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stale).To(BeEmpty())
+	})
+
+	It("Should not report the pods pinning a revision of the profile", func() {
+		oldProfile := profile.DeepCopy()
+		annotations := appliedProfileAnnotations(oldProfile, "main")
+		annotations[constants.PodProfileRevisionAnnotation] = "1"
+		pods := []metav1.PartialObjectMetadata{
+			{ObjectMeta: metav1.ObjectMeta{Name: "pinned", Annotations: annotations,
+				Labels: map[string]string{constants.PodProfileLabel: "nginx"}}},
+		}
+
+		profile.Spec.ProfilesByContainer["main"]["/usr/sbin/nginx"] = v1alpha1.Profile{
+			ReadOnly: []string{"/var/www"},
+		}
+		stale, err := findStalePods(profile, pods, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stale).To(BeEmpty())
+	})
+
+	It("Should notify each stale pod once per generation", func() {
+		notifications := &driftNotifications{}
+		key := types.NamespacedName{Namespace: profile.Namespace, Name: profile.Name}
+		first := stalePod{name: "first", uid: "uid-first", containers: []string{"main"}}
+		second := stalePod{name: "second", uid: "uid-second", containers: []string{"main"}}
+
+		Expect(notifications.update(profile, []stalePod{first})).To(Equal([]stalePod{first}))
+		Expect(notifications.update(profile, []stalePod{first, second})).To(Equal([]stalePod{second}))
+		Expect(notifications.update(profile, []stalePod{first, second})).To(BeEmpty())
+
+		By("Changing the generation of the profile")
+		profile.Generation = 2
+		Expect(notifications.update(profile, []stalePod{first, second})).To(Equal([]stalePod{first, second}))
+
+		By("Forgetting the pods that are no longer stale")
+		Expect(notifications.update(profile, []stalePod{second})).To(BeEmpty())
+		Expect(notifications.update(profile, []stalePod{first, second})).To(Equal([]stalePod{first}))
+
+		By("Forgetting the deleted profile")
+		notifications.forget(key)
+		Expect(notifications.notified).NotTo(HaveKey(key))
+		Expect(notifications.update(profile, nil)).To(BeEmpty())
+		Expect(notifications.notified).NotTo(HaveKey(key))
+	})
+})
@@ -19,13 +19,18 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -34,6 +39,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/profileref"
+	"github.com/flavio/podlock/internal/profilevalidation"
+	"github.com/flavio/podlock/internal/seal"
 	"github.com/flavio/podlock/pkg/constants"
 )
 
@@ -42,66 +50,295 @@ type LandlockProfileReconciler struct {
 	client.Client
 
 	Scheme *runtime.Scheme
+	// Recorder is used to emit Events on the pods running an outdated
+	// version of their profile. No Event is emitted when nil.
+	Recorder events.EventRecorder
+	// APIReader is used to read the workloads restarted by the automatic
+	// rollout without caching them. The Client is used when nil.
+	APIReader client.Reader
+
+	drift driftNotifications
 }
 
 // +kubebuilder:rbac:groups=podlock.kubewarden.io,resources=landlockprofiles,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=podlock.kubewarden.io,resources=landlockprofiles/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=podlock.kubewarden.io,resources=landlockprofiles/finalizers,verbs=update
+// +kubebuilder:rbac:groups=podlock.kubewarden.io,resources=landlockprofilerevisions,verbs=get;list;watch;create;delete
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;patch
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get
+
+// maxStatusPods is the maximum number of pod names reported inside of the
+// status of a LandlockProfile.
+const maxStatusPods = 10
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the LandlockProfile object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.22.4/pkg/reconcile
+// Reconcile snapshots the changes of the LandlockProfile into revisions, rolls
+// it back when requested, drives the canary of its new revisions, keeps its
+// status up to date, notifies the pods running an outdated version of the
+// profile, restarts their workloads when requested and removes the finalizer
+// of the profile once it is deleted and no pod is using it anymore.
 func (r *LandlockProfileReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	profile := &v1alpha1.LandlockProfile{}
 	if err := r.Get(ctx, req.NamespacedName, profile); err != nil {
 		if errors.IsNotFound(err) {
+			r.drift.forget(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, fmt.Errorf("failed to get LandlockProfile '%s/%s': %w", req.Namespace, req.Name, err)
 	}
 
+	pods, err := r.listPodsUsingProfile(ctx, profile)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	state := observedState{
+		pods:            podNames(pods),
+		currentRevision: profile.Status.CurrentRevision,
+		stableRevision:  profile.Status.StableRevision,
+		canary:          profile.Status.Canary,
+	}
+
+	var stable *v1alpha1.LandlockProfileRevision
+	decision := canaryIdle
+	if profile.DeletionTimestamp.IsZero() {
+		revisions, err := r.listRevisions(ctx, profile)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// The update of the profile triggers a new reconciliation
+		if rolledBack, err := r.rollback(ctx, profile, revisions); err != nil || rolledBack {
+			return ctrl.Result{}, err
+		}
+
+		if state.currentRevision, err = r.syncRevisions(ctx, profile, revisions, pods); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if stable, decision, err = r.progressCanary(ctx, profile, revisions, pods, &state); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if state.stale, err = findStalePods(profile, pods, stable); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err = r.updateStatus(ctx, profile, state); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if decision == canaryRolledBack {
+		// The update of the profile triggers a new reconciliation
+		return ctrl.Result{}, r.restoreStableRevision(ctx, profile, stable)
+	}
+
+	r.notifyStalePods(ctx, profile, state.stale)
+
 	// Check if the profile is being deleted
 	if !profile.DeletionTimestamp.IsZero() {
-		return handleDeletion(ctx, r, profile)
+		return handleDeletion(ctx, r, profile, podNames(pods))
 	}
 
-	return ctrl.Result{}, nil
+	result, err := r.rolloutWorkloads(ctx, profile, state.stale)
+	if err == nil && decision == canaryProgressing &&
+		(result.RequeueAfter == 0 || result.RequeueAfter > canaryRequeueInterval) {
+		result.RequeueAfter = canaryRequeueInterval
+	}
+
+	return result, err
 }
 
-func handleDeletion(ctx context.Context, r *LandlockProfileReconciler, profile *v1alpha1.LandlockProfile) (ctrl.Result, error) {
-	logger := log.FromContext(ctx)
+// listPodsUsingProfile returns the metadata of the pods using the profile,
+// sorted by name.
+func (r *LandlockProfileReconciler) listPodsUsingProfile(
+	ctx context.Context,
+	profile *v1alpha1.LandlockProfile,
+) ([]metav1.PartialObjectMetadata, error) {
+	// Note, this uses a PartialObjectMetadataList because the Pod cache
+	// is configured to only store metadata
+	podList := &metav1.PartialObjectMetadataList{}
+	podList.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "",
+		Version: "v1",
+		Kind:    "PodList",
+	})
 
-	if controllerutil.ContainsFinalizer(profile, v1alpha1.LandlockProfileFinalizer) {
-		// Check if any pods are using this profile
-		// Note, this usses a PartialObjectMetadataList because the Pod cache
-		// is configured to only store metadata
-		podList := &metav1.PartialObjectMetadataList{}
-		podList.SetGroupVersionKind(schema.GroupVersionKind{
-			Group:   "",
-			Version: "v1",
-			Kind:    "PodList",
-		})
-
-		err := r.List(ctx, podList,
-			client.InNamespace(profile.Namespace),
-			client.MatchingLabels{constants.PodProfileLabel: profile.Name},
-		)
-		if err != nil {
-			logger.Error(err, "Failed to list pods using profile")
-			return ctrl.Result{}, fmt.Errorf("failed to list pods using profile: %w", err)
+	// The label value can pin a revision, hence the pods are filtered
+	// after listing them
+	err := r.List(ctx, podList,
+		client.InNamespace(profile.Namespace),
+		client.HasLabels{constants.PodProfileLabel},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods using LandlockProfile '%s/%s': %w", profile.Namespace, profile.Name, err)
+	}
+
+	var pods []metav1.PartialObjectMetadata
+	for _, pod := range podList.Items {
+		ref, _, err := profileref.FromPod(pod.Labels, pod.Annotations)
+		if err == nil && ref.Name == profile.Name {
+			pods = append(pods, pod)
 		}
+	}
+	slices.SortFunc(pods, func(a, b metav1.PartialObjectMetadata) int {
+		return strings.Compare(a.Name, b.Name)
+	})
 
-		if len(podList.Items) > 0 {
+	return pods, nil
+}
+
+func podNames(pods []metav1.PartialObjectMetadata) []string {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+// observedState is what the controller observed about a LandlockProfile
+// outside of the profile itself.
+type observedState struct {
+	// pods are the names of the pods using the profile, sorted by name
+	pods []string
+	// stale are the pods running an outdated version of the profile
+	stale []stalePod
+	// currentRevision is the revision matching the spec of the profile
+	currentRevision int64
+	// stableRevision is the revision used outside of the canary
+	stableRevision int64
+	// canary is the progress of the canary, nil when none is in progress
+	canary *v1alpha1.CanaryStatus
+	// canaryCondition is the new canary condition, nil to keep the current one
+	canaryCondition *metav1.Condition
+}
+
+// updateStatus patches the status of the profile when it differs from the
+// one computed from its spec and from the observed state.
+func (r *LandlockProfileReconciler) updateStatus(
+	ctx context.Context,
+	profile *v1alpha1.LandlockProfile,
+	state observedState,
+) error {
+	original := profile.DeepCopy()
+	computeStatus(profile, state)
+
+	if equality.Semantic.DeepEqual(original.Status, profile.Status) {
+		return nil
+	}
+
+	if err := r.Status().Patch(ctx, profile, client.MergeFrom(original)); err != nil {
+		return fmt.Errorf("failed to update status of LandlockProfile '%s/%s': %w", profile.Namespace, profile.Name, err)
+	}
+
+	return nil
+}
+
+// computeStatus sets the status of the profile. The last transition time of
+// the conditions changes only when their status changes.
+func computeStatus(profile *v1alpha1.LandlockProfile, state observedState) {
+	pods, stale := state.pods, state.stale
+
+	status := &profile.Status
+	status.ObservedGeneration = profile.Generation
+	status.CurrentRevision = state.currentRevision
+	status.StableRevision = state.stableRevision
+	status.Canary = state.canary
+	//nolint:gosec // the number of pods inside of a namespace fits into an int32
+	status.PodCount = int32(len(pods))
+	status.Pods = nil
+	if len(pods) > 0 {
+		status.Pods = slices.Clone(pods[:min(len(pods), maxStatusPods)])
+	}
+	status.StalePods = nil
+	for _, pod := range stale[:min(len(stale), maxStatusPods)] {
+		status.StalePods = append(status.StalePods, pod.name)
+	}
+	//nolint:gosec // the ABI version is a small number
+	status.MinimumLandlockABI = int32(seal.MinimumLandlockABI(profile))
+
+	valid := metav1.Condition{
+		Type:               v1alpha1.LandlockProfileConditionValid,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ValidationSucceeded",
+		Message:            "The profile is valid",
+		ObservedGeneration: profile.Generation,
+	}
+	if errs := profilevalidation.ValidateLandlockProfile(profile); len(errs) > 0 {
+		valid.Status = metav1.ConditionFalse
+		valid.Reason = "ValidationFailed"
+		valid.Message = errs.ToAggregate().Error()
+	}
+	meta.SetStatusCondition(&status.Conditions, valid)
+
+	inUse := metav1.Condition{
+		Type:               v1alpha1.LandlockProfileConditionInUse,
+		Status:             metav1.ConditionFalse,
+		Reason:             "NoPods",
+		Message:            "The profile is not used by any pod",
+		ObservedGeneration: profile.Generation,
+	}
+	if len(pods) > 0 {
+		inUse.Status = metav1.ConditionTrue
+		inUse.Reason = "PodsFound"
+		inUse.Message = fmt.Sprintf("The profile is used by %d pod(s)", len(pods))
+	}
+	meta.SetStatusCondition(&status.Conditions, inUse)
+
+	deletionBlocked := metav1.Condition{
+		Type:               v1alpha1.LandlockProfileConditionDeletionBlocked,
+		Status:             metav1.ConditionFalse,
+		Reason:             "NotDeleted",
+		Message:            "The profile is not being deleted",
+		ObservedGeneration: profile.Generation,
+	}
+	if !profile.DeletionTimestamp.IsZero() {
+		deletionBlocked.Reason = "NoPods"
+		deletionBlocked.Message = "The profile is not used by any pod, it can be deleted"
+		if len(pods) > 0 {
+			deletionBlocked.Status = metav1.ConditionTrue
+			deletionBlocked.Reason = "PodsFound"
+			deletionBlocked.Message = fmt.Sprintf("The profile is still used by %d pod(s)", len(pods))
+		}
+	}
+	meta.SetStatusCondition(&status.Conditions, deletionBlocked)
+
+	drifted := metav1.Condition{
+		Type:               v1alpha1.LandlockProfileConditionDrifted,
+		Status:             metav1.ConditionFalse,
+		Reason:             "UpToDate",
+		Message:            "All the pods are running the current version of the profile",
+		ObservedGeneration: profile.Generation,
+	}
+	if len(stale) > 0 {
+		drifted.Status = metav1.ConditionTrue
+		drifted.Reason = "StalePods"
+		drifted.Message = fmt.Sprintf(
+			"%d pod(s) are running an outdated version of the profile and must be restarted: %s",
+			len(stale), strings.Join(status.StalePods, ", "))
+		if len(stale) > maxStatusPods {
+			drifted.Message += ", ..."
+		}
+	}
+	meta.SetStatusCondition(&status.Conditions, drifted)
+
+	if state.canaryCondition != nil {
+		canary := *state.canaryCondition
+		canary.ObservedGeneration = profile.Generation
+		meta.SetStatusCondition(&status.Conditions, canary)
+	}
+}
+
+func handleDeletion(ctx context.Context, r *LandlockProfileReconciler, profile *v1alpha1.LandlockProfile, pods []string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if controllerutil.ContainsFinalizer(profile, v1alpha1.LandlockProfileFinalizer) {
+		if len(pods) > 0 {
 			logger.Info("Cannot remove finalizer: profile still in use by pods",
 				"profile", profile.Name,
-				"podCount", len(podList.Items))
+				"podCount", len(pods))
 			// Requeue to check again later
 			return ctrl.Result{RequeueAfter: 90 * time.Second}, nil
 		}
@@ -122,6 +359,7 @@ func handleDeletion(ctx context.Context, r *LandlockProfileReconciler, profile *
 func (r *LandlockProfileReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	err := ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.LandlockProfile{}).
+		Owns(&v1alpha1.LandlockProfileRevision{}).
 		Watches(
 			&corev1.Pod{},
 			handler.EnqueueRequestsFromMapFunc(r.findProfilesForPod),
@@ -137,15 +375,15 @@ func (r *LandlockProfileReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 // findProfilesForPod maps a Pod to the LandlockProfile(s) it references
 func (r *LandlockProfileReconciler) findProfilesForPod(_ context.Context, pod client.Object) []ctrl.Request {
-	profileName, ok := pod.GetLabels()[constants.PodProfileLabel]
-	if !ok {
+	ref, ok, err := profileref.FromPod(pod.GetLabels(), pod.GetAnnotations())
+	if err != nil || !ok {
 		return nil
 	}
 
 	return []ctrl.Request{
 		{
 			NamespacedName: client.ObjectKey{
-				Name:      profileName,
+				Name:      ref.Name,
 				Namespace: pod.GetNamespace(),
 			},
 		},
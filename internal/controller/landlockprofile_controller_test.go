@@ -23,11 +23,13 @@ import (
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
 	"github.com/flavio/podlock/pkg/constants"
 )
 
@@ -108,6 +110,81 @@ var _ = Describe("LandlockProfile Controller", func() {
 			Expect(errors.IsNotFound(err)).To(BeTrue())
 		})
 
+		It("Should report the status of the LandlockProfile", func() {
+			By("Reconciling the created resource")
+			controllerReconciler := &LandlockProfileReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying the status of the unused LandlockProfile")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, profile)).To(Succeed())
+			Expect(profile.Status.ObservedGeneration).To(Equal(profile.Generation))
+			Expect(profile.Status.PodCount).To(BeZero())
+			Expect(profile.Status.Pods).To(BeEmpty())
+			Expect(profile.Status.MinimumLandlockABI).To(BeEquivalentTo(seal.LandlockABI))
+			Expect(meta.IsStatusConditionTrue(profile.Status.Conditions, v1alpha1.LandlockProfileConditionValid)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(profile.Status.Conditions, v1alpha1.LandlockProfileConditionInUse)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(profile.Status.Conditions, v1alpha1.LandlockProfileConditionDeletionBlocked)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(profile.Status.Conditions, v1alpha1.LandlockProfileConditionDrifted)).To(BeTrue())
+
+			By("Associating the LandlockProfile with a Pod")
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "status-pod",
+					Namespace: testNamespace,
+					Labels: map[string]string{
+						constants.PodProfileLabel: profileName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "pause",
+							Image: "registry.k8s.io/pause",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying the status of the used LandlockProfile")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, profile)).To(Succeed())
+			Expect(profile.Status.PodCount).To(BeEquivalentTo(1))
+			Expect(profile.Status.Pods).To(Equal([]string{"status-pod"}))
+			Expect(meta.IsStatusConditionTrue(profile.Status.Conditions, v1alpha1.LandlockProfileConditionInUse)).To(BeTrue())
+
+			By("Deleting the referenced LandlockProfile")
+			Expect(k8sClient.Delete(ctx, profile)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Verifying the deletion of the LandlockProfile is reported as blocked")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, profile)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(profile.Status.Conditions, v1alpha1.LandlockProfileConditionDeletionBlocked)).To(BeTrue())
+
+			By("Cleaning up the created Pod")
+			Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
 		It("Should not delete a LandlockProfile that is referenced by a Pod", func() {
 			By("Associating the LandlockProfile with a Pod")
 			podName := "test-pod"
@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/profileref"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+// defaultRevisionHistoryLimit is the number of revisions kept when the
+// profile does not set revisionHistoryLimit.
+const defaultRevisionHistoryLimit = 10
+
+// listRevisions returns the revisions of the profile, sorted by revision
+// number. The revisions left behind by a deleted profile with the same name
+// are ignored, they are going to be garbage collected.
+func (r *LandlockProfileReconciler) listRevisions(
+	ctx context.Context,
+	profile *v1alpha1.LandlockProfile,
+) ([]v1alpha1.LandlockProfileRevision, error) {
+	revisionList := &v1alpha1.LandlockProfileRevisionList{}
+	err := r.List(ctx, revisionList,
+		client.InNamespace(profile.Namespace),
+		client.MatchingLabels{constants.RevisionProfileLabel: profileref.RevisionLabelValue(profile.Name)},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions of LandlockProfile '%s/%s': %w", profile.Namespace, profile.Name, err)
+	}
+
+	var revisions []v1alpha1.LandlockProfileRevision
+	for _, revision := range revisionList.Items {
+		if metav1.IsControlledBy(&revision, profile) {
+			revisions = append(revisions, revision)
+		}
+	}
+	slices.SortFunc(revisions, func(a, b v1alpha1.LandlockProfileRevision) int {
+		return cmp.Compare(a.Revision, b.Revision)
+	})
+
+	return revisions, nil
+}
+
+// syncRevisions creates a new revision when the profiles of the LandlockProfile
+// differ from the ones of the latest revision, then removes the revisions
+// exceeding the history limit. The revisions pinned by pods and the stable
+// revision are never removed.
+// The number of the current revision is returned.
+func (r *LandlockProfileReconciler) syncRevisions(
+	ctx context.Context,
+	profile *v1alpha1.LandlockProfile,
+	revisions []v1alpha1.LandlockProfileRevision,
+	pods []metav1.PartialObjectMetadata,
+) (int64, error) {
+	logger := log.FromContext(ctx)
+
+	var current int64
+	if len(revisions) > 0 {
+		latest := revisions[len(revisions)-1]
+		if equality.Semantic.DeepEqual(latest.ProfilesByContainer, profile.Spec.ProfilesByContainer) {
+			current = latest.Revision
+		}
+	}
+
+	if current == 0 {
+		var latest int64
+		if len(revisions) > 0 {
+			latest = revisions[len(revisions)-1].Revision
+		}
+
+		revision, err := r.createRevision(ctx, profile, latest+1)
+		if err != nil {
+			return 0, err
+		}
+		logger.Info("Created LandlockProfileRevision", "profile", profile.Name, "revision", revision.Revision)
+
+		revisions = append(revisions, *revision)
+		current = revision.Revision
+	}
+
+	protected := map[int64]bool{profile.Status.StableRevision: true}
+	for _, pod := range pods {
+		if ref, _, err := profileref.FromPod(pod.Labels, pod.Annotations); err == nil && ref.Pinned() {
+			protected[ref.Revision] = true
+		}
+	}
+
+	limit := defaultRevisionHistoryLimit
+	if profile.Spec.RevisionHistoryLimit != nil {
+		limit = max(int(*profile.Spec.RevisionHistoryLimit), 1)
+	}
+
+	// The oldest revisions are removed first
+	toRemove := len(revisions) - limit
+	for i := 0; i < len(revisions) && toRemove > 0; i++ {
+		revision := &revisions[i]
+		if revision.Revision == current || protected[revision.Revision] {
+			continue
+		}
+
+		if err := r.Delete(ctx, revision); err != nil && !errors.IsNotFound(err) {
+			return 0, fmt.Errorf("failed to delete LandlockProfileRevision '%s/%s': %w", revision.Namespace, revision.Name, err)
+		}
+		logger.Info("Removed LandlockProfileRevision", "profile", profile.Name, "revision", revision.Revision)
+		toRemove--
+	}
+
+	return current, nil
+}
+
+// createRevision snapshots the profiles of the LandlockProfile into a new
+// revision. The revision is owned by the profile, so that it is garbage
+// collected when the profile is deleted.
+func (r *LandlockProfileReconciler) createRevision(
+	ctx context.Context,
+	profile *v1alpha1.LandlockProfile,
+	number int64,
+) (*v1alpha1.LandlockProfileRevision, error) {
+	revision := &v1alpha1.LandlockProfileRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      profileref.RevisionName(profile.Name, profile.UID, number),
+			Namespace: profile.Namespace,
+			Labels: map[string]string{
+				constants.RevisionProfileLabel: profileref.RevisionLabelValue(profile.Name),
+			},
+		},
+		Revision:            number,
+		ProfilesByContainer: profile.Spec.DeepCopy().ProfilesByContainer,
+	}
+	if err := controllerutil.SetControllerReference(profile, revision, r.Scheme); err != nil {
+		return nil, fmt.Errorf("failed to set owner of LandlockProfileRevision '%s/%s': %w", revision.Namespace, revision.Name, err)
+	}
+
+	// The creation fails when the cache does not contain the latest
+	// revisions yet, the reconciliation is retried once it catches up
+	if err := r.Create(ctx, revision); err != nil {
+		return nil, fmt.Errorf("failed to create LandlockProfileRevision '%s/%s': %w", revision.Namespace, revision.Name, err)
+	}
+
+	return revision, nil
+}
+
+// rollback restores the profiles of the revision requested by the rollback
+// annotation of the LandlockProfile, then removes the annotation. True is
+// returned when the LandlockProfile has been updated.
+//
+// The restored profiles are snapshotted again into a new revision, the
+// revision numbers always increase.
+func (r *LandlockProfileReconciler) rollback(
+	ctx context.Context,
+	profile *v1alpha1.LandlockProfile,
+	revisions []v1alpha1.LandlockProfileRevision,
+) (bool, error) {
+	value, found := profile.Annotations[constants.RollbackAnnotation]
+	if !found {
+		return false, nil
+	}
+
+	logger := log.FromContext(ctx)
+
+	var target *v1alpha1.LandlockProfileRevision
+	if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+		for i := range revisions {
+			if revisions[i].Revision == number {
+				target = &revisions[i]
+				break
+			}
+		}
+	}
+
+	delete(profile.Annotations, constants.RollbackAnnotation)
+	if target != nil {
+		profile.Spec.ProfilesByContainer = target.DeepCopy().ProfilesByContainer
+	}
+
+	if err := r.Update(ctx, profile); err != nil {
+		return false, fmt.Errorf("failed to roll back LandlockProfile '%s/%s' to revision '%s': %w",
+			profile.Namespace, profile.Name, value, err)
+	}
+
+	if target == nil {
+		logger.Info("Cannot roll back LandlockProfile: revision not found", "profile", profile.Name, "revision", value)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(profile, nil, corev1.EventTypeWarning, "RollbackFailed", "Rollback",
+				"Cannot roll back to revision '%s': the revision does not exist", value)
+		}
+		return true, nil
+	}
+
+	logger.Info("Rolled back LandlockProfile", "profile", profile.Name, "revision", target.Revision)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(profile, target, corev1.EventTypeNormal, "RolledBack", "Rollback",
+			"Restored the profiles of revision %d", target.Revision)
+	}
+
+	return true, nil
+}
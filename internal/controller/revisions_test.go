@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/profileref"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+var _ = Describe("LandlockProfile revisions", func() {
+	const testNamespace = "default"
+
+	var (
+		ctx        context.Context
+		fakeClient client.Client
+		reconciler *LandlockProfileReconciler
+		profile    *v1alpha1.LandlockProfile
+	)
+
+	profilesWithReadOnly := func(paths ...string) map[string]v1alpha1.ProfileByBinary {
+		return map[string]v1alpha1.ProfileByBinary{
+			"nginx": {
+				"/usr/sbin/nginx": v1alpha1.Profile{ReadOnly: paths},
+			},
+		}
+	}
+
+	sync := func(pods ...metav1.PartialObjectMetadata) int64 {
+		Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(profile), profile)).To(Succeed())
+		revisions, err := reconciler.listRevisions(ctx, profile)
+		Expect(err).NotTo(HaveOccurred())
+		current, err := reconciler.syncRevisions(ctx, profile, revisions, pods)
+		Expect(err).NotTo(HaveOccurred())
+		return current
+	}
+
+	revisionNumbers := func() []int64 {
+		revisions, err := reconciler.listRevisions(ctx, profile)
+		Expect(err).NotTo(HaveOccurred())
+		var numbers []int64
+		for _, revision := range revisions {
+			numbers = append(numbers, revision.Revision)
+		}
+		return numbers
+	}
+
+	updateProfiles := func(paths ...string) {
+		profile.Spec.ProfilesByContainer = profilesWithReadOnly(paths...)
+		Expect(fakeClient.Update(ctx, profile)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(v1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		profile = &v1alpha1.LandlockProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: testNamespace, UID: "profile-uid"},
+			Spec: v1alpha1.LandlockProfileSpec{
+				ProfilesByContainer: profilesWithReadOnly("/etc"),
+			},
+		}
+
+		fakeClient = fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(profile).
+			Build()
+		reconciler = &LandlockProfileReconciler{Client: fakeClient, Scheme: scheme}
+	})
+
+	It("Should snapshot each change of the profiles into a new revision", func() {
+		By("Creating the first revision")
+		Expect(sync()).To(Equal(int64(1)))
+
+		revision := &v1alpha1.LandlockProfileRevision{}
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: profileref.RevisionName("nginx", profile.UID, 1)}, revision)).To(Succeed())
+		Expect(revision.Labels).To(HaveKeyWithValue(constants.RevisionProfileLabel, "nginx"))
+		Expect(revision.ProfilesByContainer).To(Equal(profile.Spec.ProfilesByContainer))
+		Expect(metav1.IsControlledBy(revision, profile)).To(BeTrue())
+
+		By("Not creating a revision when the profiles did not change")
+		Expect(sync()).To(Equal(int64(1)))
+		Expect(revisionNumbers()).To(Equal([]int64{1}))
+
+		By("Creating a revision when the profiles change")
+		updateProfiles("/etc", "/var")
+		Expect(sync()).To(Equal(int64(2)))
+		Expect(revisionNumbers()).To(Equal([]int64{1, 2}))
+	})
+
+	It("Should remove the oldest revisions not pinned by pods", func() {
+		profile.Spec.RevisionHistoryLimit = ptr.To(int32(2))
+		Expect(fakeClient.Update(ctx, profile)).To(Succeed())
+		Expect(sync()).To(Equal(int64(1)))
+
+		pinned := metav1.PartialObjectMetadata{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "pinned",
+				Labels: map[string]string{constants.PodProfileLabel: "nginx_1"},
+			},
+		}
+
+		for i, path := range []string{"/a", "/b", "/c"} {
+			updateProfiles(path)
+			Expect(sync(pinned)).To(Equal(int64(i + 2)))
+		}
+		Expect(revisionNumbers()).To(Equal([]int64{1, 4}))
+
+		By("Removing the revision once it is not pinned anymore")
+		updateProfiles("/d")
+		Expect(sync()).To(Equal(int64(5)))
+		Expect(revisionNumbers()).To(Equal([]int64{4, 5}))
+	})
+
+	It("Should roll the profile back to a previous revision", func() {
+		Expect(sync()).To(Equal(int64(1)))
+		updateProfiles("/var")
+		Expect(sync()).To(Equal(int64(2)))
+
+		profile.Annotations = map[string]string{constants.RollbackAnnotation: "1"}
+		Expect(fakeClient.Update(ctx, profile)).To(Succeed())
+
+		revisions, err := reconciler.listRevisions(ctx, profile)
+		Expect(err).NotTo(HaveOccurred())
+		rolledBack, err := reconciler.rollback(ctx, profile, revisions)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rolledBack).To(BeTrue())
+
+		Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(profile), profile)).To(Succeed())
+		Expect(profile.Annotations).NotTo(HaveKey(constants.RollbackAnnotation))
+		Expect(profile.Spec.ProfilesByContainer).To(Equal(profilesWithReadOnly("/etc")))
+
+		By("Snapshotting the restored profiles into a new revision")
+		Expect(sync()).To(Equal(int64(3)))
+	})
+
+	It("Should ignore a rollback to a missing revision", func() {
+		Expect(sync()).To(Equal(int64(1)))
+
+		profile.Annotations = map[string]string{constants.RollbackAnnotation: "7"}
+		Expect(fakeClient.Update(ctx, profile)).To(Succeed())
+
+		revisions, err := reconciler.listRevisions(ctx, profile)
+		Expect(err).NotTo(HaveOccurred())
+		rolledBack, err := reconciler.rollback(ctx, profile, revisions)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rolledBack).To(BeTrue())
+
+		Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(profile), profile)).To(Succeed())
+		Expect(profile.Annotations).NotTo(HaveKey(constants.RollbackAnnotation))
+		Expect(profile.Spec.ProfilesByContainer).To(Equal(profilesWithReadOnly("/etc")))
+		Expect(sync()).To(Equal(int64(1)))
+	})
+})
@@ -0,0 +1,256 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+// rolloutRequeueInterval is the interval used to check the progress of the
+// automatic rollout, in addition to the changes of the pods.
+const rolloutRequeueInterval = 30 * time.Second
+
+// workload is a Deployment, StatefulSet or DaemonSet owning pods.
+type workload struct {
+	kind string
+	name string
+}
+
+func (w workload) String() string {
+	return w.kind + "/" + w.name
+}
+
+// object returns an empty object of the workload kind.
+func (w workload) object(namespace string) client.Object {
+	meta := metav1.ObjectMeta{Name: w.name, Namespace: namespace}
+
+	switch w.kind {
+	case "Deployment":
+		return &appsv1.Deployment{ObjectMeta: meta}
+	case "StatefulSet":
+		return &appsv1.StatefulSet{ObjectMeta: meta}
+	default:
+		return &appsv1.DaemonSet{ObjectMeta: meta}
+	}
+}
+
+// podTemplate returns the pod template of the given workload object.
+func podTemplate(obj client.Object) *corev1.PodTemplateSpec {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return &o.Spec.Template
+	case *appsv1.StatefulSet:
+		return &o.Spec.Template
+	case *appsv1.DaemonSet:
+		return &o.Spec.Template
+	default:
+		return nil
+	}
+}
+
+// rolloutValue returns the value of the rollout annotation identifying the
+// current version of the profile. The UID prevents a recreated profile from
+// being mistaken for the deleted one.
+func rolloutValue(profile *v1alpha1.LandlockProfile) string {
+	return fmt.Sprintf("%s/%d", profile.UID, profile.Generation)
+}
+
+// reader returns the client used to read the workloads. They are read
+// without going through the cache, to avoid caching all the workloads of the
+// cluster just for the few profiles with automatic rollout enabled.
+func (r *LandlockProfileReconciler) reader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+// rolloutWorkloads performs a rolling restart of the workloads owning the
+// stale pods, when the automatic rollout is enabled for the profile.
+//
+// A workload is restarted by setting the rollout annotation on its pod
+// template. The workloads whose pod template already has the annotation set
+// to the current version of the profile, but still own stale pods, are being
+// restarted: they count against the maximum number of concurrent restarts.
+// The bare pods and the ones owned by other kinds of controllers are skipped.
+func (r *LandlockProfileReconciler) rolloutWorkloads(
+	ctx context.Context,
+	profile *v1alpha1.LandlockProfile,
+	stale []stalePod,
+) (ctrl.Result, error) {
+	rollout := profile.Spec.Rollout
+	if rollout == nil || !rollout.Automatic || len(stale) == 0 || !profile.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	logger := log.FromContext(ctx)
+
+	workloads, err := r.findStaleWorkloads(ctx, profile.Namespace, stale)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	value := rolloutValue(profile)
+	inProgress := 0
+	var pending []workload
+	for _, w := range workloads {
+		obj := w.object(profile.Namespace)
+		if err = r.reader().Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return ctrl.Result{}, fmt.Errorf("failed to get %s '%s/%s': %w", w.kind, profile.Namespace, w.name, err)
+		}
+
+		if podTemplate(obj).Annotations[constants.RolloutAnnotation] == value {
+			inProgress++
+		} else {
+			pending = append(pending, w)
+		}
+	}
+
+	maxConcurrent := max(int(rollout.MaxConcurrent), 1)
+	available := max(maxConcurrent-inProgress, 0)
+	for _, w := range pending[:min(available, len(pending))] {
+		obj := w.object(profile.Namespace)
+		if err = r.restartWorkload(ctx, w, obj, value); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		logger.Info("Restarted workload to apply the current version of the profile",
+			"profile", profile.Name,
+			"workload", w.String())
+		if r.Recorder != nil {
+			r.Recorder.Eventf(profile, obj, corev1.EventTypeNormal, "RolloutTriggered", "Rollout",
+				"Restarting %s to apply generation %d of the profile", w, profile.Generation)
+		}
+	}
+
+	if len(pending) > available {
+		logger.Info("Waiting for the restart of other workloads before restarting more",
+			"profile", profile.Name,
+			"inProgress", inProgress+available,
+			"pending", len(pending)-available)
+	}
+
+	return ctrl.Result{RequeueAfter: rolloutRequeueInterval}, nil
+}
+
+// restartWorkload sets the rollout annotation on the pod template of the
+// workload, which triggers a rolling restart of its pods.
+func (r *LandlockProfileReconciler) restartWorkload(ctx context.Context, w workload, obj client.Object, value string) error {
+	patch, err := json.Marshal(map[string]any{
+		"spec": map[string]any{
+			"template": map[string]any{
+				"metadata": map[string]any{
+					"annotations": map[string]string{
+						constants.RolloutAnnotation: value,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot marshal rollout patch: %w", err)
+	}
+
+	if err = r.Patch(ctx, obj, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return fmt.Errorf("failed to restart %s '%s/%s': %w", w.kind, obj.GetNamespace(), w.name, err)
+	}
+
+	return nil
+}
+
+// findStaleWorkloads returns the workloads owning the stale pods, sorted by
+// kind and name. Pods owned by ReplicaSets are resolved to their Deployment.
+func (r *LandlockProfileReconciler) findStaleWorkloads(
+	ctx context.Context,
+	namespace string,
+	stale []stalePod,
+) ([]workload, error) {
+	logger := log.FromContext(ctx)
+
+	found := map[workload]struct{}{}
+	replicaSets := map[string]*metav1.OwnerReference{}
+
+	for _, pod := range stale {
+		owner := pod.owner
+		if owner == nil {
+			logger.V(1).Info("Skipping bare pod during rollout", "pod", pod.name)
+			continue
+		}
+
+		if owner.Kind == "ReplicaSet" && owner.APIVersion == appsv1.SchemeGroupVersion.String() {
+			rsOwner, cached := replicaSets[owner.Name]
+			if !cached {
+				var err error
+				rsOwner, err = r.replicaSetOwner(ctx, namespace, owner.Name)
+				if err != nil {
+					return nil, err
+				}
+				replicaSets[owner.Name] = rsOwner
+			}
+			if rsOwner == nil {
+				logger.V(1).Info("Skipping pod owned by a standalone ReplicaSet during rollout", "pod", pod.name)
+				continue
+			}
+			owner = rsOwner
+		}
+
+		if owner.APIVersion != appsv1.SchemeGroupVersion.String() ||
+			!slices.Contains([]string{"Deployment", "StatefulSet", "DaemonSet"}, owner.Kind) {
+			logger.V(1).Info("Skipping pod with unsupported owner during rollout",
+				"pod", pod.name,
+				"ownerKind", owner.Kind)
+			continue
+		}
+
+		found[workload{kind: owner.Kind, name: owner.Name}] = struct{}{}
+	}
+
+	workloads := make([]workload, 0, len(found))
+	for w := range found {
+		workloads = append(workloads, w)
+	}
+	slices.SortFunc(workloads, func(a, b workload) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	return workloads, nil
+}
+
+// replicaSetOwner returns the Deployment owning the given ReplicaSet, nil
+// when the ReplicaSet does not exist or it is not owned by a Deployment.
+func (r *LandlockProfileReconciler) replicaSetOwner(ctx context.Context, namespace, name string) (*metav1.OwnerReference, error) {
+	rs := &metav1.PartialObjectMetadata{}
+	rs.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("ReplicaSet"))
+
+	if err := r.reader().Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, rs); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get ReplicaSet '%s/%s': %w", namespace, name, err)
+	}
+
+	owner := metav1.GetControllerOf(rs)
+	if owner == nil || owner.Kind != "Deployment" {
+		return nil, nil
+	}
+
+	return owner, nil
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+func controllerRef(kind, name string) *metav1.OwnerReference {
+	return &metav1.OwnerReference{
+		APIVersion: appsv1.SchemeGroupVersion.String(),
+		Kind:       kind,
+		Name:       name,
+		Controller: ptr.To(true),
+	}
+}
+
+func rolloutAnnotation(c client.Client, obj client.Object) string {
+	Expect(c.Get(context.Background(), client.ObjectKeyFromObject(obj), obj)).To(Succeed())
+	return podTemplate(obj).Annotations[constants.RolloutAnnotation]
+}
+
+var _ = Describe("Automatic rollout", func() {
+	const testNamespace = "default"
+
+	var (
+		ctx          context.Context
+		fakeClient   client.Client
+		reconciler   *LandlockProfileReconciler
+		profile      *v1alpha1.LandlockProfile
+		deployment   *appsv1.Deployment
+		statefulSet  *appsv1.StatefulSet
+		daemonSet    *appsv1.DaemonSet
+		deploymentRS *appsv1.ReplicaSet
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(v1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		objectMeta := func(name string) metav1.ObjectMeta {
+			return metav1.ObjectMeta{Name: name, Namespace: testNamespace}
+		}
+		deployment = &appsv1.Deployment{ObjectMeta: objectMeta("web")}
+		statefulSet = &appsv1.StatefulSet{ObjectMeta: objectMeta("db")}
+		daemonSet = &appsv1.DaemonSet{ObjectMeta: objectMeta("agent")}
+		deploymentRS = &appsv1.ReplicaSet{ObjectMeta: objectMeta("web-123")}
+		deploymentRS.OwnerReferences = []metav1.OwnerReference{*controllerRef("Deployment", "web")}
+
+		fakeClient = fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(deployment, statefulSet, daemonSet, deploymentRS).
+			Build()
+		reconciler = &LandlockProfileReconciler{Client: fakeClient, Scheme: scheme}
+
+		profile = &v1alpha1.LandlockProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: testNamespace, UID: "profile-uid", Generation: 2},
+			Spec: v1alpha1.LandlockProfileSpec{
+				Rollout: &v1alpha1.Rollout{Automatic: true, MaxConcurrent: 2},
+			},
+		}
+	})
+
+	stalePods := func() []stalePod {
+		return []stalePod{
+			{name: "web-123-a", uid: "uid-1", owner: controllerRef("ReplicaSet", "web-123")},
+			{name: "web-123-b", uid: "uid-2", owner: controllerRef("ReplicaSet", "web-123")},
+			{name: "db-0", uid: "uid-3", owner: controllerRef("StatefulSet", "db")},
+			{name: "agent-x", uid: "uid-4", owner: controllerRef("DaemonSet", "agent")},
+			{name: "bare", uid: "uid-5"},
+		}
+	}
+
+	It("Should not restart the workloads when the automatic rollout is disabled", func() {
+		profile.Spec.Rollout = nil
+
+		result, err := reconciler.rolloutWorkloads(ctx, profile, stalePods())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		Expect(rolloutAnnotation(fakeClient, deployment)).To(BeEmpty())
+		Expect(rolloutAnnotation(fakeClient, statefulSet)).To(BeEmpty())
+		Expect(rolloutAnnotation(fakeClient, daemonSet)).To(BeEmpty())
+	})
+
+	It("Should restart the workloads owning stale pods respecting the maximum concurrency", func() {
+		By("Restarting the first two workloads")
+		result, err := reconciler.rolloutWorkloads(ctx, profile, stalePods())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(rolloutRequeueInterval))
+
+		Expect(rolloutAnnotation(fakeClient, daemonSet)).To(Equal("profile-uid/2"))
+		Expect(rolloutAnnotation(fakeClient, deployment)).To(Equal("profile-uid/2"))
+		Expect(rolloutAnnotation(fakeClient, statefulSet)).To(BeEmpty())
+
+		By("Waiting while the restarted workloads still own stale pods")
+		_, err = reconciler.rolloutWorkloads(ctx, profile, stalePods())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rolloutAnnotation(fakeClient, statefulSet)).To(BeEmpty())
+
+		By("Restarting the last workload once the DaemonSet pods are up to date")
+		_, err = reconciler.rolloutWorkloads(ctx, profile, stalePods()[:3])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rolloutAnnotation(fakeClient, statefulSet)).To(Equal("profile-uid/2"))
+	})
+
+	It("Should restart the workloads again when the profile changes", func() {
+		profile.Spec.Rollout.MaxConcurrent = 3
+		_, err := reconciler.rolloutWorkloads(ctx, profile, stalePods())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rolloutAnnotation(fakeClient, statefulSet)).To(Equal("profile-uid/2"))
+
+		profile.Generation = 3
+		_, err = reconciler.rolloutWorkloads(ctx, profile, stalePods())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rolloutAnnotation(fakeClient, deployment)).To(Equal("profile-uid/3"))
+		Expect(rolloutAnnotation(fakeClient, statefulSet)).To(Equal("profile-uid/3"))
+		Expect(rolloutAnnotation(fakeClient, daemonSet)).To(Equal("profile-uid/3"))
+	})
+})
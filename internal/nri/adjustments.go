@@ -13,13 +13,15 @@ const (
 	mountOptionPriv = "rprivate"
 	hookArgBackup   = "-backup"
 	hookArgTarget   = "-target"
+	hookArgFailures = "-failures-dir"
 	swapOciHookCmd  = "swap-oci-hook"
 )
 
-func createContainerAdjustment(
+func (p *Plugin) createContainerAdjustment(
 	podID, containerName string,
 	profileByBinary podlockv1alpha1.ProfileByBinary,
 	logLevel string,
+	terminationLogPath string,
 ) *api.ContainerAdjustment {
 	adjustment := &api.ContainerAdjustment{}
 
@@ -34,7 +36,15 @@ func createContainerAdjustment(
 	// inject the profile
 	adjustment.AddMount(&api.Mount{
 		Destination: ContainerProfilePathInsideContainer(),
-		Source:      landlockProfilePathOnHost(podID, containerName),
+		Source:      p.landlockProfilePathOnHost(podID, containerName),
+		Options:     []string{mountOptionPriv, mountOptionBind, "ro"},
+		Type:        mountTypeBind,
+	})
+
+	// inject the compiled rulesets
+	adjustment.AddMount(&api.Mount{
+		Destination: PodLockContainerRulesetsDir,
+		Source:      p.rulesetsDirOnHost(podID, containerName),
 		Options:     []string{mountOptionPriv, mountOptionBind, "ro"},
 		Type:        mountTypeBind,
 	})
@@ -44,7 +54,7 @@ func createContainerAdjustment(
 	createContainerHooks := []*api.Hook{}
 
 	for binary := range profileByBinary {
-		swappedBinOnHost := swappedBinaryPathOnHost(podID, containerName, binary)
+		swappedBinOnHost := p.swappedBinaryPathOnHost(podID, containerName, binary)
 		swappedBinInsideContainer := SwappedBinaryPathInsideContainer(binary)
 
 		adjustment.AddMount(&api.Mount{
@@ -56,7 +66,12 @@ func createContainerAdjustment(
 
 		hook := &api.Hook{
 			Path: SwapOciHookBinaryPathHost,
-			Args: []string{swapOciHookCmd, hookArgTarget, binary, hookArgBackup, swappedBinInsideContainer},
+			Args: []string{
+				swapOciHookCmd,
+				hookArgTarget, binary,
+				hookArgBackup, swappedBinInsideContainer,
+				hookArgFailures, p.hookFailuresDirOnHost(podID, containerName),
+			},
 		}
 
 		createContainerHooks = append(createContainerHooks, hook)
@@ -66,6 +81,21 @@ func createContainerAdjustment(
 	})
 
 	adjustment.AddEnv(seal.LogLevelEnvVar, logLevel)
+	if terminationLogPath != "" && terminationLogPath != seal.DefaultTerminationLogPath {
+		adjustment.AddEnv(seal.TerminationLogEnvVar, terminationLogPath)
+	}
 
 	return adjustment
 }
+
+// terminationMessagePathAnnotation is the annotation the kubelet sets on the
+// containers with the terminationMessagePath of their spec.
+const terminationMessagePathAnnotation = "io.kubernetes.container.terminationMessagePath"
+
+// terminationLogPath returns the path of the termination log inside of the
+// container, which is the value of the terminationMessagePath of the
+// container. An empty string is returned when the runtime does not report
+// it, seal uses the default path then.
+func terminationLogPath(ctr *api.Container) string {
+	return ctr.GetAnnotations()[terminationMessagePathAnnotation]
+}
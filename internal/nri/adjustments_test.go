@@ -9,12 +9,15 @@ import (
 )
 
 func TestCreateContainerAdjustment(t *testing.T) {
+	plugin := &Plugin{RunDir: t.TempDir()}
+
 	tests := []struct {
 		name            string
 		podID           string
 		containerName   string
 		profileByBinary podlockv1alpha1.ProfileByBinary
 		logLevel        string
+		terminationLog  string
 		expectMounts    []api.Mount
 		expectEnv       map[string]string
 		expectHooks     []*api.Hook
@@ -36,13 +39,19 @@ func TestCreateContainerAdjustment(t *testing.T) {
 				},
 				{
 					Destination: ContainerProfilePathInsideContainer(),
-					Source:      landlockProfilePathOnHost("pod1", "cont1"),
+					Source:      plugin.landlockProfilePathOnHost("pod1", "cont1"),
+					Options:     []string{"rprivate", "rbind", "ro"},
+					Type:        "bind",
+				},
+				{
+					Destination: PodLockContainerRulesetsDir,
+					Source:      plugin.rulesetsDirOnHost("pod1", "cont1"),
 					Options:     []string{"rprivate", "rbind", "ro"},
 					Type:        "bind",
 				},
 				{
 					Destination: SwappedBinaryPathInsideContainer("/bin/ls"),
-					Source:      swappedBinaryPathOnHost("pod1", "cont1", "/bin/ls"),
+					Source:      plugin.swappedBinaryPathOnHost("pod1", "cont1", "/bin/ls"),
 					Options:     []string{"rprivate", "rbind", "ro"},
 					Type:        "bind",
 				},
@@ -53,7 +62,12 @@ func TestCreateContainerAdjustment(t *testing.T) {
 			expectHooks: []*api.Hook{
 				{
 					Path: SwapOciHookBinaryPathHost,
-					Args: []string{"swap-oci-hook", "-target", "/bin/ls", "-backup", SwappedBinaryPathInsideContainer("/bin/ls")},
+					Args: []string{
+						"swap-oci-hook",
+						"-target", "/bin/ls",
+						"-backup", SwappedBinaryPathInsideContainer("/bin/ls"),
+						"-failures-dir", plugin.hookFailuresDirOnHost("pod1", "cont1"),
+					},
 				},
 			},
 		},
@@ -75,19 +89,19 @@ func TestCreateContainerAdjustment(t *testing.T) {
 				},
 				{
 					Destination: ContainerProfilePathInsideContainer(),
-					Source:      landlockProfilePathOnHost("pod2", "cont2"),
+					Source:      plugin.landlockProfilePathOnHost("pod2", "cont2"),
 					Options:     []string{"rprivate", "rbind", "ro"},
 					Type:        "bind",
 				},
 				{
 					Destination: SwappedBinaryPathInsideContainer("/bin/ls"),
-					Source:      swappedBinaryPathOnHost("pod2", "cont2", "/bin/ls"),
+					Source:      plugin.swappedBinaryPathOnHost("pod2", "cont2", "/bin/ls"),
 					Options:     []string{"rprivate", "rbind", "ro"},
 					Type:        "bind",
 				},
 				{
 					Destination: SwappedBinaryPathInsideContainer("/bin/cat"),
-					Source:      swappedBinaryPathOnHost("pod2", "cont2", "/bin/cat"),
+					Source:      plugin.swappedBinaryPathOnHost("pod2", "cont2", "/bin/cat"),
 					Options:     []string{"rprivate", "rbind", "ro"},
 					Type:        "bind",
 				},
@@ -98,11 +112,21 @@ func TestCreateContainerAdjustment(t *testing.T) {
 			expectHooks: []*api.Hook{
 				{
 					Path: SwapOciHookBinaryPathHost,
-					Args: []string{"swap-oci-hook", "-target", "/bin/ls", "-backup", SwappedBinaryPathInsideContainer("/bin/ls")},
+					Args: []string{
+						"swap-oci-hook",
+						"-target", "/bin/ls",
+						"-backup", SwappedBinaryPathInsideContainer("/bin/ls"),
+						"-failures-dir", plugin.hookFailuresDirOnHost("pod2", "cont2"),
+					},
 				},
 				{
 					Path: SwapOciHookBinaryPathHost,
-					Args: []string{"swap-oci-hook", "-target", "/bin/cat", "-backup", SwappedBinaryPathInsideContainer("/bin/cat")},
+					Args: []string{
+						"swap-oci-hook",
+						"-target", "/bin/cat",
+						"-backup", SwappedBinaryPathInsideContainer("/bin/cat"),
+						"-failures-dir", plugin.hookFailuresDirOnHost("pod2", "cont2"),
+					},
 				},
 			},
 		},
@@ -121,7 +145,7 @@ func TestCreateContainerAdjustment(t *testing.T) {
 				},
 				{
 					Destination: ContainerProfilePathInsideContainer(),
-					Source:      landlockProfilePathOnHost("pod3", "cont3"),
+					Source:      plugin.landlockProfilePathOnHost("pod3", "cont3"),
 					Options:     []string{"rprivate", "rbind", "ro"},
 					Type:        "bind",
 				},
@@ -131,11 +155,24 @@ func TestCreateContainerAdjustment(t *testing.T) {
 			},
 			expectHooks: []*api.Hook{},
 		},
+		{
+			name:            "custom termination log",
+			podID:           "pod4",
+			containerName:   "cont4",
+			profileByBinary: podlockv1alpha1.ProfileByBinary{},
+			logLevel:        "info",
+			terminationLog:  "/tmp/termination-log",
+			expectEnv: map[string]string{
+				"SEAL_LOG_LEVEL":            "info",
+				"SEAL_TERMINATION_LOG_PATH": "/tmp/termination-log",
+			},
+			expectHooks: []*api.Hook{},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			adj := createContainerAdjustment(tt.podID, tt.containerName, tt.profileByBinary, tt.logLevel)
+			adj := plugin.createContainerAdjustment(tt.podID, tt.containerName, tt.profileByBinary, tt.logLevel, tt.terminationLog)
 			assert.NotNil(t, adj)
 
 			// Check mounts (order doesn't matter)
@@ -161,6 +198,38 @@ func TestCreateContainerAdjustment(t *testing.T) {
 	}
 }
 
+func TestTerminationLogPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+	}{
+		{
+			name: "termination message path set by the kubelet",
+			annotations: map[string]string{
+				"io.kubernetes.container.hash":   "3f2e1d0c",
+				terminationMessagePathAnnotation: "/tmp/termination-log",
+			},
+			want: "/tmp/termination-log",
+		},
+		{
+			name:        "no termination message path",
+			annotations: nil,
+			want:        "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctr := &api.Container{
+				Name:        "nginx",
+				Annotations: tt.annotations,
+			}
+			assert.Equal(t, tt.want, terminationLogPath(ctr))
+		})
+	}
+}
+
 func containsMountWithFields(actual []*api.Mount, expected *api.Mount) bool {
 	for _, act := range actual {
 		match := true
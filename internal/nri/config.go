@@ -0,0 +1,257 @@
+package nri
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	"github.com/flavio/podlock/internal/cmdutil"
+)
+
+// DefaultConfigReloadInterval is the default interval between two checks of
+// the configuration file. A polling is used rather than inotify, since the
+// ConfigMaps are updated by swapping a symlink.
+const DefaultConfigReloadInterval = 10 * time.Second
+
+// Config is the configuration of the plugin, read from a YAML file. The
+// settings missing from the file keep the values given by the flags.
+//
+// HostPaths and Metrics are used only when the plugin starts, the other
+// settings are applied again when the file changes.
+type Config struct {
+	// LogLevel is the log level of the plugin
+	LogLevel string `json:"logLevel"`
+	// SealLogLevel is the log level passed to seal inside of the containers
+	SealLogLevel string `json:"sealLogLevel"`
+	// ExcludedNamespaces are the namespaces whose pods are never sandboxed
+	ExcludedNamespaces     []string               `json:"excludedNamespaces,omitempty"`
+	FailurePolicy          FailurePolicy          `json:"failurePolicy"`
+	FailureRetryTimeout    metav1.Duration        `json:"failureRetryTimeout"`
+	SwapVerificationPolicy SwapVerificationPolicy `json:"swapVerificationPolicy"`
+	// RecordAppliedProfiles tells whether the pods are annotated with the
+	// profiles applied to their containers, which requires the permission
+	// to patch the pods
+	RecordAppliedProfiles bool            `json:"recordAppliedProfiles"`
+	HostPaths             HostPathsConfig `json:"hostPaths"`
+	Metrics               MetricsConfig   `json:"metrics"`
+}
+
+// HostPathsConfig holds the paths used by the plugin on the host. They are
+// the same inside of the plugin container, which must mount them.
+type HostPathsConfig struct {
+	RunDir          string `json:"runDir"`
+	OfflineCacheDir string `json:"offlineCacheDir"`
+	// DebugSocket is empty when the debug API is disabled
+	DebugSocket string `json:"debugSocket"`
+}
+
+// MetricsConfig configures the metrics server, like the flags of the
+// controller.
+type MetricsConfig struct {
+	// BindAddress is "0" when the metrics are disabled
+	BindAddress string `json:"bindAddress"`
+	Secure      bool   `json:"secure"`
+	CertPath    string `json:"certPath,omitempty"`
+	CertName    string `json:"certName"`
+	CertKey     string `json:"certKey"`
+	EnableHTTP2 bool   `json:"enableHTTP2"`
+}
+
+// DefaultConfig returns the configuration used when neither the flags nor
+// the configuration file change it.
+func DefaultConfig() Config {
+	return Config{
+		LogLevel:               slog.LevelInfo.String(),
+		SealLogLevel:           slog.LevelInfo.String(),
+		FailurePolicy:          FailurePolicyDeny,
+		FailureRetryTimeout:    metav1.Duration{Duration: DefaultFailureRetryTimeout},
+		SwapVerificationPolicy: SwapVerificationPolicyDeny,
+		RecordAppliedProfiles:  true,
+		HostPaths: HostPathsConfig{
+			RunDir:          PodLockVarRunDir,
+			OfflineCacheDir: PodLockOfflineCacheDir,
+			DebugSocket:     PodLockDebugSocket,
+		},
+		Metrics: MetricsConfig{
+			BindAddress: "0",
+			Secure:      true,
+			CertName:    "tls.crt",
+			CertKey:     "tls.key",
+		},
+	}
+}
+
+// LoadConfig reads the configuration file over the base configuration, then
+// validates the result. Unknown settings are rejected.
+func LoadConfig(path string, base Config) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read configuration file '%s': %w", path, err)
+	}
+
+	return parseConfig(data, base)
+}
+
+func parseConfig(data []byte, base Config) (Config, error) {
+	cfg := base
+	// The lists are replaced, not merged
+	cfg.ExcludedNamespaces = slices.Clone(base.ExcludedNamespaces)
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse configuration: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the configuration, all the errors are returned together.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if _, err := cmdutil.ParseLogLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("logLevel: %w", err))
+	}
+	if _, err := cmdutil.ParseLogLevel(c.SealLogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("sealLogLevel: %w", err))
+	}
+	for _, namespace := range c.ExcludedNamespaces {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			errs = append(errs, fmt.Errorf("excludedNamespaces: '%s': %s", namespace, msg))
+		}
+	}
+	if _, err := ParseFailurePolicy(string(c.FailurePolicy)); err != nil {
+		errs = append(errs, fmt.Errorf("failurePolicy: %w", err))
+	}
+	if c.FailureRetryTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("failureRetryTimeout: must be positive"))
+	}
+	if _, err := ParseSwapVerificationPolicy(string(c.SwapVerificationPolicy)); err != nil {
+		errs = append(errs, fmt.Errorf("swapVerificationPolicy: %w", err))
+	}
+
+	for name, path := range map[string]string{
+		"hostPaths.runDir":          c.HostPaths.RunDir,
+		"hostPaths.offlineCacheDir": c.HostPaths.OfflineCacheDir,
+	} {
+		if !filepath.IsAbs(path) {
+			errs = append(errs, fmt.Errorf("%s: '%s' is not an absolute path", name, path))
+		}
+	}
+	if c.HostPaths.DebugSocket != "" && !filepath.IsAbs(c.HostPaths.DebugSocket) {
+		errs = append(errs, fmt.Errorf("hostPaths.debugSocket: '%s' is not an absolute path", c.HostPaths.DebugSocket))
+	}
+	if c.Metrics.BindAddress == "" {
+		errs = append(errs, errors.New("metrics.bindAddress: must not be empty, use 0 to disable the metrics"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// excludesNamespace tells whether the pods of the namespace are never
+// sandboxed.
+func (c *Config) excludesNamespace(namespace string) bool {
+	return slices.Contains(c.ExcludedNamespaces, namespace)
+}
+
+// ApplyConfig replaces the configuration of the plugin. The host paths are
+// applied only by the first configuration, since the runtime files of the
+// running containers are stored under them. The runtime dir is the RunDir
+// of the plugin, it is never changed by a configuration.
+func (p *Plugin) ApplyConfig(cfg Config) {
+	if previous := p.config.Load(); previous != nil {
+		if !reflect.DeepEqual(previous.HostPaths, cfg.HostPaths) || !reflect.DeepEqual(previous.Metrics, cfg.Metrics) {
+			p.Logger.Warn("the host paths and the metrics settings are applied only when the plugin starts")
+		}
+		cfg.HostPaths = previous.HostPaths
+		cfg.Metrics = previous.Metrics
+	} else if cfg.HostPaths.RunDir != p.runDir() {
+		p.Logger.Warn("the runtime dir is set when the plugin is created, the configured one is ignored",
+			slog.String("runDir", p.runDir()),
+			slog.String("configured", cfg.HostPaths.RunDir),
+		)
+	}
+	cfg.HostPaths.RunDir = p.runDir()
+
+	if p.Level != nil {
+		// Validated when loaded
+		level, _ := cmdutil.ParseLogLevel(cfg.LogLevel)
+		p.Level.Set(level)
+	}
+	p.config.Store(&cfg)
+}
+
+// settings returns the configuration of the plugin. The fields of the
+// plugin are used until a configuration is applied.
+func (p *Plugin) settings() *Config {
+	if cfg := p.config.Load(); cfg != nil {
+		return cfg
+	}
+
+	cfg := DefaultConfig()
+	cfg.LogLevel = p.LogLevel
+	cfg.SealLogLevel = p.LogLevel
+	if p.FailurePolicy != "" {
+		cfg.FailurePolicy = p.FailurePolicy
+	}
+	if p.FailureRetryTimeout != 0 {
+		cfg.FailureRetryTimeout.Duration = p.FailureRetryTimeout
+	}
+	if p.SwapVerificationPolicy != "" {
+		cfg.SwapVerificationPolicy = p.SwapVerificationPolicy
+	}
+	cfg.HostPaths.RunDir = p.runDir()
+
+	return &cfg
+}
+
+// RunConfigReload applies again the configuration file every time it
+// changes, until the context is done. An invalid configuration is logged and
+// ignored, the previous one stays in effect.
+func (p *Plugin) RunConfigReload(ctx context.Context, path string, base Config, interval time.Duration) {
+	var last []byte
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			data, err := os.ReadFile(path)
+			if err != nil {
+				p.Logger.WarnContext(ctx, "failed to read configuration file", slog.String("path", path), slog.Any("err", err))
+				continue
+			}
+			if bytes.Equal(data, last) {
+				continue
+			}
+			last = data
+
+			cfg, err := parseConfig(data, base)
+			if err != nil {
+				p.Logger.ErrorContext(ctx, "ignoring invalid configuration file", slog.String("path", path), slog.Any("err", err))
+				continue
+			}
+			// The file may not have changed since it was loaded at startup
+			if current := p.config.Load(); current != nil && reflect.DeepEqual(*current, cfg) {
+				continue
+			}
+			p.ApplyConfig(cfg)
+			p.Logger.InfoContext(ctx, "configuration reloaded", slog.String("path", path))
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package nri
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+func TestParseConfig(t *testing.T) {
+	base := DefaultConfig()
+	base.ExcludedNamespaces = []string{"kube-system"}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected func(cfg *Config)
+		wantErr  string
+	}{
+		{
+			name:     "empty file keeps the base",
+			data:     "{}",
+			expected: func(*Config) {},
+		},
+		{
+			name: "settings override the base",
+			data: `
+logLevel: debug
+sealLogLevel: warn
+excludedNamespaces:
+  - monitoring
+failurePolicy: Retry
+failureRetryTimeout: 500ms
+swapVerificationPolicy: Warn
+hostPaths:
+  debugSocket: ""
+metrics:
+  bindAddress: ":8443"
+`,
+			expected: func(cfg *Config) {
+				cfg.LogLevel = "debug"
+				cfg.SealLogLevel = "warn"
+				cfg.ExcludedNamespaces = []string{"monitoring"}
+				cfg.FailurePolicy = FailurePolicyRetry
+				cfg.FailureRetryTimeout.Duration = 500 * time.Millisecond
+				cfg.SwapVerificationPolicy = SwapVerificationPolicyWarn
+				cfg.HostPaths.DebugSocket = ""
+				cfg.Metrics.BindAddress = ":8443"
+			},
+		},
+		{
+			name:    "unknown settings are rejected",
+			data:    "learningMode: true",
+			wantErr: `unknown field "learningMode"`,
+		},
+		{
+			name:    "invalid log level",
+			data:    "sealLogLevel: verbose",
+			wantErr: "sealLogLevel",
+		},
+		{
+			name:    "invalid namespace",
+			data:    "excludedNamespaces: [Kube_System]",
+			wantErr: "excludedNamespaces: 'Kube_System'",
+		},
+		{
+			name:    "invalid failure policy",
+			data:    "failurePolicy: allow",
+			wantErr: "failurePolicy",
+		},
+		{
+			name:    "non positive retry timeout",
+			data:    "failureRetryTimeout: 0s",
+			wantErr: "failureRetryTimeout: must be positive",
+		},
+		{
+			name:    "relative host path",
+			data:    "hostPaths: {runDir: var/run/podlock}",
+			wantErr: "hostPaths.runDir: 'var/run/podlock' is not an absolute path",
+		},
+		{
+			name:    "empty metrics bind address",
+			data:    `metrics: {bindAddress: ""}`,
+			wantErr: "metrics.bindAddress",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseConfig([]byte(tt.data), base)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			expected := base
+			tt.expected(&expected)
+			assert.Equal(t, expected, cfg)
+		})
+	}
+
+	assert.Equal(t, []string{"kube-system"}, base.ExcludedNamespaces, "the base must not be modified")
+}
+
+func TestApplyConfig(t *testing.T) {
+	runDir := t.TempDir()
+
+	level := &slog.LevelVar{}
+	plugin := &Plugin{Logger: slog.New(slog.DiscardHandler), Level: level, RunDir: runDir}
+
+	cfg := DefaultConfig()
+	cfg.HostPaths.RunDir = runDir
+	plugin.ApplyConfig(cfg)
+
+	updated := cfg
+	updated.LogLevel = "debug"
+	updated.HostPaths.RunDir = "/elsewhere"
+	updated.Metrics.BindAddress = ":8443"
+	plugin.ApplyConfig(updated)
+
+	assert.Equal(t, slog.LevelDebug, level.Level())
+	assert.Equal(t, "debug", plugin.settings().LogLevel)
+	assert.Equal(t, cfg.HostPaths, plugin.settings().HostPaths, "the host paths are applied only at startup")
+	assert.Equal(t, cfg.Metrics, plugin.settings().Metrics, "the metrics are applied only at startup")
+	assert.Equal(t, runDir, plugin.runDir())
+}
+
+func TestApplyConfig_RunDirSetAtCreation(t *testing.T) {
+	runDir := t.TempDir()
+
+	plugin := &Plugin{Logger: slog.New(slog.DiscardHandler), RunDir: runDir}
+	plugin.ApplyConfig(DefaultConfig())
+
+	assert.Equal(t, runDir, plugin.runDir())
+	assert.Equal(t, runDir, plugin.settings().HostPaths.RunDir)
+}
+
+func TestCreateContainer_ExcludedNamespace(t *testing.T) {
+	runDir := t.TempDir()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, eventsv1.AddToScheme(scheme))
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newTestProfile()).Build()
+
+	plugin := &Plugin{
+		RunDir: runDir,
+		Logger: slog.New(slog.DiscardHandler),
+		Client: kubeClient,
+	}
+	cfg := DefaultConfig()
+	cfg.HostPaths.RunDir = runDir
+	cfg.ExcludedNamespaces = []string{"default"}
+	plugin.ApplyConfig(cfg)
+
+	pod := &api.PodSandbox{
+		Id:        "pod-1",
+		Name:      "testpod",
+		Namespace: "default",
+		Labels:    map[string]string{constants.PodProfileLabel: "nginx"},
+	}
+	adjustment, _, err := plugin.CreateContainer(context.Background(), pod, &api.Container{Id: "ctr-1", PodSandboxId: "pod-1", Name: "main"})
+	require.NoError(t, err)
+	assert.Nil(t, adjustment)
+	assert.NoDirExists(t, plugin.podDirOnHost("pod-1"))
+
+	plugin.recording.Wait()
+	events := &eventsv1.EventList{}
+	require.NoError(t, kubeClient.List(context.Background(), events))
+	require.Len(t, events.Items, 1)
+	assert.Equal(t, corev1.EventTypeWarning, events.Items[0].Type)
+	assert.Equal(t, constants.NamespaceExcludedEventReason, events.Items[0].Reason)
+	assert.Equal(t, "Namespace default is excluded by the PodLock configuration, container main created "+
+		"without LandlockProfile nginx", events.Items[0].Note)
+}
+
+func TestRunConfigReload(t *testing.T) {
+	runDir := t.TempDir()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("logLevel: info"), 0o600))
+
+	base := DefaultConfig()
+	base.HostPaths.RunDir = runDir
+	cfg, err := LoadConfig(path, base)
+	require.NoError(t, err)
+
+	plugin := &Plugin{Logger: slog.New(slog.DiscardHandler), RunDir: runDir}
+	plugin.ApplyConfig(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go plugin.RunConfigReload(ctx, path, base, time.Millisecond)
+
+	require.NoError(t, os.WriteFile(path, []byte("excludedNamespaces: [monitoring]"), 0o600))
+	assert.Eventually(t, func() bool {
+		return plugin.settings().excludesNamespace("monitoring")
+	}, time.Second, time.Millisecond)
+
+	// An invalid configuration is ignored
+	require.NoError(t, os.WriteFile(path, []byte("failurePolicy: Maybe"), 0o600))
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, plugin.settings().excludesNamespace("monitoring"))
+	assert.Equal(t, FailurePolicyDeny, plugin.settings().FailurePolicy)
+}
+
+func TestDebugHandler_Config(t *testing.T) {
+	runDir := t.TempDir()
+
+	plugin := &Plugin{Logger: slog.New(slog.DiscardHandler), RunDir: runDir}
+	cfg := DefaultConfig()
+	cfg.HostPaths.RunDir = runDir
+	cfg.ExcludedNamespaces = []string{"kube-system"}
+	plugin.ApplyConfig(cfg)
+
+	server := httptest.NewServer(plugin.DebugHandler())
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "/config")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var active Config
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&active))
+	assert.Equal(t, cfg, active)
+}
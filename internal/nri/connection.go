@@ -0,0 +1,64 @@
+package nri
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// The bounds of the exponential backoff used to reconnect to the runtime.
+// They are changed only by the tests.
+var (
+	reconnectInitialBackoff = time.Second
+	reconnectMaxBackoff     = 30 * time.Second
+)
+
+// Run connects the plugin to the runtime until the context is done. The
+// connection is established again, with an exponential backoff, whenever it
+// is lost, e.g. when the runtime is restarted. The runtime then synchronizes
+// the state of the plugin through Synchronize.
+func (p *Plugin) Run(ctx context.Context) error {
+	backoff := reconnectInitialBackoff
+
+	for {
+		syncs := p.syncs.Load()
+		err := p.Stub.Run(ctx)
+		p.connected.Store(false)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// Start again from the shortest delay once connected, the runtime
+		// was probably restarted
+		if p.syncs.Load() != syncs {
+			backoff = reconnectInitialBackoff
+		}
+
+		p.Logger.WarnContext(ctx, "disconnected from the runtime, reconnecting",
+			slog.Duration("backoff", backoff),
+			slog.Any("err", err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(2*backoff, reconnectMaxBackoff)
+	}
+}
+
+// Connected returns true when the plugin is registered to the runtime and
+// its state is synchronized with it.
+func (p *Plugin) Connected() bool {
+	return p.connected.Load()
+}
+
+// OnClose is called by the stub when the connection to the runtime is lost.
+// The plugin is connected again by Run.
+func (p *Plugin) OnClose() {
+	p.connected.Store(false)
+	p.Logger.Warn("Connection to the runtime lost")
+}
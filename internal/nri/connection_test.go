@@ -0,0 +1,92 @@
+package nri
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/containerd/nri/pkg/stub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStub simulates the connections to the runtime, each call to Run is
+// handled by the next function.
+type fakeStub struct {
+	stub.Stub
+
+	runs  []func(ctx context.Context) error
+	calls int
+}
+
+func (s *fakeStub) Run(ctx context.Context) error {
+	run := s.runs[s.calls]
+	s.calls++
+	return run(ctx)
+}
+
+func TestRun_Reconnects(t *testing.T) {
+	original := reconnectInitialBackoff
+	reconnectInitialBackoff = time.Millisecond
+	t.Cleanup(func() { reconnectInitialBackoff = original })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	plugin := &Plugin{Logger: slog.New(slog.DiscardHandler), RunDir: t.TempDir()}
+	fake := &fakeStub{
+		runs: []func(ctx context.Context) error{
+			// Connected, then the runtime is restarted
+			func(ctx context.Context) error {
+				_, err := plugin.Synchronize(ctx, nil, nil)
+				require.NoError(t, err)
+				assert.True(t, plugin.Connected())
+
+				plugin.OnClose()
+				assert.False(t, plugin.Connected())
+				return errors.New("ttrpc: server closed")
+			},
+			// The runtime is not listening yet
+			func(context.Context) error {
+				return errors.New("failed to connect to NRI service")
+			},
+			// Connected again, until the plugin is stopped
+			func(ctx context.Context) error {
+				_, err := plugin.Synchronize(ctx, nil, nil)
+				require.NoError(t, err)
+				assert.True(t, plugin.Connected())
+
+				cancel()
+				return ctx.Err()
+			},
+		},
+	}
+	plugin.Stub = fake
+
+	err := plugin.Run(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 3, fake.calls)
+	assert.False(t, plugin.Connected())
+	assert.Equal(t, uint64(2), plugin.syncs.Load())
+}
+
+func TestRun_StopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	plugin := &Plugin{Logger: slog.New(slog.DiscardHandler)}
+	fake := &fakeStub{
+		runs: []func(ctx context.Context) error{
+			func(context.Context) error {
+				cancel()
+				return errors.New("failed to connect to NRI service")
+			},
+		},
+	}
+	plugin.Stub = fake
+
+	err := plugin.Run(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, fake.calls)
+}
@@ -21,10 +21,23 @@ const (
 	// where PodLock stores the swapped binaries
 	PodLockContainerSwappedBinariesDir = "/.podlock/swapped-binaries/"
 
+	// PodLockContainerRulesetsDir is the directory inside the container
+	// where seal reads the rulesets compiled by the PodLock NRI plugin
+	PodLockContainerRulesetsDir = "/.podlock/rulesets/"
+
 	// PodLockVarRunDir is the directory where PodLock NRI plugin stores
 	// runtime files. It's the same dir inside the container and on the host.
 	PodLockVarRunDir = "/var/run/podlock/"
 
+	// PodLockOfflineCacheDir is the directory where PodLock NRI plugin stores
+	// the offline cache. Unlike PodLockVarRunDir, it survives the reboots
+	// of the node. It's the same dir inside the container and on the host.
+	PodLockOfflineCacheDir = "/var/lib/podlock/cache/"
+
+	// PodLockDebugSocket is the unix socket where PodLock NRI plugin serves
+	// its debug API. It's the same path inside the container and on the host.
+	PodLockDebugSocket = "/var/run/podlock/debug.sock"
+
 	// ContainerProfileName is the name of the file where the landlock profile
 	// is storedr.
 	ContainerProfileName = "profile.json"
@@ -0,0 +1,239 @@
+package nri
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/flavio/podlock/internal/seal"
+)
+
+// HookStatus tells whether swap-oci-hook failed for a container.
+type HookStatus string
+
+const (
+	// HookStatusFailed is reported when swap-oci-hook recorded failures.
+	HookStatusFailed HookStatus = "Failed"
+	// HookStatusNoFailures is reported when swap-oci-hook recorded no
+	// failure. The hook records only its failures, hence this does not
+	// tell whether it has already run.
+	HookStatusNoFailures HookStatus = "NoFailures"
+)
+
+// DebugPod describes the runtime files of a pod, as served by the debug API.
+type DebugPod struct {
+	ID string `json:"id"`
+	// Namespace and Name are empty when the pod is not known to the plugin,
+	// e.g. when no container of the pod has been created since it started
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Live tells whether the pod is running according to the runtime, the
+	// runtime files of the pods that are not live are garbage collected
+	Live       bool             `json:"live"`
+	Containers []DebugContainer `json:"containers"`
+}
+
+// DebugContainer describes the runtime files of a container, as served by
+// the debug API.
+type DebugContainer struct {
+	Name string `json:"name"`
+	// Instances are the IDs of the container instances using the files
+	Instances       []string          `json:"instances"`
+	Profile         *seal.ProfileFile `json:"profile,omitempty"`
+	ProfileHash     string            `json:"profileHash,omitempty"`
+	SwappedBinaries []string          `json:"swappedBinaries"`
+	HookStatus      HookStatus        `json:"hookStatus"`
+	HookFailures    []string          `json:"hookFailures,omitempty"`
+}
+
+// DebugPods describes the pods having runtime files on the node, sorted by
+// pod ID. The pods and the containers whose runtime files are removed while
+// they are listed, e.g. by the garbage collection, are skipped.
+func (p *Plugin) DebugPods() ([]DebugPod, error) {
+	entries, err := os.ReadDir(p.runDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return []DebugPod{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PodLock runtime dir '%s': %w", p.runDir(), err)
+	}
+
+	pods := []DebugPod{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		pod, err := p.debugPod(entry.Name())
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, pod)
+	}
+
+	return pods, nil
+}
+
+// debugPod describes the runtime files of the pod.
+func (p *Plugin) debugPod(podID string) (DebugPod, error) {
+	pod := DebugPod{ID: podID, Containers: []DebugContainer{}}
+	if name, found := p.state.podName(podID); found {
+		pod.Namespace, pod.Name = name.Namespace, name.Name
+	}
+	if live, _, synced := p.state.snapshot(); synced {
+		pod.Live = live[podID]
+	}
+
+	entries, err := os.ReadDir(p.podDirOnHost(podID))
+	if err != nil {
+		return pod, fmt.Errorf("failed to read runtime dir of pod '%s': %w", podID, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		ctr, err := p.debugContainer(podID, entry.Name())
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return pod, err
+		}
+		pod.Containers = append(pod.Containers, ctr)
+
+		// The profiles are in the namespace of the pod
+		if pod.Namespace == "" && ctr.Profile != nil {
+			pod.Namespace = ctr.Profile.Source.Namespace
+		}
+	}
+
+	return pod, nil
+}
+
+// debugContainer describes the runtime files of the container.
+func (p *Plugin) debugContainer(podID, containerName string) (DebugContainer, error) {
+	ctr := DebugContainer{
+		Name:            containerName,
+		Instances:       []string{},
+		SwappedBinaries: []string{},
+		HookStatus:      HookStatusNoFailures,
+	}
+	dir := p.containerDirOnHost(podID, containerName)
+	if _, err := os.Stat(dir); err != nil {
+		return ctr, fmt.Errorf("failed to read runtime dir of container '%s': %w", containerName, err)
+	}
+
+	refs, err := os.ReadDir(filepath.Join(dir, containerRefsDirName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return ctr, fmt.Errorf("failed to read refs of container '%s': %w", containerName, err)
+	}
+	for _, ref := range refs {
+		ctr.Instances = append(ctr.Instances, ref.Name())
+	}
+
+	if f, err := os.Open(p.landlockProfilePathOnHost(podID, containerName)); err == nil {
+		ctr.Profile, err = seal.ParseProfileFile(f)
+		f.Close()
+		if err != nil {
+			return ctr, fmt.Errorf("failed to parse profile of container '%s': %w", containerName, err)
+		}
+		if ctr.ProfileHash, err = seal.ContainerProfileHash(ctr.Profile.Profiles); err != nil {
+			return ctr, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return ctr, fmt.Errorf("failed to open profile of container '%s': %w", containerName, err)
+	}
+
+	swappedDir := p.swappedBinaryPathOnHost(podID, containerName, "")
+	err = filepath.WalkDir(swappedDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			ctr.SwappedBinaries = append(ctr.SwappedBinaries, "/"+strings.TrimPrefix(path, swappedDir+"/"))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return ctr, fmt.Errorf("failed to read swapped binaries of container '%s': %w", containerName, err)
+	}
+
+	failures, err := os.ReadDir(p.hookFailuresDirOnHost(podID, containerName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return ctr, fmt.Errorf("failed to read hook failures of container '%s': %w", containerName, err)
+	}
+	for _, failure := range failures {
+		message, err := os.ReadFile(filepath.Join(p.hookFailuresDirOnHost(podID, containerName), failure.Name()))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return ctr, fmt.Errorf("failed to read hook failure of container '%s': %w", containerName, err)
+		}
+		ctr.HookFailures = append(ctr.HookFailures, string(message))
+		ctr.HookStatus = HookStatusFailed
+	}
+	slices.Sort(ctr.SwappedBinaries)
+
+	return ctr, nil
+}
+
+// DebugHandler returns the handler of the debug API:
+//
+//   - GET /pods lists the pods having runtime files on the node.
+//   - GET /pods/{id} describes a single pod.
+//   - GET /config returns the active configuration of the plugin.
+//
+// The API exposes the profiles of the containers, it must be served only
+// to the administrators of the node, e.g. over a unix socket.
+func (p *Plugin) DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pods", func(w http.ResponseWriter, _ *http.Request) {
+		pods, err := p.DebugPods()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, pods)
+	})
+	mux.HandleFunc("GET /pods/{id}", func(w http.ResponseWriter, r *http.Request) {
+		podID := r.PathValue("id")
+		if podID == "." || podID == ".." || strings.Contains(podID, "/") {
+			http.Error(w, "invalid pod ID", http.StatusBadRequest)
+			return
+		}
+		pod, err := p.debugPod(podID)
+		if errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, fmt.Sprintf("pod '%s' not found", podID), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, pod)
+	})
+	mux.HandleFunc("GET /config", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, p.settings())
+	})
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
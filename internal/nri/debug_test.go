@@ -0,0 +1,138 @@
+package nri
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+func TestDebugHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	plugin := &Plugin{
+		RunDir:   t.TempDir(),
+		LogLevel: "info",
+		Logger:   slog.New(slog.DiscardHandler),
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(newTestProfile()).Build(),
+	}
+	pod := &api.PodSandbox{
+		Id:        "pod-1",
+		Name:      "testpod",
+		Namespace: "default",
+		Uid:       "pod-uid",
+		Labels:    map[string]string{constants.PodProfileLabel: "nginx"},
+	}
+	_, err := plugin.Synchronize(context.Background(), []*api.PodSandbox{pod}, nil)
+	require.NoError(t, err)
+	_, _, err = plugin.CreateContainer(context.Background(), pod, &api.Container{Id: "ctr-1", PodSandboxId: "pod-1", Name: "main"})
+	require.NoError(t, err)
+
+	failures := plugin.hookFailuresDirOnHost("pod-1", "main")
+	require.NoError(t, os.MkdirAll(failures, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(failures, "1-10"), []byte("failed to swap '/usr/sbin/nginx'"), 0o600))
+
+	server := httptest.NewServer(plugin.DebugHandler())
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "/pods")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var pods []DebugPod
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&pods))
+	require.Len(t, pods, 1)
+	assert.Equal(t, "pod-1", pods[0].ID)
+	assert.Equal(t, "default", pods[0].Namespace)
+	assert.Equal(t, "testpod", pods[0].Name)
+	assert.True(t, pods[0].Live)
+	require.Len(t, pods[0].Containers, 1)
+
+	ctr := pods[0].Containers[0]
+	hash, err := seal.ContainerProfileHash(newTestProfile().Spec.ProfilesByContainer["main"])
+	require.NoError(t, err)
+	assert.Equal(t, "main", ctr.Name)
+	assert.Equal(t, []string{"ctr-1"}, ctr.Instances)
+	require.NotNil(t, ctr.Profile)
+	assert.Equal(t, "nginx", ctr.Profile.Source.Name)
+	assert.Equal(t, newTestProfile().Spec.ProfilesByContainer["main"], ctr.Profile.Profiles)
+	assert.Equal(t, hash, ctr.ProfileHash)
+	assert.Equal(t, []string{"/usr/sbin/nginx"}, ctr.SwappedBinaries)
+	assert.Equal(t, HookStatusFailed, ctr.HookStatus)
+	assert.Equal(t, []string{"failed to swap '/usr/sbin/nginx'"}, ctr.HookFailures)
+
+	resp, err = http.Get(server.URL + "/pods/pod-1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var single DebugPod
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&single))
+	assert.Equal(t, pods[0], single)
+
+	resp, err = http.Get(server.URL + "/pods/missing")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestDebugPods_MissingRunDir(t *testing.T) {
+	runDir := t.TempDir()
+	require.NoError(t, os.RemoveAll(runDir))
+
+	plugin := &Plugin{Logger: slog.New(slog.DiscardHandler), RunDir: runDir}
+	pods, err := plugin.DebugPods()
+	require.NoError(t, err)
+	assert.Empty(t, pods)
+}
+
+func TestDebugPods_PodRemovedDuringListing(t *testing.T) {
+	plugin := &Plugin{Logger: slog.New(slog.DiscardHandler), RunDir: t.TempDir()}
+	for i := range 20 {
+		require.NoError(t, os.MkdirAll(plugin.containerRefPathOnHost(fmt.Sprintf("pod-%d", i), "main", "ctr"), 0o750))
+	}
+
+	// The pod dirs are removed and created again while they are listed, like
+	// the garbage collection and the runtime do
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			podID := fmt.Sprintf("pod-%d", i%20)
+			_ = os.RemoveAll(plugin.podDirOnHost(podID))
+			_ = os.MkdirAll(plugin.containerRefPathOnHost(podID, "main", "ctr"), 0o750)
+		}
+	}()
+
+	for range 200 {
+		_, err := plugin.DebugPods()
+		if !assert.NoError(t, err) {
+			break
+		}
+	}
+	close(stop)
+	<-done
+}
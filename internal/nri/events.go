@@ -0,0 +1,154 @@
+package nri
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/nri/pkg/api"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/flavio/podlock/internal/seal"
+)
+
+// eventReportingController identifies the NRI plugin as the source of the
+// Events it creates.
+const eventReportingController = "podlock.kubewarden.io/nri"
+
+const (
+	// eventDedupInterval is the time during which the same Event is not
+	// recorded again for a pod, e.g. for the instances of a container
+	// restarting in a loop.
+	eventDedupInterval = 5 * time.Minute
+	// eventRate and eventBurst limit the Events created by the plugin, so
+	// that the API server is not flooded when many pods are created at once.
+	eventRate  = rate.Limit(5)
+	eventBurst = 25
+	// eventTimeout bounds the time spent creating an Event.
+	eventTimeout = 10 * time.Second
+)
+
+// eventLimiter rate limits the Events recorded by the plugin. Its zero value
+// is ready to use.
+type eventLimiter struct {
+	mu       sync.Mutex
+	limiter  *rate.Limiter
+	recorded map[string]time.Time
+}
+
+// allow tells whether the Event identified by key can be recorded now.
+func (l *eventLimiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limiter == nil {
+		l.limiter = rate.NewLimiter(eventRate, eventBurst)
+		l.recorded = map[string]time.Time{}
+	}
+
+	if last, found := l.recorded[key]; found && now.Sub(last) < eventDedupInterval {
+		return false
+	}
+	if !l.limiter.AllowN(now, 1) {
+		return false
+	}
+
+	// Forget the Events that can be recorded again, so that the map does not grow
+	maps.DeleteFunc(l.recorded, func(_ string, last time.Time) bool {
+		return now.Sub(last) >= eventDedupInterval
+	})
+	l.recorded[key] = now
+
+	return true
+}
+
+// recordPodEvent creates an Event regarding the pod. Recording is best
+// effort, failures are only logged. The Events are rate limited, the same
+// Event is recorded at most once per eventDedupInterval.
+//
+// The Event is created in the background, so that the request of the
+// runtime never waits for the API server.
+func (p *Plugin) recordPodEvent(ctx context.Context, pod *api.PodSandbox, eventType, reason, action, note string) {
+	// The API server is probably unreachable, the request of the runtime
+	// would time out while waiting for the Event to be created
+	if !p.cacheSynced() {
+		p.Logger.DebugContext(ctx, "cache not synced with the API server, not recording event",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.String("reason", reason),
+		)
+		return
+	}
+
+	if !p.events.allow(pod.GetUid()+"/"+reason+"/"+note, time.Now()) {
+		p.Logger.DebugContext(ctx, "event rate limited",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.String("reason", reason),
+		)
+		return
+	}
+
+	event := &eventsv1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: pod.GetName() + ".",
+			Namespace:    pod.GetNamespace(),
+		},
+		EventTime:           metav1.NewMicroTime(time.Now()),
+		ReportingController: eventReportingController,
+		ReportingInstance:   eventReportingController + "-" + p.NodeName,
+		Action:              action,
+		Reason:              reason,
+		Regarding: corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Name:       pod.GetName(),
+			Namespace:  pod.GetNamespace(),
+			UID:        types.UID(pod.GetUid()),
+		},
+		Note: note,
+		Type: eventType,
+	}
+
+	p.recording.Go(func() {
+		createCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), eventTimeout)
+		defer cancel()
+
+		if err := p.Client.Create(createCtx, event); err != nil {
+			p.Logger.WarnContext(createCtx, "failed to record event",
+				slog.String("pod", event.Regarding.Name),
+				slog.String("namespace", event.Regarding.Namespace),
+				slog.String("reason", reason),
+				slog.Any("err", err),
+			)
+		}
+	})
+}
+
+// profileVersion describes the version of the profile, for the notes of the
+// Events.
+func profileVersion(name string, generation, revision int64) string {
+	if revision > 0 {
+		return fmt.Sprintf("LandlockProfile %s revision %d (generation %d)", name, revision, generation)
+	}
+	return fmt.Sprintf("LandlockProfile %s (generation %d)", name, generation)
+}
+
+// profileAppliedNote returns the note of the Event recorded when the profile
+// is applied to a container.
+func profileAppliedNote(profileFile *seal.ProfileFile) string {
+	return fmt.Sprintf("%s applied to container %s, binaries: %s",
+		profileVersion(profileFile.Source.Name, profileFile.Generation, profileFile.Revision),
+		profileFile.Source.Container,
+		strings.Join(slices.Sorted(maps.Keys(profileFile.Profiles)), ", "),
+	)
+}
@@ -0,0 +1,123 @@
+package nri
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+func TestEventLimiter(t *testing.T) {
+	limiter := &eventLimiter{}
+	now := time.Now()
+
+	assert.True(t, limiter.allow("pod-uid/reason/note", now))
+	assert.False(t, limiter.allow("pod-uid/reason/note", now.Add(time.Minute)), "same event recorded again")
+	assert.True(t, limiter.allow("pod-uid/reason/other note", now.Add(time.Minute)))
+	assert.True(t, limiter.allow("pod-uid/reason/note", now.Add(eventDedupInterval)))
+
+	// The burst is exhausted by the events created at once
+	later := now.Add(time.Hour)
+	allowed := 0
+	for i := range 2 * eventBurst {
+		if limiter.allow(string(rune('a'+i)), later) {
+			allowed++
+		}
+	}
+	assert.Equal(t, eventBurst, allowed)
+}
+
+func TestCreateContainer_SealingEvents(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, eventsv1.AddToScheme(scheme))
+
+	profile := newTestProfile()
+	profile.Generation = 3
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(profile).Build()
+
+	plugin := &Plugin{
+		RunDir:   t.TempDir(),
+		LogLevel: "info",
+		Logger:   slog.New(slog.DiscardHandler),
+		Client:   kubeClient,
+	}
+	pod := &api.PodSandbox{
+		Id:        "pod-1",
+		Name:      "testpod",
+		Namespace: "default",
+		Uid:       "pod-uid",
+		Labels:    map[string]string{constants.PodProfileLabel: "nginx"},
+	}
+
+	for _, ctr := range []*api.Container{
+		{Id: "ctr-1", Name: "main"},
+		{Id: "ctr-2", Name: "sidecar"},
+		// The restarted container does not record the Event again
+		{Id: "ctr-3", Name: "main"},
+	} {
+		_, _, err := plugin.CreateContainer(context.Background(), pod, ctr)
+		require.NoError(t, err)
+	}
+
+	plugin.recording.Wait()
+	events := &eventsv1.EventList{}
+	require.NoError(t, kubeClient.List(context.Background(), events))
+	notes := map[string]string{}
+	for _, event := range events.Items {
+		notes[event.Reason] = event.Note
+		if event.Reason == constants.ProfileEntryMissingEventReason {
+			assert.Equal(t, corev1.EventTypeWarning, event.Type)
+		}
+	}
+	assert.Equal(t, map[string]string{
+		constants.ProfileAppliedEventReason: "LandlockProfile nginx (generation 3) applied to container main, " +
+			"binaries: /usr/sbin/nginx",
+		constants.ProfileEntryMissingEventReason: "LandlockProfile nginx (generation 3) has no entry for container sidecar, " +
+			"created without Landlock sandbox",
+	}, notes)
+	assert.Len(t, events.Items, 2)
+}
+
+func TestRecordPodEventInBackground(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, eventsv1.AddToScheme(scheme))
+
+	// The Event is held until the runtime request has been answered
+	unblock := make(chan struct{})
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				<-unblock
+				return c.Create(ctx, obj, opts...)
+			},
+		}).Build()
+	plugin := &Plugin{
+		Logger: slog.New(slog.DiscardHandler),
+		Client: kubeClient,
+	}
+
+	pod := &api.PodSandbox{Name: "testpod", Namespace: "default", Uid: "pod-uid"}
+	plugin.recordPodEvent(context.Background(), pod, corev1.EventTypeNormal, constants.ProfileAppliedEventReason,
+		"CreateContainer", "applied")
+	close(unblock)
+	plugin.recording.Wait()
+
+	events := &eventsv1.EventList{}
+	require.NoError(t, kubeClient.List(context.Background(), events))
+	require.Len(t, events.Items, 1)
+	assert.Equal(t, "applied", events.Items[0].Note)
+}
@@ -0,0 +1,149 @@
+package nri
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/containerd/nri/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/flavio/podlock/internal/profileref"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+// FailurePolicy tells what happens to a container whose LandlockProfile
+// cannot be retrieved, e.g. because the profile is missing or the API server
+// cannot be reached.
+type FailurePolicy string
+
+const (
+	// FailurePolicyDeny prevents the container from being created.
+	FailurePolicyDeny FailurePolicy = "Deny"
+	// FailurePolicyAllow creates the container without Landlock sandbox,
+	// an Event is recorded on the pod.
+	FailurePolicyAllow FailurePolicy = "Allow"
+	// FailurePolicyRetry retries to retrieve the profile until the retry
+	// timeout expires, then prevents the container from being created.
+	FailurePolicyRetry FailurePolicy = "Retry"
+)
+
+// DefaultFailureRetryTimeout is the default time spent retrying to retrieve
+// the profile with FailurePolicyRetry. It must be shorter than the request
+// timeout of the runtime, which defaults to 2 seconds.
+const DefaultFailureRetryTimeout = time.Second
+
+// failureRetryInterval is the delay between two attempts to retrieve the
+// profile with FailurePolicyRetry. It is changed only by the tests.
+var failureRetryInterval = 100 * time.Millisecond
+
+// ParseFailurePolicy converts the string into a FailurePolicy.
+func ParseFailurePolicy(value string) (FailurePolicy, error) {
+	switch policy := FailurePolicy(value); policy {
+	case FailurePolicyDeny, FailurePolicyAllow, FailurePolicyRetry:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid failure policy '%s', must be one of %s, %s, %s",
+			value, FailurePolicyDeny, FailurePolicyAllow, FailurePolicyRetry)
+	}
+}
+
+// failurePolicy returns the failure policy of the namespace, falling back to
+// the global one when the namespace does not set it.
+func (p *Plugin) failurePolicy(ctx context.Context, namespace string) FailurePolicy {
+	global := p.settings().FailurePolicy
+
+	// Reading from an unsynced cache would block
+	if !p.cacheSynced() {
+		return global
+	}
+
+	ns := &corev1.Namespace{}
+	if err := p.Client.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		p.Logger.DebugContext(ctx, "cannot get namespace, using the global failure policy",
+			slog.String("namespace", namespace),
+			slog.Any("err", err),
+		)
+		return global
+	}
+
+	value, found := ns.Labels[constants.NamespaceFailurePolicyLabel]
+	if !found {
+		return global
+	}
+	policy, err := ParseFailurePolicy(value)
+	if err != nil {
+		p.Logger.WarnContext(ctx, "invalid failure policy set on namespace, using the global one",
+			slog.String("namespace", namespace),
+			slog.Any("err", err),
+		)
+		return global
+	}
+
+	return policy
+}
+
+// lookupProfile runs the lookup of the profile of the container, according
+// to the failure policy of its namespace. True is returned when the lookup
+// failed but the container can be created without Landlock sandbox.
+func (p *Plugin) lookupProfile(
+	ctx context.Context,
+	pod *api.PodSandbox,
+	ctr *api.Container,
+	lookup func(context.Context) error,
+) (bool, error) {
+	err := lookup(ctx)
+	if err == nil {
+		return false, nil
+	}
+
+	policy := p.failurePolicy(ctx, pod.GetNamespace())
+
+	// The profile is known, since the lookup is done after parsing the reference
+	ref, _, _ := profileref.FromPod(pod.GetLabels(), pod.GetAnnotations())
+	profileLookupFailures.WithLabelValues(pod.GetNamespace(), ref.Name, string(policy)).Inc()
+
+	switch policy {
+	case FailurePolicyAllow:
+		p.Logger.WarnContext(ctx, "creating container without LandlockProfile",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.String("container name", ctr.GetName()),
+			slog.Any("err", err),
+		)
+		p.recordPodEvent(ctx, pod, corev1.EventTypeWarning, constants.ProfileUnavailableEventReason, "CreateContainer",
+			fmt.Sprintf("Container %s created without Landlock sandbox: %v", ctr.GetName(), err))
+		return true, nil
+	case FailurePolicyRetry:
+		err = p.retryLookup(ctx, lookup, err)
+	}
+
+	if err != nil {
+		p.recordPodEvent(ctx, pod, corev1.EventTypeWarning, constants.ProfileLookupFailedEventReason, "CreateContainer",
+			fmt.Sprintf("Container %s not created, LandlockProfile %s lookup failed: %v", ctr.GetName(), ref.Name, err))
+	}
+	return false, err
+}
+
+// retryLookup retries the lookup until it succeeds or the retry timeout
+// expires. The last error is returned in the latter case.
+func (p *Plugin) retryLookup(ctx context.Context, lookup func(context.Context) error, err error) error {
+	ctx, cancel := context.WithTimeout(ctx, p.settings().FailureRetryTimeout.Duration)
+	defer cancel()
+
+	ticker := time.NewTicker(failureRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("retry timeout expired: %w", err)
+		case <-ticker.C:
+			if err = lookup(ctx); err == nil {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package nri
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+func TestParseFailurePolicy(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected FailurePolicy
+		wantErr  bool
+	}{
+		{value: "Deny", expected: FailurePolicyDeny},
+		{value: "Allow", expected: FailurePolicyAllow},
+		{value: "Retry", expected: FailurePolicyRetry},
+		{value: "allow", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			policy, err := ParseFailurePolicy(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, policy)
+		})
+	}
+}
+
+func TestCreateContainer_FailurePolicy(t *testing.T) {
+	original := failureRetryInterval
+	failureRetryInterval = time.Millisecond
+	t.Cleanup(func() { failureRetryInterval = original })
+
+	profile := &v1alpha1.LandlockProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+		Spec: v1alpha1.LandlockProfileSpec{
+			ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+				"main": {"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		globalPolicy    FailurePolicy
+		namespaceLabels map[string]string
+		// number of failed attempts to get the profile, -1 for always
+		failures       int
+		wantErr        bool
+		wantAdjustment bool
+		wantReason     string
+	}{
+		{
+			name:       "default policy denies",
+			failures:   -1,
+			wantErr:    true,
+			wantReason: constants.ProfileLookupFailedEventReason,
+		},
+		{
+			name:         "deny",
+			globalPolicy: FailurePolicyDeny,
+			failures:     -1,
+			wantErr:      true,
+			wantReason:   constants.ProfileLookupFailedEventReason,
+		},
+		{
+			name:         "allow",
+			globalPolicy: FailurePolicyAllow,
+			failures:     -1,
+			wantReason:   constants.ProfileUnavailableEventReason,
+		},
+		{
+			name:           "retry until the profile is found",
+			globalPolicy:   FailurePolicyRetry,
+			failures:       3,
+			wantAdjustment: true,
+			wantReason:     constants.ProfileAppliedEventReason,
+		},
+		{
+			name:         "retry until the timeout expires",
+			globalPolicy: FailurePolicyRetry,
+			failures:     -1,
+			wantErr:      true,
+			wantReason:   constants.ProfileLookupFailedEventReason,
+		},
+		{
+			name:            "namespace overrides the global policy",
+			globalPolicy:    FailurePolicyDeny,
+			namespaceLabels: map[string]string{constants.NamespaceFailurePolicyLabel: "Allow"},
+			failures:        -1,
+			wantReason:      constants.ProfileUnavailableEventReason,
+		},
+		{
+			name:            "invalid namespace policy is ignored",
+			globalPolicy:    FailurePolicyAllow,
+			namespaceLabels: map[string]string{constants.NamespaceFailurePolicyLabel: "allow"},
+			failures:        -1,
+			wantReason:      constants.ProfileUnavailableEventReason,
+		},
+		{
+			name:            "invalid namespace policy falls back to deny",
+			namespaceLabels: map[string]string{constants.NamespaceFailurePolicyLabel: "open"},
+			failures:        -1,
+			wantErr:         true,
+			wantReason:      constants.ProfileLookupFailedEventReason,
+		},
+		{
+			name:           "no failure",
+			globalPolicy:   FailurePolicyDeny,
+			wantAdjustment: true,
+			wantReason:     constants.ProfileAppliedEventReason,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			require.NoError(t, v1alpha1.AddToScheme(scheme))
+			require.NoError(t, corev1.AddToScheme(scheme))
+			require.NoError(t, eventsv1.AddToScheme(scheme))
+
+			namespace := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: tt.namespaceLabels},
+			}
+
+			attempts := 0
+			kubeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(profile.DeepCopy(), namespace).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+						if _, ok := obj.(*v1alpha1.LandlockProfile); ok {
+							attempts++
+							if tt.failures < 0 || attempts <= tt.failures {
+								return errors.New("connection refused")
+							}
+						}
+						return c.Get(ctx, key, obj, opts...)
+					},
+				}).
+				Build()
+
+			plugin := &Plugin{
+				RunDir:              t.TempDir(),
+				LogLevel:            "info",
+				Logger:              slog.New(slog.DiscardHandler),
+				Client:              kubeClient,
+				NodeName:            "node-1",
+				FailurePolicy:       tt.globalPolicy,
+				FailureRetryTimeout: 50 * time.Millisecond,
+			}
+
+			pod := &api.PodSandbox{
+				Id:        "pod-1",
+				Name:      "testpod",
+				Namespace: "default",
+				Uid:       "pod-uid",
+				Labels:    map[string]string{constants.PodProfileLabel: "nginx"},
+			}
+
+			adj, _, err := plugin.CreateContainer(context.Background(), pod, &api.Container{Id: "ctr-1", Name: "main"})
+			if tt.wantErr {
+				require.ErrorContains(t, err, "connection refused")
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantAdjustment, adj != nil)
+
+			plugin.recording.Wait()
+			events := &eventsv1.EventList{}
+			require.NoError(t, kubeClient.List(context.Background(), events))
+			require.Len(t, events.Items, 1)
+			event := events.Items[0]
+			assert.Equal(t, tt.wantReason, event.Reason)
+			assert.Equal(t, "testpod", event.Regarding.Name)
+			assert.Equal(t, "default", event.Namespace)
+			assert.Equal(t, "podlock.kubewarden.io/nri-node-1", event.ReportingInstance)
+			if tt.wantReason == constants.ProfileUnavailableEventReason {
+				assert.Equal(t, corev1.EventTypeWarning, event.Type)
+				assert.Contains(t, event.Note, "Container main created without Landlock sandbox")
+			}
+		})
+	}
+}
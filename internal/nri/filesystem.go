@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 
 	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
 )
 
 // fileSha256 returns the SHA256 checksum of the given file.
@@ -101,11 +102,9 @@ func SwappedBinaryPathInsideContainer(originalBinaryPath string) string {
 // For example, for pod ID "pod123", container name "ctr1", and original binary
 // "/usr/bin/curl", the swapped binary path on the host will be
 // "/var/run/podlock/pod123/ctr1/swapped-binaries/usr/bin/curl".
-func swappedBinaryPathOnHost(podID, containerName, originalBinaryPath string) string {
+func (p *Plugin) swappedBinaryPathOnHost(podID, containerName, originalBinaryPath string) string {
 	return filepath.Join(
-		PodLockVarRunDir,
-		podID,
-		containerName,
+		p.containerDirOnHost(podID, containerName),
 		"swapped-binaries",
 		originalBinaryPath,
 	)
@@ -119,7 +118,7 @@ func (p *Plugin) reserveSwappedBinaries(
 	profileByBinary podlockv1alpha1.ProfileByBinary,
 ) error {
 	for binary := range profileByBinary {
-		swappedBin := swappedBinaryPathOnHost(podID, containerName, binary)
+		swappedBin := p.swappedBinaryPathOnHost(podID, containerName, binary)
 		p.Logger.Debug(
 			"reserving swapped binary path",
 			slog.String("pod ID", podID),
@@ -166,11 +165,44 @@ func (p *Plugin) reserveSwappedBinaries(
 	return nil
 }
 
-func landlockProfilePathOnHost(podID, containerName string) string {
+// rulesetsDirOnHost returns the directory on the host where the plugin stores
+// the compiled rulesets of the given container.
+func (p *Plugin) rulesetsDirOnHost(podID, containerName string) string {
 	return filepath.Join(
-		PodLockVarRunDir,
-		podID,
-		containerName,
+		p.containerDirOnHost(podID, containerName),
+		"rulesets",
+	)
+}
+
+// prepareRulesetsDir creates an empty directory for the compiled rulesets of
+// the container. Stale rulesets are removed, since the profile of the container
+// might have changed.
+//
+// The directory is written only by the plugin, see compileRulesets. It is
+// readable by all the users of the container, which cannot write to it.
+func (p *Plugin) prepareRulesetsDir(podID, containerName string) error {
+	dir := p.rulesetsDirOnHost(podID, containerName)
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove rulesets dir '%s': %w", dir, err)
+	}
+	//nolint:gosec // the directory must be readable by all the users of the container
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create rulesets dir '%s': %w", dir, err)
+	}
+	//nolint:gosec // the directory must be readable by all the users of the container
+	if err := os.Chmod(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to set permissions of rulesets dir '%s': %w", dir, err)
+	}
+
+	p.Logger.Debug("prepared rulesets dir", slog.String("path", dir))
+
+	return nil
+}
+
+func (p *Plugin) landlockProfilePathOnHost(podID, containerName string) string {
+	return filepath.Join(
+		p.containerDirOnHost(podID, containerName),
 		ContainerProfileName,
 	)
 }
@@ -178,15 +210,17 @@ func landlockProfilePathOnHost(podID, containerName string) string {
 func (p *Plugin) writeLandlockProfileToHostFilesystem(
 	podID,
 	containerName string,
-	profileByBinary podlockv1alpha1.ProfileByBinary,
+	profileFile *seal.ProfileFile,
 ) error {
-	landlockProfilePath := landlockProfilePathOnHost(podID, containerName)
+	landlockProfilePath := p.landlockProfilePathOnHost(podID, containerName)
 
 	p.Logger.Debug(
 		"writing landlock profile to host filesystem",
 		slog.String("pod ID", podID),
 		slog.String("container name", containerName),
 		slog.String("landlock profile path", landlockProfilePath),
+		slog.String("apiVersion", profileFile.APIVersion),
+		slog.Int64("generation", profileFile.Generation),
 	)
 
 	// Create the directory for the landlock profile
@@ -219,7 +253,7 @@ func (p *Plugin) writeLandlockProfileToHostFilesystem(
 
 	encoder := json.NewEncoder(f)
 	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(profileByBinary); err != nil {
+	if err := encoder.Encode(profileFile); err != nil {
 		return fmt.Errorf(
 			"failed to write landlock profile JSON to file '%s': %w",
 			landlockProfilePath,
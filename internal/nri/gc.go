@@ -0,0 +1,368 @@
+package nri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/containerd/nri/pkg/api"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// gcMinAge is how old the runtime files must be before being garbage
+// collected. It prevents removing the files of a container created while the
+// garbage collection is running.
+const gcMinAge = time.Minute
+
+// DefaultGCInterval is the default interval between two sweeps of the stale
+// runtime files.
+const DefaultGCInterval = 10 * time.Minute
+
+// runtimeState tracks the pods and the containers using runtime files on the
+// host. It is seeded by the runtime when the plugin connects to it, then kept
+// up to date by the container and pod sandbox events.
+type runtimeState struct {
+	mu         sync.Mutex
+	synced     bool
+	pods       map[string]bool
+	containers map[string]bool
+	// names holds the namespace and the name of the pods, by pod ID
+	names map[string]types.NamespacedName
+}
+
+// reset replaces the known state with the pods and the containers running
+// inside of the runtime.
+func (s *runtimeState) reset(pods []*api.PodSandbox, containers []*api.Container) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pods = make(map[string]bool, len(pods))
+	s.names = make(map[string]types.NamespacedName, len(pods))
+	for _, pod := range pods {
+		s.pods[pod.GetId()] = true
+		s.names[pod.GetId()] = types.NamespacedName{Namespace: pod.GetNamespace(), Name: pod.GetName()}
+	}
+	s.containers = make(map[string]bool, len(containers))
+	for _, ctr := range containers {
+		s.containers[ctr.GetId()] = true
+		s.pods[ctr.GetPodSandboxId()] = true
+	}
+	s.synced = true
+}
+
+func (s *runtimeState) addContainer(podID, containerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pods == nil {
+		s.pods = map[string]bool{}
+		s.containers = map[string]bool{}
+	}
+	s.pods[podID] = true
+	s.containers[containerID] = true
+}
+
+// trackPodName records the namespace and the name of the pod.
+func (s *runtimeState) trackPodName(pod *api.PodSandbox) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.names == nil {
+		s.names = map[string]types.NamespacedName{}
+	}
+	s.names[pod.GetId()] = types.NamespacedName{Namespace: pod.GetNamespace(), Name: pod.GetName()}
+}
+
+// podName returns the namespace and the name of the pod, false is returned
+// when they are not known.
+func (s *runtimeState) podName(podID string) (types.NamespacedName, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, found := s.names[podID]
+	return name, found
+}
+
+func (s *runtimeState) removeContainer(containerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.containers, containerID)
+}
+
+func (s *runtimeState) removePod(podID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pods, podID)
+	delete(s.names, podID)
+}
+
+// snapshot returns a copy of the live pods and containers. False is returned
+// when the state has not been synchronized with the runtime yet.
+func (s *runtimeState) snapshot() (map[string]bool, map[string]bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.synced {
+		return nil, nil, false
+	}
+
+	pods := make(map[string]bool, len(s.pods))
+	for id := range s.pods {
+		pods[id] = true
+	}
+	containers := make(map[string]bool, len(s.containers))
+	for id := range s.containers {
+		containers[id] = true
+	}
+
+	return pods, containers, true
+}
+
+// gcResult reports the runtime files removed by a garbage collection.
+type gcResult struct {
+	Pods       int
+	Containers int
+	Refs       int
+	Bytes      int64
+}
+
+func (r gcResult) empty() bool {
+	return r.Pods == 0 && r.Containers == 0 && r.Refs == 0
+}
+
+// Synchronize is called by the runtime when the plugin connects to it. The
+// runtime files of the pods and the containers that do not exist anymore are
+// removed, e.g. the ones leaked while the plugin was not running.
+func (p *Plugin) Synchronize(ctx context.Context, pods []*api.PodSandbox, containers []*api.Container) ([]*api.ContainerUpdate, error) {
+	p.Logger.InfoContext(ctx, "Synchronize called",
+		slog.Int("pods", len(pods)),
+		slog.Int("containers", len(containers)),
+	)
+
+	p.state.reset(pods, containers)
+
+	// Stale runtime files must not prevent the plugin from starting
+	p.collectGarbage(ctx)
+
+	p.syncs.Add(1)
+	p.connected.Store(true)
+
+	return nil, nil
+}
+
+// RunGarbageCollection periodically removes the stale runtime files until
+// the context is done.
+func (p *Plugin) RunGarbageCollection(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.collectGarbage(ctx)
+		}
+	}
+}
+
+// collectGarbage removes the runtime files of the pods and the containers
+// that are not running anymore, and logs how much space was reclaimed.
+func (p *Plugin) collectGarbage(ctx context.Context) {
+	pods, containers, synced := p.state.snapshot()
+	if !synced {
+		p.Logger.DebugContext(ctx, "runtime state not synchronized yet, skipping garbage collection")
+		return
+	}
+
+	p.reportAllHookFailures(ctx)
+
+	result, err := p.removeStaleRuntimeFiles(pods, containers, time.Now().Add(-gcMinAge))
+	if err != nil {
+		p.Logger.ErrorContext(ctx, "failed to garbage collect PodLock runtime files", slog.Any("err", err))
+	}
+	gcReclaimedBytes.Add(float64(result.Bytes))
+
+	if size, err := diskUsage(p.runDir()); err == nil {
+		runtimeDirBytes.Set(float64(size))
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		p.Logger.WarnContext(ctx, "failed to compute the size of the PodLock runtime files", slog.Any("err", err))
+	}
+
+	if result.empty() {
+		p.Logger.DebugContext(ctx, "no stale PodLock runtime files found")
+		return
+	}
+
+	p.Logger.InfoContext(ctx, "garbage collected stale PodLock runtime files",
+		slog.Int("pods", result.Pods),
+		slog.Int("containers", result.Containers),
+		slog.Int("refs", result.Refs),
+		slog.Int64("bytes", result.Bytes),
+	)
+}
+
+// removeStaleRuntimeFiles removes the runtime files of the pods and the
+// container instances that are not live. Only the files last modified
+// before the given time are considered. The removal goes on when a file
+// cannot be removed, all the errors are returned together.
+func (p *Plugin) removeStaleRuntimeFiles(pods, containers map[string]bool, before time.Time) (gcResult, error) {
+	var result gcResult
+
+	podEntries, err := os.ReadDir(p.runDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to read PodLock runtime dir '%s': %w", p.runDir(), err)
+	}
+
+	var errs []error
+	for _, podEntry := range podEntries {
+		if !podEntry.IsDir() {
+			continue
+		}
+		podID := podEntry.Name()
+
+		if !pods[podID] {
+			removed, size, err := removeStaleDir(p.podDirOnHost(podID), before)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if removed {
+				result.Pods++
+				result.Bytes += size
+				p.Logger.Debug("removed stale pod runtime dir", slog.String("pod ID", podID))
+			}
+			continue
+		}
+
+		ctrEntries, err := os.ReadDir(p.podDirOnHost(podID))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read runtime dir of pod '%s': %w", podID, err))
+			continue
+		}
+		for _, ctrEntry := range ctrEntries {
+			if !ctrEntry.IsDir() {
+				continue
+			}
+			refs, removed, size, err := p.removeStaleContainerDir(podID, ctrEntry.Name(), containers, before)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			result.Refs += refs
+			if removed {
+				result.Containers++
+				result.Bytes += size
+				p.Logger.Debug("removed stale container runtime dir",
+					slog.String("pod ID", podID),
+					slog.String("container name", ctrEntry.Name()),
+				)
+			}
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// removeStaleContainerDir drops the references of the container instances
+// that are not live, then removes the runtime files of the container when
+// no instance uses them anymore. The runtime files of a container without
+// refs dir are left untouched, since its users are not known.
+func (p *Plugin) removeStaleContainerDir(
+	podID, containerName string,
+	containers map[string]bool,
+	before time.Time,
+) (int, bool, int64, error) {
+	refsDir := filepath.Join(p.containerDirOnHost(podID, containerName), containerRefsDirName)
+
+	refs, err := os.ReadDir(refsDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, false, 0, nil
+	}
+	if err != nil {
+		return 0, false, 0, fmt.Errorf("failed to read refs of container '%s': %w", containerName, err)
+	}
+
+	removedRefs := 0
+	for _, ref := range refs {
+		if containers[ref.Name()] {
+			continue
+		}
+		removed, _, err := removeStaleDir(filepath.Join(refsDir, ref.Name()), before)
+		if err != nil {
+			return removedRefs, false, 0, err
+		}
+		if removed {
+			removedRefs++
+		}
+	}
+
+	if refs, err = os.ReadDir(refsDir); err != nil {
+		return removedRefs, false, 0, fmt.Errorf("failed to read refs of container '%s': %w", containerName, err)
+	}
+	if len(refs) > 0 {
+		return removedRefs, false, 0, nil
+	}
+
+	removed, size, err := removeStaleDir(p.containerDirOnHost(podID, containerName), before)
+	return removedRefs, removed, size, err
+}
+
+// removeStaleDir removes the file or the directory when it was last modified
+// before the given time. The number of bytes reclaimed is returned.
+func removeStaleDir(path string, before time.Time) (bool, int64, error) {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to stat '%s': %w", path, err)
+	}
+	if !info.ModTime().Before(before) {
+		return false, 0, nil
+	}
+
+	size, err := diskUsage(path)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if err = os.RemoveAll(path); err != nil {
+		return false, 0, fmt.Errorf("failed to remove '%s': %w", path, err)
+	}
+
+	return true, size, nil
+}
+
+// diskUsage returns the size of the regular files found under path.
+func diskUsage(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute the size of '%s': %w", path, err)
+	}
+
+	return size, nil
+}
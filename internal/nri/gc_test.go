@@ -0,0 +1,196 @@
+package nri
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createRuntimeFiles creates the runtime files of a container instance, with
+// a profile of the given size, last modified at the given time.
+func createRuntimeFiles(t *testing.T, runDir, podID, containerName, containerID string, size int, modTime time.Time) {
+	t.Helper()
+
+	plugin := &Plugin{Logger: slog.New(slog.DiscardHandler), RunDir: runDir}
+	require.NoError(t, plugin.acquireContainerDir(podID, containerName, containerID))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(plugin.containerDirOnHost(podID, containerName), "profile.json"),
+		make([]byte, size),
+		0o600,
+	))
+
+	require.NoError(t, filepath.WalkDir(plugin.podDirOnHost(podID), func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Chtimes(path, modTime, modTime)
+	}))
+}
+
+func TestSynchronize_RemovesStaleRuntimeFiles(t *testing.T) {
+	plugin := &Plugin{Logger: slog.New(slog.DiscardHandler), RunDir: t.TempDir()}
+
+	old := time.Now().Add(-time.Hour)
+	createRuntimeFiles(t, plugin.RunDir, "live-pod", "main", "live-ctr", 10, old)
+	createRuntimeFiles(t, plugin.RunDir, "live-pod", "main", "old-ctr", 10, old)
+	createRuntimeFiles(t, plugin.RunDir, "live-pod", "sidecar", "removed-ctr", 20, old)
+	createRuntimeFiles(t, plugin.RunDir, "removed-pod", "main", "removed-pod-ctr", 30, old)
+
+	_, err := plugin.Synchronize(context.Background(),
+		[]*api.PodSandbox{{Id: "live-pod"}},
+		[]*api.Container{{Id: "live-ctr", PodSandboxId: "live-pod", Name: "main"}},
+	)
+	require.NoError(t, err)
+
+	assert.FileExists(t, plugin.containerRefPathOnHost("live-pod", "main", "live-ctr"))
+	assert.NoFileExists(t, plugin.containerRefPathOnHost("live-pod", "main", "old-ctr"))
+	assert.NoDirExists(t, plugin.containerDirOnHost("live-pod", "sidecar"))
+	assert.NoDirExists(t, plugin.podDirOnHost("removed-pod"))
+}
+
+func TestRemoveStaleRuntimeFiles(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		setup      func(t *testing.T, plugin *Plugin)
+		pods       map[string]bool
+		containers map[string]bool
+		expected   gcResult
+		// paths relative to the run dir
+		remaining []string
+		removed   []string
+	}{
+		{
+			name: "nothing to collect",
+			setup: func(t *testing.T, plugin *Plugin) {
+				createRuntimeFiles(t, plugin.RunDir, "pod", "main", "ctr", 10, old)
+			},
+			pods:       map[string]bool{"pod": true},
+			containers: map[string]bool{"ctr": true},
+			remaining:  []string{"pod/main/refs/ctr"},
+		},
+		{
+			name: "stale pod",
+			setup: func(t *testing.T, plugin *Plugin) {
+				createRuntimeFiles(t, plugin.RunDir, "pod", "main", "ctr", 10, old)
+				createRuntimeFiles(t, plugin.RunDir, "pod", "sidecar", "sidecar-ctr", 20, old)
+			},
+			pods:       map[string]bool{},
+			containers: map[string]bool{},
+			expected:   gcResult{Pods: 1, Bytes: 30},
+			removed:    []string{"pod"},
+		},
+		{
+			name: "stale container of a live pod",
+			setup: func(t *testing.T, plugin *Plugin) {
+				createRuntimeFiles(t, plugin.RunDir, "pod", "main", "ctr", 10, old)
+				createRuntimeFiles(t, plugin.RunDir, "pod", "init", "init-ctr", 20, old)
+			},
+			pods:       map[string]bool{"pod": true},
+			containers: map[string]bool{"ctr": true},
+			expected:   gcResult{Containers: 1, Refs: 1, Bytes: 20},
+			remaining:  []string{"pod/main/refs/ctr"},
+			removed:    []string{"pod/init"},
+		},
+		{
+			name: "stale instance of a restarted container",
+			setup: func(t *testing.T, plugin *Plugin) {
+				createRuntimeFiles(t, plugin.RunDir, "pod", "main", "old-ctr", 10, old)
+				createRuntimeFiles(t, plugin.RunDir, "pod", "main", "new-ctr", 10, old)
+			},
+			pods:       map[string]bool{"pod": true},
+			containers: map[string]bool{"new-ctr": true},
+			expected:   gcResult{Refs: 1},
+			remaining:  []string{"pod/main/refs/new-ctr"},
+			removed:    []string{"pod/main/refs/old-ctr"},
+		},
+		{
+			name: "recently modified files are kept",
+			setup: func(t *testing.T, plugin *Plugin) {
+				createRuntimeFiles(t, plugin.RunDir, "pod", "main", "ctr", 10, time.Now())
+				createRuntimeFiles(t, plugin.RunDir, "other-pod", "main", "other-ctr", 10, time.Now())
+			},
+			pods:       map[string]bool{"pod": true},
+			containers: map[string]bool{},
+			remaining: []string{
+				"pod/main/refs/ctr",
+				"other-pod/main/refs/other-ctr",
+			},
+		},
+		{
+			name: "container without refs is kept",
+			setup: func(t *testing.T, plugin *Plugin) {
+				createRuntimeFiles(t, plugin.RunDir, "pod", "main", "ctr", 10, old)
+				require.NoError(t, os.RemoveAll(filepath.Join(plugin.containerDirOnHost("pod", "main"), containerRefsDirName)))
+			},
+			pods:       map[string]bool{"pod": true},
+			containers: map[string]bool{},
+			remaining:  []string{"pod/main/profile.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runDir := t.TempDir()
+			plugin := &Plugin{Logger: slog.New(slog.DiscardHandler), RunDir: runDir}
+			tt.setup(t, plugin)
+
+			result, err := plugin.removeStaleRuntimeFiles(tt.pods, tt.containers, time.Now().Add(-gcMinAge))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+
+			for _, path := range tt.remaining {
+				assert.FileExists(t, filepath.Join(runDir, path))
+			}
+			for _, path := range tt.removed {
+				assert.NoFileExists(t, filepath.Join(runDir, path))
+				assert.NoDirExists(t, filepath.Join(runDir, path))
+			}
+		})
+	}
+}
+
+func TestRemoveStaleRuntimeFiles_MissingRunDir(t *testing.T) {
+	runDir := t.TempDir()
+	runDir = filepath.Join(runDir, "missing")
+
+	plugin := &Plugin{Logger: slog.New(slog.DiscardHandler), RunDir: runDir}
+	result, err := plugin.removeStaleRuntimeFiles(nil, nil, time.Now())
+	require.NoError(t, err)
+	assert.True(t, result.empty())
+}
+
+func TestCollectGarbage_TracksEvents(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	ctx := context.Background()
+	plugin := &Plugin{Logger: slog.New(slog.DiscardHandler), RunDir: t.TempDir()}
+
+	// Nothing is collected before the state is synchronized with the runtime
+	createRuntimeFiles(t, plugin.RunDir, "pod", "main", "ctr", 10, old)
+	plugin.collectGarbage(ctx)
+	require.DirExists(t, plugin.podDirOnHost("pod"))
+
+	_, err := plugin.Synchronize(ctx, nil, nil)
+	require.NoError(t, err)
+	require.NoDirExists(t, plugin.podDirOnHost("pod"))
+
+	// Containers created after the synchronization are tracked
+	require.NoError(t, plugin.acquireContainerDir("pod", "main", "ctr"))
+	plugin.collectGarbage(ctx)
+	require.FileExists(t, plugin.containerRefPathOnHost("pod", "main", "ctr"))
+
+	// The pod is forgotten once its sandbox is stopped
+	plugin.state.removePod("pod")
+	createRuntimeFiles(t, plugin.RunDir, "pod", "main", "ctr", 10, old)
+	plugin.collectGarbage(ctx)
+	assert.NoDirExists(t, plugin.podDirOnHost("pod"))
+}
@@ -0,0 +1,112 @@
+package nri
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/flavio/podlock/internal/seal"
+)
+
+// hookFailuresDirName is the directory of the runtime files of a container
+// where swap-oci-hook records its failures, one file per failure holding the
+// error message. The plugin cannot see these failures otherwise, since the
+// hook is run by the OCI runtime.
+const hookFailuresDirName = "hook-failures"
+
+// hookFailureReportedSuffix is appended to the name of the failures once
+// they are reported. They are kept until the runtime files of the container
+// are removed, so that the debug API can list them.
+const hookFailureReportedSuffix = ".reported"
+
+// hookFailuresDirOnHost returns the directory on the host where swap-oci-hook
+// records its failures.
+func (p *Plugin) hookFailuresDirOnHost(podID, containerName string) string {
+	return filepath.Join(p.containerDirOnHost(podID, containerName), hookFailuresDirName)
+}
+
+// reportHookFailures reports the failures recorded by swap-oci-hook for the
+// container, then marks them as reported so that they are reported only once.
+func (p *Plugin) reportHookFailures(ctx context.Context, podID, containerName string) {
+	dir := p.hookFailuresDirOnHost(podID, containerName)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			p.Logger.WarnContext(ctx, "failed to read hook failures", slog.String("dir", dir), slog.Any("err", err))
+		}
+		return
+	}
+	failures := slices.DeleteFunc(entries, func(entry os.DirEntry) bool {
+		return strings.HasSuffix(entry.Name(), hookFailureReportedSuffix)
+	})
+	if len(failures) == 0 {
+		return
+	}
+
+	// The profile file tells which profile the container was using
+	namespace, profile := "", ""
+	if f, err := os.Open(p.landlockProfilePathOnHost(podID, containerName)); err == nil {
+		if profileFile, err := seal.ParseProfileFile(f); err == nil {
+			namespace, profile = profileFile.Source.Namespace, profileFile.Source.Name
+		}
+		f.Close()
+	}
+
+	for _, failure := range failures {
+		path := filepath.Join(dir, failure.Name())
+		message, err := os.ReadFile(path)
+		if err != nil {
+			p.Logger.WarnContext(ctx, "failed to read hook failure", slog.String("path", path), slog.Any("err", err))
+			continue
+		}
+
+		hookFailures.WithLabelValues(namespace, profile).Inc()
+		p.Logger.ErrorContext(ctx, "swap-oci-hook failed",
+			slog.String("pod ID", podID),
+			slog.String("namespace", namespace),
+			slog.String("container name", containerName),
+			slog.String("profile name", profile),
+			slog.String("err", string(message)),
+		)
+
+		if err = os.Rename(path, path+hookFailureReportedSuffix); err != nil {
+			p.Logger.WarnContext(ctx, "failed to mark hook failure as reported", slog.String("path", path), slog.Any("err", err))
+		}
+	}
+}
+
+// reportPodHookFailures reports the failures recorded by swap-oci-hook for
+// all the containers of the pod.
+func (p *Plugin) reportPodHookFailures(ctx context.Context, podID string) {
+	containers, err := os.ReadDir(p.podDirOnHost(podID))
+	if err != nil {
+		return
+	}
+
+	for _, ctr := range containers {
+		if ctr.IsDir() {
+			p.reportHookFailures(ctx, podID, ctr.Name())
+		}
+	}
+}
+
+// reportAllHookFailures reports the failures recorded by swap-oci-hook for
+// all the containers of the node.
+func (p *Plugin) reportAllHookFailures(ctx context.Context) {
+	pods, err := os.ReadDir(p.runDir())
+	if err != nil {
+		return
+	}
+
+	for _, pod := range pods {
+		if pod.IsDir() {
+			p.reportPodHookFailures(ctx, pod.Name())
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package nri
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+func TestReportHookFailures(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	plugin := &Plugin{
+		RunDir:   t.TempDir(),
+		LogLevel: "info",
+		Logger:   slog.New(slog.DiscardHandler),
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(newTestProfile()).Build(),
+	}
+	pod := &api.PodSandbox{
+		Id:        "pod-1",
+		Name:      "testpod",
+		Namespace: "default",
+		Uid:       "pod-uid",
+		Labels:    map[string]string{constants.PodProfileLabel: "nginx"},
+	}
+	ctr := &api.Container{Id: "ctr-1", PodSandboxId: "pod-1", Name: "main"}
+
+	_, _, err := plugin.CreateContainer(context.Background(), pod, ctr)
+	require.NoError(t, err)
+
+	// Failures recorded by swap-oci-hook
+	dir := plugin.hookFailuresDirOnHost("pod-1", "main")
+	require.NoError(t, os.MkdirAll(dir, 0o750))
+	for _, name := range []string{"1-10", "2-11"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("failed to swap '/usr/sbin/nginx'"), 0o600))
+	}
+
+	failures := testutil.ToFloat64(hookFailures.WithLabelValues("default", "nginx"))
+	plugin.reportHookFailures(context.Background(), "pod-1", "main")
+	assert.InDelta(t, failures+2, testutil.ToFloat64(hookFailures.WithLabelValues("default", "nginx")), 0)
+
+	// The failures are kept for the debug API
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.True(t, strings.HasSuffix(entry.Name(), hookFailureReportedSuffix))
+	}
+
+	// Failures are reported only once
+	plugin.reportHookFailures(context.Background(), "pod-1", "main")
+	assert.InDelta(t, failures+2, testutil.ToFloat64(hookFailures.WithLabelValues("default", "nginx")), 0)
+}
@@ -0,0 +1,196 @@
+package nri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/containerd/nri/pkg/api"
+)
+
+// runDir returns the directory where the runtime files of the pods are
+// stored on the host, PodLockVarRunDir when RunDir is not set.
+func (p *Plugin) runDir() string {
+	if p.RunDir == "" {
+		return PodLockVarRunDir
+	}
+	return p.RunDir
+}
+
+// containerRefsDirName is the name of the directory holding one empty file
+// per container instance using the runtime files of a container. The
+// instances of a container share the same files, e.g. when the container is
+// restarted by the kubelet the new instance is created before the old one is
+// removed.
+const containerRefsDirName = "refs"
+
+// podDirOnHost returns the directory on the host holding the runtime files of
+// all the containers of the pod.
+func (p *Plugin) podDirOnHost(podID string) string {
+	return filepath.Join(p.runDir(), podID)
+}
+
+// containerDirOnHost returns the directory on the host holding the runtime
+// files of the container, e.g. "/var/run/podlock/<pod ID>/<container name>".
+func (p *Plugin) containerDirOnHost(podID, containerName string) string {
+	return filepath.Join(p.podDirOnHost(podID), containerName)
+}
+
+func (p *Plugin) containerRefPathOnHost(podID, containerName, containerID string) string {
+	return filepath.Join(p.containerDirOnHost(podID, containerName), containerRefsDirName, containerID)
+}
+
+// acquireContainerDir records that the container instance uses the runtime
+// files of its container. The references are stored on the host, so that
+// they survive the restarts of the plugin.
+func (p *Plugin) acquireContainerDir(podID, containerName, containerID string) error {
+	// Track the container before creating its ref, the garbage collection
+	// could otherwise consider it as stale
+	p.state.addContainer(podID, containerID)
+
+	ref := p.containerRefPathOnHost(podID, containerName, containerID)
+	if err := os.MkdirAll(filepath.Dir(ref), 0o750); err != nil {
+		return fmt.Errorf("failed to create refs dir of container '%s': %w", containerName, err)
+	}
+
+	f, err := os.Create(ref)
+	if err != nil {
+		return fmt.Errorf("failed to create ref '%s': %w", ref, err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to close ref '%s': %w", ref, err)
+	}
+
+	p.Logger.Debug("acquired container runtime dir",
+		slog.String("pod ID", podID),
+		slog.String("container name", containerName),
+		slog.String("container ID", containerID),
+	)
+
+	return nil
+}
+
+// releaseContainerDir drops the reference of the container instance to the
+// runtime files of its container. The files are removed once no instance
+// uses them anymore, true is returned in that case.
+func (p *Plugin) releaseContainerDir(podID, containerName, containerID string) (bool, error) {
+	p.state.removeContainer(containerID)
+
+	dir := p.containerDirOnHost(podID, containerName)
+
+	ref := p.containerRefPathOnHost(podID, containerName, containerID)
+	if err := os.Remove(ref); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("failed to remove ref '%s': %w", ref, err)
+	}
+
+	refs, err := os.ReadDir(filepath.Dir(ref))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("failed to read refs of container '%s': %w", containerName, err)
+	}
+	if len(refs) > 0 {
+		p.Logger.Debug("container runtime dir still in use",
+			slog.String("pod ID", podID),
+			slog.String("container name", containerName),
+			slog.Int("refs", len(refs)),
+		)
+		return false, nil
+	}
+
+	if err = os.RemoveAll(dir); err != nil {
+		return false, fmt.Errorf("failed to remove container runtime dir '%s': %w", dir, err)
+	}
+
+	return true, nil
+}
+
+// removePodDir removes the runtime files of all the containers of the pod.
+func (p *Plugin) removePodDir(podID string) error {
+	// Never remove the whole run dir because of an empty ID
+	if podID == "" {
+		return errors.New("empty pod ID")
+	}
+
+	dir := p.podDirOnHost(podID)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove PodLock runtime dir '%s': %w", dir, err)
+	}
+
+	return nil
+}
+
+// RemoveContainer releases the runtime files of the container. They are
+// removed only when no other instance of the same container uses them.
+func (p *Plugin) RemoveContainer(ctx context.Context, pod *api.PodSandbox, ctr *api.Container) error {
+	p.Logger.InfoContext(ctx, "RemoveContainer called",
+		slog.String("pod", pod.GetName()),
+		slog.String("namespace", pod.GetNamespace()),
+		slog.String("container", ctr.GetName()),
+	)
+
+	if pod.GetId() == "" || ctr.GetName() == "" || ctr.GetId() == "" {
+		return nil
+	}
+
+	p.reportHookFailures(ctx, pod.GetId(), ctr.GetName())
+
+	removed, err := p.releaseContainerDir(pod.GetId(), ctr.GetName(), ctr.GetId())
+	if err != nil {
+		p.Logger.ErrorContext(ctx, "failed to release container runtime dir",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.String("container", ctr.GetName()),
+			slog.Any("err", err),
+		)
+		return err
+	}
+
+	if removed {
+		p.Logger.InfoContext(ctx, "cleaned up container runtime dir",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.String("container", ctr.GetName()),
+			slog.String("dir", p.containerDirOnHost(pod.GetId(), ctr.GetName())),
+		)
+	}
+
+	return nil
+}
+
+// StopPodSandbox removes the runtime files of all the containers of the pod.
+// A stopped pod sandbox is never started again.
+func (p *Plugin) StopPodSandbox(ctx context.Context, pod *api.PodSandbox) error {
+	return p.cleanupPod(ctx, "StopPodSandbox", pod)
+}
+
+// RemovePodSandbox removes the runtime files of all the containers of the
+// pod, in case they were not removed when the pod sandbox was stopped.
+func (p *Plugin) RemovePodSandbox(ctx context.Context, pod *api.PodSandbox) error {
+	return p.cleanupPod(ctx, "RemovePodSandbox", pod)
+}
+
+func (p *Plugin) cleanupPod(ctx context.Context, event string, pod *api.PodSandbox) error {
+	p.Logger.InfoContext(ctx, event+" called",
+		slog.String("pod", pod.GetName()),
+		slog.String("namespace", pod.GetNamespace()),
+	)
+
+	p.state.removePod(pod.GetId())
+	if pod.GetId() != "" {
+		p.reportPodHookFailures(ctx, pod.GetId())
+	}
+
+	if err := p.removePodDir(pod.GetId()); err != nil {
+		p.Logger.ErrorContext(ctx, "failed to remove PodLock runtime dir",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.Any("err", err),
+		)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package nri
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+func TestPodLifecycle_MultiContainerPod(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	profile := &v1alpha1.LandlockProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1alpha1.LandlockProfileSpec{
+			ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+				"init":    {"/bin/setup": {ReadWrite: []string{"/data"}}},
+				"main":    {"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}}},
+				"sidecar": {"/usr/bin/envoy": {ReadOnly: []string{"/etc/envoy"}}},
+			},
+		},
+	}
+
+	plugin := &Plugin{
+		RunDir:   t.TempDir(),
+		LogLevel: "info",
+		Logger:   slog.New(slog.DiscardHandler),
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(profile).Build(),
+	}
+
+	ctx := context.Background()
+	pod := &api.PodSandbox{
+		Id:        "pod-1",
+		Name:      "web",
+		Namespace: "default",
+		Uid:       "pod-uid",
+		Labels:    map[string]string{constants.PodProfileLabel: "web"},
+	}
+
+	createContainer := func(name, id string) {
+		t.Helper()
+		adj, _, err := plugin.CreateContainer(ctx, pod, &api.Container{Name: name, Id: id})
+		require.NoError(t, err)
+		require.NotNil(t, adj)
+	}
+	removeContainer := func(name, id string) {
+		t.Helper()
+		require.NoError(t, plugin.RemoveContainer(ctx, pod, &api.Container{Name: name, Id: id}))
+	}
+	profileOf := func(name string) string {
+		return plugin.landlockProfilePathOnHost(pod.GetId(), name)
+	}
+
+	createContainer("init", "init-1")
+	createContainer("main", "main-1")
+	createContainer("sidecar", "sidecar-1")
+
+	// The init container completes and is removed while the pod is running
+	removeContainer("init", "init-1")
+	assert.NoDirExists(t, plugin.containerDirOnHost(pod.GetId(), "init"))
+	assert.FileExists(t, profileOf("main"))
+	assert.FileExists(t, profileOf("sidecar"))
+	assert.FileExists(t, plugin.swappedBinaryPathOnHost(pod.GetId(), "main", "/usr/sbin/nginx"))
+
+	// The sidecar is restarted, the old instance is removed after the new
+	// one is created
+	createContainer("sidecar", "sidecar-2")
+	removeContainer("sidecar", "sidecar-1")
+	assert.FileExists(t, profileOf("sidecar"))
+	assert.DirExists(t, plugin.rulesetsDirOnHost(pod.GetId(), "sidecar"))
+
+	// Removing a container twice is harmless
+	removeContainer("sidecar", "sidecar-1")
+	assert.FileExists(t, profileOf("sidecar"))
+
+	removeContainer("sidecar", "sidecar-2")
+	assert.NoDirExists(t, plugin.containerDirOnHost(pod.GetId(), "sidecar"))
+	assert.FileExists(t, profileOf("main"))
+
+	// Containers without a profile never own runtime files
+	removeContainer("logger", "logger-1")
+	assert.FileExists(t, profileOf("main"))
+
+	require.NoError(t, plugin.StopPodSandbox(ctx, pod))
+	assert.NoDirExists(t, plugin.podDirOnHost(pod.GetId()))
+
+	require.NoError(t, plugin.RemovePodSandbox(ctx, pod))
+}
+
+func TestPodLifecycle_OtherPodsAreNotAffected(t *testing.T) {
+	runDir := t.TempDir()
+
+	plugin := &Plugin{Logger: slog.New(slog.DiscardHandler), RunDir: runDir}
+	ctx := context.Background()
+
+	for _, podID := range []string{"pod-1", "pod-2"} {
+		require.NoError(t, plugin.acquireContainerDir(podID, "main", podID+"-main"))
+	}
+
+	require.NoError(t, plugin.RemovePodSandbox(ctx, &api.PodSandbox{Id: "pod-1"}))
+	assert.NoDirExists(t, plugin.podDirOnHost("pod-1"))
+	assert.DirExists(t, plugin.containerDirOnHost("pod-2", "main"))
+
+	require.Error(t, plugin.RemovePodSandbox(ctx, &api.PodSandbox{}), "an empty pod ID must not remove the run dir")
+	assert.DirExists(t, runDir)
+}
@@ -0,0 +1,149 @@
+package nri
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Labels of the metrics regarding the containers.
+const (
+	metricLabelNamespace = "namespace"
+	metricLabelProfile   = "profile"
+)
+
+var (
+	containersEvaluated = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "podlock_nri_containers_evaluated_total",
+			Help: "Number of containers evaluated by the plugin, by namespace and LandlockProfile.",
+		},
+		[]string{metricLabelNamespace, metricLabelProfile},
+	)
+	containersSealed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "podlock_nri_containers_sealed_total",
+			Help: "Number of containers sandboxed with Landlock by the plugin, by namespace and LandlockProfile.",
+		},
+		[]string{metricLabelNamespace, metricLabelProfile},
+	)
+	createContainerErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "podlock_nri_create_container_errors_total",
+			Help: "Number of containers whose creation was denied by the plugin, by namespace and LandlockProfile.",
+		},
+		[]string{metricLabelNamespace, metricLabelProfile},
+	)
+	createContainerDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "podlock_nri_create_container_duration_seconds",
+			Help:    "Time spent handling the CreateContainer requests, by namespace and LandlockProfile.",
+			Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2},
+		},
+		[]string{metricLabelNamespace, metricLabelProfile},
+	)
+	profileLookupFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "podlock_nri_profile_lookup_failures_total",
+			Help: "Number of failed lookups of LandlockProfiles and their revisions, by namespace, " +
+				"LandlockProfile and failure policy applied.",
+		},
+		[]string{metricLabelNamespace, metricLabelProfile, "policy"},
+	)
+	hookFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "podlock_nri_hook_failures_total",
+			Help: "Number of failures of the OCI hook swapping the binaries, by namespace and LandlockProfile.",
+		},
+		[]string{metricLabelNamespace, metricLabelProfile},
+	)
+	swapVerificationFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "podlock_nri_swap_verification_failures_total",
+			Help: "Number of containers started with binaries not swapped with seal, hence without " +
+				"Landlock sandbox, by namespace and LandlockProfile.",
+		},
+		[]string{metricLabelNamespace, metricLabelProfile},
+	)
+	swapVerificationErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "podlock_nri_swap_verification_errors_total",
+			Help: "Number of containers whose binary swaps could not be verified, by namespace and LandlockProfile.",
+		},
+		[]string{metricLabelNamespace, metricLabelProfile},
+	)
+	runtimeDirBytes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "podlock_nri_runtime_dir_bytes",
+			Help: "Size of the runtime files of the containers stored on the node.",
+		},
+	)
+	gcReclaimedBytes = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "podlock_nri_gc_reclaimed_bytes_total",
+			Help: "Size of the stale runtime files removed by the garbage collection.",
+		},
+	)
+	offlineCacheEntries = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "podlock_nri_offline_cache_entries",
+			Help: "Number of objects stored inside of the offline cache, by state (fresh or stale).",
+		},
+		[]string{"state"},
+	)
+	offlineCacheLastRefresh = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "podlock_nri_offline_cache_last_refresh_timestamp_seconds",
+			Help: "Last time an object of the offline cache was refreshed from the API server.",
+		},
+	)
+	offlineCacheServed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "podlock_nri_offline_cache_served_total",
+			Help: "Number of objects served from the offline cache, by resource.",
+		},
+		[]string{"resource"},
+	)
+	offlineCacheServedAge = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "podlock_nri_offline_cache_served_age_seconds",
+			Help:    "Time since the objects served from the offline cache were last read from the API server.",
+			Buckets: []float64{60, 300, 900, 3600, 4 * 3600, 24 * 3600, 7 * 24 * 3600},
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		containersEvaluated,
+		containersSealed,
+		createContainerErrors,
+		createContainerDuration,
+		profileLookupFailures,
+		hookFailures,
+		swapVerificationFailures,
+		swapVerificationErrors,
+		runtimeDirBytes,
+		gcReclaimedBytes,
+		offlineCacheEntries,
+		offlineCacheLastRefresh,
+		offlineCacheServed,
+		offlineCacheServedAge,
+	)
+}
+
+// RegisterCacheSyncedMetric reports whether the cache of the objects read by
+// the plugin is synced with the API server.
+func (p *Plugin) RegisterCacheSyncedMetric() error {
+	return metrics.Registry.Register(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "podlock_nri_cache_synced",
+			Help: "Whether the cache of the objects read by the plugin is synced with the API server.",
+		},
+		func() float64 {
+			if p.cacheSynced() {
+				return 1
+			}
+			return 0
+		},
+	))
+}
@@ -0,0 +1,57 @@
+package nri
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+func TestCreateContainer_Metrics(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	plugin := &Plugin{
+		RunDir:   t.TempDir(),
+		LogLevel: "info",
+		Logger:   slog.New(slog.DiscardHandler),
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(newTestProfile()).Build(),
+	}
+	pod := &api.PodSandbox{
+		Id:        "pod-1",
+		Name:      "testpod",
+		Namespace: "default",
+		Uid:       "pod-uid",
+		Labels:    map[string]string{constants.PodProfileLabel: "nginx"},
+	}
+
+	evaluated := testutil.ToFloat64(containersEvaluated.WithLabelValues("default", "nginx"))
+	sealed := testutil.ToFloat64(containersSealed.WithLabelValues("default", "nginx"))
+	errs := testutil.ToFloat64(createContainerErrors.WithLabelValues("default", "nginx"))
+
+	// The profile has a policy for the main container only
+	_, _, err := plugin.CreateContainer(context.Background(), pod, &api.Container{Id: "ctr-1", Name: "main"})
+	require.NoError(t, err)
+	_, _, err = plugin.CreateContainer(context.Background(), pod, &api.Container{Id: "ctr-2", Name: "sidecar"})
+	require.NoError(t, err)
+
+	assert.InDelta(t, evaluated+2, testutil.ToFloat64(containersEvaluated.WithLabelValues("default", "nginx")), 0)
+	assert.InDelta(t, sealed+1, testutil.ToFloat64(containersSealed.WithLabelValues("default", "nginx")), 0)
+	assert.InDelta(t, errs, testutil.ToFloat64(createContainerErrors.WithLabelValues("default", "nginx")), 0)
+
+	// The profile is missing from the other namespace
+	pod.Namespace = "other"
+	_, _, err = plugin.CreateContainer(context.Background(), pod, &api.Container{Id: "ctr-3", Name: "main"})
+	require.Error(t, err)
+	assert.InDelta(t, 1, testutil.ToFloat64(createContainerErrors.WithLabelValues("other", "nginx")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(profileLookupFailures.WithLabelValues("other", "nginx", string(FailurePolicyDeny))), 0)
+}
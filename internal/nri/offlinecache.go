@@ -0,0 +1,305 @@
+package nri
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+// errNotInOfflineCache is returned when the object is not found inside of the
+// offline cache.
+var errNotInOfflineCache = errors.New("not found in the offline cache")
+
+// offlineCacheRefreshInterval is how often the time an unchanged object was
+// last read from the API server is written to the disk.
+const offlineCacheRefreshInterval = time.Minute
+
+// OfflineCache persists on the node the last known version of the objects
+// read by the plugin, i.e. the LandlockProfiles and their revisions. They are
+// served when the objects cannot be read from the API server, e.g. when the
+// node is rebooted while the API server is unreachable.
+//
+// The objects are stored as "<dir>/<resource>/<namespace>/<name>.json". The
+// objects loaded from the disk are marked as stale, since they could have
+// been changed while the plugin was not running. They are marked as fresh
+// again once read from the API server.
+type OfflineCache struct {
+	dir string
+
+	mu      sync.Mutex
+	entries map[string]*offlineCacheEntry
+}
+
+// offlineCacheEntry is the content of the files of the offline cache.
+type offlineCacheEntry struct {
+	// SavedAt is when the object was last read from the API server
+	SavedAt time.Time `json:"savedAt"`
+	// Stale is true when the object has not been read from the API server
+	// since the plugin started
+	Stale           bool            `json:"stale"`
+	ResourceVersion string          `json:"resourceVersion"`
+	Object          json.RawMessage `json:"object"`
+}
+
+// OfflineCacheHit describes an object served from the offline cache.
+type OfflineCacheHit struct {
+	SavedAt time.Time
+	Stale   bool
+}
+
+// NewOfflineCache loads the offline cache stored inside of dir. All the
+// loaded objects are marked as stale.
+func NewOfflineCache(dir string) (*OfflineCache, error) {
+	c := &OfflineCache{
+		dir:     dir,
+		entries: map[string]*offlineCacheEntry{},
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == dir {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read offline cache entry '%s': %w", path, err)
+		}
+		entry := &offlineCacheEntry{}
+		if err = json.Unmarshal(data, entry); err != nil {
+			// A corrupted entry must not prevent the plugin from starting
+			return os.Remove(path)
+		}
+		entry.Stale = true
+
+		c.entries[path] = entry
+		return c.write(path, entry)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load offline cache '%s': %w", dir, err)
+	}
+
+	c.updateMetrics()
+
+	return c, nil
+}
+
+// Store saves the object read from the API server. An unchanged object is
+// written at most once per offlineCacheRefreshInterval.
+func (c *OfflineCache) Store(obj client.Object) error {
+	path, err := c.path(obj.GetNamespace(), obj.GetName(), obj)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Avoid writing the same object each time it is read
+	now := time.Now()
+	if entry, found := c.entries[path]; found && !entry.Stale &&
+		entry.ResourceVersion == obj.GetResourceVersion() && now.Sub(entry.SavedAt) < offlineCacheRefreshInterval {
+		return nil
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("failed to marshal '%s/%s': %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	entry := &offlineCacheEntry{
+		SavedAt:         now,
+		ResourceVersion: obj.GetResourceVersion(),
+		Object:          data,
+	}
+	if err = c.write(path, entry); err != nil {
+		return err
+	}
+
+	c.entries[path] = entry
+	c.updateMetrics()
+	offlineCacheLastRefresh.Set(float64(now.Unix()))
+
+	return nil
+}
+
+// Delete removes the object, e.g. because it does not exist anymore.
+func (c *OfflineCache) Delete(key client.ObjectKey, obj client.Object) error {
+	path, err := c.path(key.Namespace, key.Name, obj)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err = os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove offline cache entry '%s': %w", path, err)
+	}
+	delete(c.entries, path)
+	c.updateMetrics()
+
+	return nil
+}
+
+// Get fills obj with the stored object. errNotInOfflineCache is returned
+// when the object is not stored.
+func (c *OfflineCache) Get(key client.ObjectKey, obj client.Object) (OfflineCacheHit, error) {
+	path, err := c.path(key.Namespace, key.Name, obj)
+	if err != nil {
+		return OfflineCacheHit{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.entries[path]
+	if !found {
+		return OfflineCacheHit{}, errNotInOfflineCache
+	}
+	if err = json.Unmarshal(entry.Object, obj); err != nil {
+		return OfflineCacheHit{}, fmt.Errorf("failed to unmarshal offline cache entry '%s': %w", path, err)
+	}
+
+	return OfflineCacheHit{SavedAt: entry.SavedAt, Stale: entry.Stale}, nil
+}
+
+// path returns the file storing the object.
+func (c *OfflineCache) path(namespace, name string, obj client.Object) (string, error) {
+	resource, err := offlineCacheResource(obj)
+	if err != nil {
+		return "", err
+	}
+	if namespace == "" || name == "" {
+		return "", errors.New("offline cache objects must have a namespace and a name")
+	}
+
+	return filepath.Join(c.dir, resource, namespace, name+".json"), nil
+}
+
+// offlineCacheResource returns the resource of the objects supported by the
+// offline cache.
+func offlineCacheResource(obj client.Object) (string, error) {
+	switch obj.(type) {
+	case *podlockv1alpha1.LandlockProfile:
+		return "landlockprofiles", nil
+	case *podlockv1alpha1.LandlockProfileRevision:
+		return "landlockprofilerevisions", nil
+	default:
+		return "", fmt.Errorf("unsupported offline cache object %T", obj)
+	}
+}
+
+// write atomically writes the entry, so that a crash cannot leave a partially
+// written file behind.
+func (c *OfflineCache) write(path string, entry *offlineCacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offline cache entry '%s': %w", path, err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create offline cache dir '%s': %w", filepath.Dir(path), err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-")
+	if err != nil {
+		return fmt.Errorf("failed to create offline cache entry '%s': %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write offline cache entry '%s': %w", path, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close offline cache entry '%s': %w", path, err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename offline cache entry '%s': %w", path, err)
+	}
+
+	return nil
+}
+
+// updateMetrics reports the number of fresh and stale entries. The caller
+// must hold the lock.
+func (c *OfflineCache) updateMetrics() {
+	fresh, stale := 0, 0
+	for _, entry := range c.entries {
+		if entry.Stale {
+			stale++
+		} else {
+			fresh++
+		}
+	}
+	offlineCacheEntries.WithLabelValues("fresh").Set(float64(fresh))
+	offlineCacheEntries.WithLabelValues("stale").Set(float64(stale))
+}
+
+// cacheSynced returns true when the cache of the objects read by the plugin
+// is synced with the API server.
+func (p *Plugin) cacheSynced() bool {
+	return p.CacheSynced == nil || p.CacheSynced()
+}
+
+// getObject reads the object from the cache of the API server. While the
+// cache is not synced, the object is served from the offline cache instead:
+// reading it from an unsynced cache would block until the request of the
+// runtime times out.
+func (p *Plugin) getObject(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if p.OfflineCache == nil {
+		return p.Client.Get(ctx, key, obj)
+	}
+
+	if !p.cacheSynced() {
+		hit, err := p.OfflineCache.Get(key, obj)
+		if err != nil {
+			return fmt.Errorf("cache not synced with the API server, '%s' %w", key, err)
+		}
+
+		resource, _ := offlineCacheResource(obj)
+		age := time.Since(hit.SavedAt)
+		offlineCacheServed.WithLabelValues(resource).Inc()
+		offlineCacheServedAge.Observe(age.Seconds())
+		p.Logger.WarnContext(ctx, "cache not synced with the API server, serving object from the offline cache",
+			slog.String("resource", resource),
+			slog.String("namespace", key.Namespace),
+			slog.String("name", key.Name),
+			slog.Bool("stale", hit.Stale),
+			slog.Duration("age", age),
+		)
+		return nil
+	}
+
+	if err := p.Client.Get(ctx, key, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			if err := p.OfflineCache.Delete(key, obj); err != nil {
+				p.Logger.WarnContext(ctx, "failed to remove object from the offline cache", slog.Any("err", err))
+			}
+		}
+		return err
+	}
+
+	if err := p.OfflineCache.Store(obj); err != nil {
+		p.Logger.WarnContext(ctx, "failed to store object inside of the offline cache", slog.Any("err", err))
+	}
+
+	return nil
+}
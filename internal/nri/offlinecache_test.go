@@ -0,0 +1,199 @@
+package nri
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+func newTestProfile() *v1alpha1.LandlockProfile {
+	return &v1alpha1.LandlockProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default", ResourceVersion: "1"},
+		Spec: v1alpha1.LandlockProfileSpec{
+			ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+				"main": {"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}}},
+			},
+		},
+	}
+}
+
+func TestOfflineCache(t *testing.T) {
+	dir := t.TempDir()
+	key := client.ObjectKey{Namespace: "default", Name: "nginx"}
+
+	cache, err := NewOfflineCache(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	_, err = cache.Get(key, &v1alpha1.LandlockProfile{})
+	require.ErrorIs(t, err, errNotInOfflineCache)
+
+	cache, err = NewOfflineCache(dir)
+	require.NoError(t, err)
+	require.NoError(t, cache.Store(newTestProfile()))
+	assert.FileExists(t, filepath.Join(dir, "landlockprofiles", "default", "nginx.json"))
+	assert.InDelta(t, 1, testutil.ToFloat64(offlineCacheEntries.WithLabelValues("fresh")), 0)
+
+	profile := &v1alpha1.LandlockProfile{}
+	hit, err := cache.Get(key, profile)
+	require.NoError(t, err)
+	assert.False(t, hit.Stale)
+	assert.Equal(t, newTestProfile().Spec, profile.Spec)
+
+	// Objects loaded from the disk are stale until read again from the API server
+	cache, err = NewOfflineCache(dir)
+	require.NoError(t, err)
+	hit, err = cache.Get(key, &v1alpha1.LandlockProfile{})
+	require.NoError(t, err)
+	assert.True(t, hit.Stale)
+	assert.InDelta(t, 1, testutil.ToFloat64(offlineCacheEntries.WithLabelValues("stale")), 0)
+
+	data, err := os.ReadFile(filepath.Join(dir, "landlockprofiles", "default", "nginx.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"stale":true`)
+
+	require.NoError(t, cache.Store(newTestProfile()))
+	hit, err = cache.Get(key, &v1alpha1.LandlockProfile{})
+	require.NoError(t, err)
+	assert.False(t, hit.Stale)
+
+	require.NoError(t, cache.Delete(key, &v1alpha1.LandlockProfile{}))
+	_, err = cache.Get(key, &v1alpha1.LandlockProfile{})
+	require.ErrorIs(t, err, errNotInOfflineCache)
+	assert.NoFileExists(t, filepath.Join(dir, "landlockprofiles", "default", "nginx.json"))
+}
+
+func TestOfflineCache_CorruptedEntry(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "landlockprofiles", "default", "nginx.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+	cache, err := NewOfflineCache(dir)
+	require.NoError(t, err)
+	_, err = cache.Get(client.ObjectKey{Namespace: "default", Name: "nginx"}, &v1alpha1.LandlockProfile{})
+	require.ErrorIs(t, err, errNotInOfflineCache)
+	assert.NoFileExists(t, path)
+}
+
+func TestOfflineCache_UnsupportedObject(t *testing.T) {
+	cache, err := NewOfflineCache(t.TempDir())
+	require.NoError(t, err)
+
+	require.Error(t, cache.Store(&metav1.PartialObjectMetadata{}))
+}
+
+func TestCreateContainer_OfflineCache(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	pod := &api.PodSandbox{
+		Id:        "pod-1",
+		Name:      "testpod",
+		Namespace: "default",
+		Uid:       "pod-uid",
+		Labels:    map[string]string{constants.PodProfileLabel: "nginx"},
+	}
+
+	t.Run("profiles read from the API server are stored", func(t *testing.T) {
+		runDir := t.TempDir()
+		cache, err := NewOfflineCache(t.TempDir())
+		require.NoError(t, err)
+
+		plugin := &Plugin{
+			RunDir:       runDir,
+			LogLevel:     "info",
+			Logger:       slog.New(slog.DiscardHandler),
+			Client:       fake.NewClientBuilder().WithScheme(scheme).WithObjects(newTestProfile()).Build(),
+			OfflineCache: cache,
+			CacheSynced:  func() bool { return true },
+		}
+
+		adj, _, err := plugin.CreateContainer(context.Background(), pod, &api.Container{Id: "ctr-1", Name: "main"})
+		require.NoError(t, err)
+		require.NotNil(t, adj)
+
+		_, err = cache.Get(client.ObjectKey{Namespace: "default", Name: "nginx"}, &v1alpha1.LandlockProfile{})
+		require.NoError(t, err)
+	})
+
+	t.Run("deleted profiles are removed", func(t *testing.T) {
+		runDir := t.TempDir()
+		cache, err := NewOfflineCache(t.TempDir())
+		require.NoError(t, err)
+		require.NoError(t, cache.Store(newTestProfile()))
+
+		plugin := &Plugin{
+			RunDir:       runDir,
+			LogLevel:     "info",
+			Logger:       slog.New(slog.DiscardHandler),
+			Client:       fake.NewClientBuilder().WithScheme(scheme).Build(),
+			OfflineCache: cache,
+			CacheSynced:  func() bool { return true },
+		}
+
+		_, _, err = plugin.CreateContainer(context.Background(), pod, &api.Container{Id: "ctr-1", Name: "main"})
+		require.Error(t, err)
+
+		_, err = cache.Get(client.ObjectKey{Namespace: "default", Name: "nginx"}, &v1alpha1.LandlockProfile{})
+		require.ErrorIs(t, err, errNotInOfflineCache)
+	})
+
+	t.Run("profiles are served while the cache is not synced", func(t *testing.T) {
+		runDir := t.TempDir()
+		dir := t.TempDir()
+		cache, err := NewOfflineCache(dir)
+		require.NoError(t, err)
+		require.NoError(t, cache.Store(newTestProfile()))
+		// Simulate a restart of the plugin
+		cache, err = NewOfflineCache(dir)
+		require.NoError(t, err)
+
+		plugin := &Plugin{
+			RunDir:   runDir,
+			LogLevel: "info",
+			Logger:   slog.New(slog.DiscardHandler),
+			// The API server does not know about the profile
+			Client:       fake.NewClientBuilder().WithScheme(scheme).Build(),
+			OfflineCache: cache,
+			CacheSynced:  func() bool { return false },
+		}
+
+		served := testutil.ToFloat64(offlineCacheServed.WithLabelValues("landlockprofiles"))
+		adj, _, err := plugin.CreateContainer(context.Background(), pod, &api.Container{Id: "ctr-1", Name: "main"})
+		require.NoError(t, err)
+		require.NotNil(t, adj)
+		assert.InDelta(t, served+1, testutil.ToFloat64(offlineCacheServed.WithLabelValues("landlockprofiles")), 0)
+	})
+
+	t.Run("missing profiles while the cache is not synced", func(t *testing.T) {
+		runDir := t.TempDir()
+		cache, err := NewOfflineCache(t.TempDir())
+		require.NoError(t, err)
+
+		plugin := &Plugin{
+			RunDir:       runDir,
+			LogLevel:     "info",
+			Logger:       slog.New(slog.DiscardHandler),
+			Client:       fake.NewClientBuilder().WithScheme(scheme).WithObjects(newTestProfile()).Build(),
+			OfflineCache: cache,
+			CacheSynced:  func() bool { return false },
+		}
+
+		_, _, err = plugin.CreateContainer(context.Background(), pod, &api.Container{Id: "ctr-1", Name: "main"})
+		require.ErrorContains(t, err, "cache not synced with the API server")
+		require.ErrorIs(t, err, errNotInOfflineCache)
+	})
+}
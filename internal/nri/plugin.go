@@ -5,14 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"os"
-	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/containerd/nri/pkg/api"
 	"github.com/containerd/nri/pkg/stub"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/profileref"
+	"github.com/flavio/podlock/internal/seal"
 	"github.com/flavio/podlock/pkg/constants"
 )
 
@@ -21,15 +27,80 @@ type Plugin struct {
 	Logger   *slog.Logger
 	Stub     stub.Stub
 	Client   client.Client
+	NodeName string
+	// RunDir is the directory where the runtime files of the pods are stored
+	// on the host, it is set once when the plugin is created
+	RunDir string
+
+	// FailurePolicy is the global failure policy, namespaces can override
+	// it with the NamespaceFailurePolicyLabel
+	FailurePolicy       FailurePolicy
+	FailureRetryTimeout time.Duration
+
+	// SwapVerificationPolicy tells what happens to the containers whose
+	// binaries have not been swapped with seal
+	SwapVerificationPolicy SwapVerificationPolicy
+
+	// Level is the level of Logger, it is updated by ApplyConfig when set
+	Level *slog.LevelVar
+	// config replaces the settings above once applied, see ApplyConfig
+	config atomic.Pointer[Config]
+
+	// OfflineCache serves the objects while the cache is not synced with
+	// the API server, CacheSynced reports whether it is
+	OfflineCache *OfflineCache
+	CacheSynced  func() bool
+
+	state  runtimeState
+	events eventLimiter
+	// recording tracks the pods being annotated with their applied profile
+	// and the Events being created
+	recording sync.WaitGroup
+
+	// connected tracks whether the plugin is connected to the runtime,
+	// syncs counts the synchronizations done by the runtime
+	connected atomic.Bool
+	syncs     atomic.Uint64
 }
 
+// CreateContainer sandboxes the container with the LandlockProfile of its pod.
 func (p *Plugin) CreateContainer(ctx context.Context, pod *api.PodSandbox, ctr *api.Container) (*api.ContainerAdjustment, []*api.ContainerUpdate, error) {
+	start := time.Now()
+	adjustment, updates, err := p.createContainer(ctx, pod, ctr)
+
+	// The profile is unknown when the reference is invalid
+	ref, _, _ := profileref.FromPod(pod.GetLabels(), pod.GetAnnotations())
+	labels := prometheus.Labels{
+		metricLabelNamespace: pod.GetNamespace(),
+		metricLabelProfile:   ref.Name,
+	}
+	createContainerDuration.With(labels).Observe(time.Since(start).Seconds())
+	containersEvaluated.With(labels).Inc()
+	switch {
+	case err != nil:
+		createContainerErrors.With(labels).Inc()
+	case adjustment != nil:
+		containersSealed.With(labels).Inc()
+	}
+
+	return adjustment, updates, err
+}
+
+func (p *Plugin) createContainer(ctx context.Context, pod *api.PodSandbox, ctr *api.Container) (*api.ContainerAdjustment, []*api.ContainerUpdate, error) {
 	if pod == nil {
 		p.Logger.ErrorContext(ctx, "pod is nil")
 		return nil, nil, errors.New("pod is nil")
 	}
 
-	profileName, enable := pod.GetLabels()[constants.PodProfileLabel]
+	ref, enable, err := profileref.FromPod(pod.GetLabels(), pod.GetAnnotations())
+	if err != nil {
+		p.Logger.ErrorContext(ctx, "invalid LandlockProfile reference",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.Any("err", err),
+		)
+		return nil, nil, err
+	}
 	if !enable {
 		p.Logger.DebugContext(ctx, "no podlock label found on pod, skipping mutation",
 			slog.String("pod", pod.GetName()),
@@ -38,25 +109,81 @@ func (p *Plugin) CreateContainer(ctx context.Context, pod *api.PodSandbox, ctr *
 
 		return nil, nil, nil
 	}
+	profileName := ref.Name
+
+	if p.settings().excludesNamespace(pod.GetNamespace()) {
+		p.Logger.DebugContext(ctx, "namespace excluded by the configuration, skipping mutation",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+		)
+		p.recordNamespaceExcluded(ctx, pod, ctr, profileName)
+		return nil, nil, nil
+	}
 
 	if ctr == nil {
 		p.Logger.ErrorContext(ctx, "container is nil")
 		return nil, nil, errors.New("container is nil")
 	}
 
-	// Fetch the LandlockProfile named "profileName" in the "default" namespace
+	// Fetch the LandlockProfile named "profileName" in the namespace of the pod
 	var profile podlockv1alpha1.LandlockProfile
-	if err := p.Client.Get(ctx, client.ObjectKey{
-		Namespace: pod.GetNamespace(),
-		Name:      profileName,
-	}, &profile); err != nil {
+	unprotected, err := p.lookupProfile(ctx, pod, ctr, func(ctx context.Context) error {
+		if err := p.getObject(ctx, client.ObjectKey{
+			Namespace: pod.GetNamespace(),
+			Name:      profileName,
+		}, &profile); err != nil {
+			return fmt.Errorf("failed to get LandlockProfile '%s': %w", profileName, err)
+		}
+		return nil
+	})
+	if err != nil {
 		p.Logger.ErrorContext(ctx, "failed to get LandlockProfile",
 			slog.String("pod", pod.GetName()),
 			slog.String("namespace", pod.GetNamespace()),
 			slog.String("profile name", profileName),
 			slog.Any("err", err),
 		)
-		return nil, nil, fmt.Errorf("failed to get LandlockProfile '%s': %w", profileName, err)
+		return nil, nil, err
+	}
+	if unprotected {
+		return nil, nil, nil
+	}
+
+	// Pods outside of a canary keep using the stable revision of the profile
+	if !ref.Pinned() {
+		if ref.Revision, err = profileref.StableRevision(&profile, pod.GetUid(), pod.GetLabels()); err != nil {
+			p.Logger.ErrorContext(ctx, "failed to select the revision of LandlockProfile",
+				slog.String("pod", pod.GetName()),
+				slog.String("namespace", pod.GetNamespace()),
+				slog.String("profile name", profileName),
+				slog.Any("err", err),
+			)
+			return nil, nil, fmt.Errorf("failed to select the revision of LandlockProfile '%s': %w", profileName, err)
+		}
+	}
+
+	var revision *podlockv1alpha1.LandlockProfileRevision
+	if ref.Pinned() {
+		unprotected, err = p.lookupProfile(ctx, pod, ctr, func(ctx context.Context) error {
+			var lookupErr error
+			revision, lookupErr = p.getRevision(ctx, &profile, ref)
+			return lookupErr
+		})
+		if err != nil {
+			p.Logger.ErrorContext(ctx, "failed to get LandlockProfileRevision",
+				slog.String("pod", pod.GetName()),
+				slog.String("namespace", pod.GetNamespace()),
+				slog.String("profile name", profileName),
+				slog.Int64("revision", ref.Revision),
+				slog.Any("err", err),
+			)
+			return nil, nil, err
+		}
+		if unprotected {
+			return nil, nil, nil
+		}
+		// The profiles of the revision replace the current ones
+		profile.Spec.ProfilesByContainer = revision.ProfilesByContainer
 	}
 
 	if profile.Spec.ProfilesByContainer == nil {
@@ -65,6 +192,9 @@ func (p *Plugin) CreateContainer(ctx context.Context, pod *api.PodSandbox, ctr *
 			slog.String("namespace", pod.GetNamespace()),
 			slog.String("profile name", profileName),
 		)
+		// A profile could be added to the container later on
+		p.recordAppliedProfile(ctx, pod, ctr.GetName(), &profile)
+		p.recordProfileEntryMissing(ctx, pod, ctr, &profile, ref)
 		return nil, nil, nil
 	}
 
@@ -76,9 +206,24 @@ func (p *Plugin) CreateContainer(ctx context.Context, pod *api.PodSandbox, ctr *
 			slog.String("profile name", profileName),
 			slog.String("container name", ctr.GetName()),
 		)
+		p.recordAppliedProfile(ctx, pod, ctr.GetName(), &profile)
+		p.recordProfileEntryMissing(ctx, pod, ctr, &profile, ref)
 		return nil, nil, nil
 	}
 
+	if err := p.acquireContainerDir(pod.GetId(), ctr.GetName(), ctr.GetId()); err != nil {
+		p.Logger.ErrorContext(ctx, "failed to acquire container runtime dir",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.String("container name", ctr.GetName()),
+			slog.Any("err", err),
+		)
+		return nil, nil, err
+	}
+	p.state.trackPodName(pod)
+	// The failures of the previous instances of the container
+	p.reportHookFailures(ctx, pod.GetId(), ctr.GetName())
+
 	if err := p.reserveSwappedBinaries(pod.GetId(), ctr.GetName(), profileByBinary); err != nil {
 		p.Logger.ErrorContext(ctx, "failed to create container runtime dir",
 			slog.String("pod", pod.GetName()),
@@ -89,7 +234,11 @@ func (p *Plugin) CreateContainer(ctx context.Context, pod *api.PodSandbox, ctr *
 		return nil, nil, err
 	}
 
-	if err := p.writeLandlockProfileToHostFilesystem(pod.GetId(), ctr.GetName(), profileByBinary); err != nil {
+	profileFile := seal.NewProfileFile(&profile, ctr.GetName())
+	if revision != nil {
+		profileFile = seal.NewProfileFileFromRevision(&profile, revision, ctr.GetName())
+	}
+	if err := p.writeLandlockProfileToHostFilesystem(pod.GetId(), ctr.GetName(), profileFile); err != nil {
 		p.Logger.ErrorContext(ctx, "failed to write landlock profile to host filesystem",
 			slog.String("pod", pod.GetName()),
 			slog.String("namespace", pod.GetNamespace()),
@@ -99,7 +248,21 @@ func (p *Plugin) CreateContainer(ctx context.Context, pod *api.PodSandbox, ctr *
 		return nil, nil, err
 	}
 
-	adjustment := createContainerAdjustment(pod.GetId(), ctr.GetName(), profileByBinary, p.LogLevel)
+	if err := p.prepareRulesetsDir(pod.GetId(), ctr.GetName()); err != nil {
+		p.Logger.ErrorContext(ctx, "failed to prepare compiled rulesets dir",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.String("container name", ctr.GetName()),
+			slog.Any("err", err),
+		)
+		return nil, nil, err
+	}
+
+	p.recordAppliedProfile(ctx, pod, ctr.GetName(), &profile)
+	p.recordPodEvent(ctx, pod, corev1.EventTypeNormal, constants.ProfileAppliedEventReason, "CreateContainer",
+		profileAppliedNote(profileFile))
+
+	adjustment := p.createContainerAdjustment(pod.GetId(), ctr.GetName(), profileByBinary, p.settings().SealLogLevel, terminationLogPath(ctr))
 
 	p.Logger.InfoContext(ctx, "podlock annotation found, mutation requested",
 		slog.String("namespace", pod.GetNamespace()),
@@ -111,40 +274,51 @@ func (p *Plugin) CreateContainer(ctx context.Context, pod *api.PodSandbox, ctr *
 	return adjustment, nil, nil
 }
 
-func (p *Plugin) RemoveContainer(ctx context.Context, pod *api.PodSandbox, ctr *api.Container) error {
-	p.Logger.InfoContext(ctx, "RemoveContainer called",
-		slog.String("pod", pod.GetName()),
-		slog.String("namespace", pod.GetNamespace()),
-		slog.String("container", ctr.GetName()),
-	)
-
-	// Clean up swapped binaries
-	podlockRuntimePodDir := filepath.Join(
-		PodLockVarRunDir,
-		pod.GetId(),
-	)
+// getRevision returns the LandlockProfileRevision of the profile referenced
+// by ref. The revision must be owned by the profile: the revisions left behind
+// by a deleted profile with the same name are rejected.
+func (p *Plugin) getRevision(
+	ctx context.Context,
+	profile *podlockv1alpha1.LandlockProfile,
+	ref profileref.Reference,
+) (*podlockv1alpha1.LandlockProfileRevision, error) {
+	revision := &podlockv1alpha1.LandlockProfileRevision{}
+	if err := p.getObject(ctx, client.ObjectKey{
+		Namespace: profile.Namespace,
+		Name:      ref.RevisionName(profile.UID),
+	}, revision); err != nil {
+		return nil, fmt.Errorf("failed to get revision %d of LandlockProfile '%s': %w", ref.Revision, ref.Name, err)
+	}
 
-	if err := os.RemoveAll(podlockRuntimePodDir); err != nil {
-		p.Logger.ErrorContext(ctx, "failed to remove PodLock runtime dir",
-			slog.String("pod", pod.GetName()),
-			slog.String("namespace", pod.GetNamespace()),
-			slog.String("container", ctr.GetName()),
-			slog.String("swapped binaries dir", podlockRuntimePodDir),
-			slog.Any("err", err),
-		)
-		return fmt.Errorf("failed to remove PodLock runtime dir '%s': %w", podlockRuntimePodDir, err)
+	if revision.Labels[constants.RevisionProfileLabel] != profileref.RevisionLabelValue(ref.Name) || revision.Revision != ref.Revision ||
+		!metav1.IsControlledBy(revision, profile) {
+		return nil, fmt.Errorf("LandlockProfileRevision '%s' is not revision %d of LandlockProfile '%s'",
+			revision.Name, ref.Revision, ref.Name)
 	}
 
-	p.Logger.InfoContext(ctx, "cleaned up swapped binaries",
-		slog.String("pod", pod.GetName()),
-		slog.String("namespace", pod.GetNamespace()),
-		slog.String("container", ctr.GetName()),
-		slog.String("swapped binaries dir", podlockRuntimePodDir),
-	)
-	return nil
+	return revision, nil
+}
+
+// recordProfileEntryMissing records an Event on the pod, telling that the
+// container is created without Landlock sandbox since its profile has no
+// entry for it.
+func (p *Plugin) recordProfileEntryMissing(
+	ctx context.Context,
+	pod *api.PodSandbox,
+	ctr *api.Container,
+	profile *podlockv1alpha1.LandlockProfile,
+	ref profileref.Reference,
+) {
+	p.recordPodEvent(ctx, pod, corev1.EventTypeWarning, constants.ProfileEntryMissingEventReason, "CreateContainer",
+		fmt.Sprintf("%s has no entry for container %s, created without Landlock sandbox",
+			profileVersion(profile.GetName(), profile.GetGeneration(), ref.Revision), ctr.GetName()))
 }
 
-func (p *Plugin) OnClose() {
-	p.Logger.Info("Connection to the runtime lost, exiting...")
-	os.Exit(1)
+// recordNamespaceExcluded records an Event on the pod, telling that the
+// container is created without Landlock sandbox since the namespace of the
+// pod is excluded by the configuration.
+func (p *Plugin) recordNamespaceExcluded(ctx context.Context, pod *api.PodSandbox, ctr *api.Container, profileName string) {
+	p.recordPodEvent(ctx, pod, corev1.EventTypeWarning, constants.NamespaceExcludedEventReason, "CreateContainer",
+		fmt.Sprintf("Namespace %s is excluded by the PodLock configuration, container %s created without LandlockProfile %s",
+			pod.GetNamespace(), ctr.GetName(), profileName))
 }
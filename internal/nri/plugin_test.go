@@ -10,10 +10,14 @@ import (
 	"github.com/containerd/nri/pkg/api"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/profileref"
 	"github.com/flavio/podlock/pkg/constants"
 )
 
@@ -77,3 +81,178 @@ func TestCreateContainer_ProfileNotFound(t *testing.T) {
 	assert.Nil(t, adj)
 	assert.Nil(t, updates)
 }
+
+func TestCreateContainer_InvalidProfileReference(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	plugin := &Plugin{
+		LogLevel: "info",
+		Logger:   logger,
+		Client:   fake.NewClientBuilder().WithScheme(scheme).Build(),
+	}
+
+	pod := &api.PodSandbox{
+		Name:      "testpod",
+		Namespace: "default",
+		Labels: map[string]string{
+			constants.PodProfileLabel: "nginx_latest",
+		},
+	}
+
+	container := &api.Container{
+		Name: "main",
+	}
+
+	adj, updates, err := plugin.CreateContainer(context.Background(), pod, container)
+	require.ErrorIs(t, err, profileref.ErrInvalidReference)
+	assert.Nil(t, adj)
+	assert.Nil(t, updates)
+}
+
+func TestGetRevision(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	profile := func(name string, uid types.UID) *v1alpha1.LandlockProfile {
+		return &v1alpha1.LandlockProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: uid},
+		}
+	}
+	revision := func(owner *v1alpha1.LandlockProfile, profileName string, number int64) *v1alpha1.LandlockProfileRevision {
+		return &v1alpha1.LandlockProfileRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      profileref.RevisionName(profileName, owner.UID, number),
+				Namespace: "default",
+				Labels:    map[string]string{constants.RevisionProfileLabel: owner.Name},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: v1alpha1.GroupVersion.String(),
+					Kind:       "LandlockProfile",
+					Name:       owner.Name,
+					UID:        owner.UID,
+					Controller: ptr.To(true),
+				}},
+			},
+			Revision: number,
+		}
+	}
+
+	nginx := profile("nginx", "nginx-uid")
+	web := profile("web", "web-uid")
+	deletedNginx := profile("nginx", "deleted-nginx-uid")
+
+	// Planted with the name the current nginx profile would use
+	stale := revision(deletedNginx, "nginx", 3)
+	stale.Name = profileref.RevisionName("nginx", nginx.UID, 3)
+
+	plugin := &Plugin{
+		Logger: slog.New(slog.DiscardHandler),
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			revision(nginx, "nginx", 2),
+			// Not created by the controller for the "web" profile
+			revision(profile("other", web.UID), "web", 1),
+			// Left behind by a deleted profile with the same name
+			revision(deletedNginx, "nginx", 1),
+			stale,
+		).Build(),
+	}
+
+	tests := []struct {
+		name    string
+		profile *v1alpha1.LandlockProfile
+		ref     profileref.Reference
+		wantErr string
+	}{
+		{
+			name:    "revision found",
+			profile: nginx,
+			ref:     profileref.Reference{Name: "nginx", Revision: 2},
+		},
+		{
+			name:    "revision not found",
+			profile: nginx,
+			ref:     profileref.Reference{Name: "nginx", Revision: 4},
+			wantErr: "failed to get revision 4 of LandlockProfile 'nginx'",
+		},
+		{
+			name:    "revision of another profile",
+			profile: web,
+			ref:     profileref.Reference{Name: "web", Revision: 1},
+			wantErr: "is not revision 1 of LandlockProfile 'web'",
+		},
+		{
+			name:    "revision of a deleted profile with the same name",
+			profile: nginx,
+			ref:     profileref.Reference{Name: "nginx", Revision: 1},
+			wantErr: "failed to get revision 1 of LandlockProfile 'nginx'",
+		},
+		{
+			name:    "revision owned by a deleted profile with the same name",
+			profile: nginx,
+			ref:     profileref.Reference{Name: "nginx", Revision: 3},
+			wantErr: "is not revision 3 of LandlockProfile 'nginx'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := plugin.getRevision(context.Background(), tt.profile, tt.ref)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.ref.RevisionName(tt.profile.UID), got.Name)
+		})
+	}
+}
+
+func TestCreateContainer_CanaryUsesStableRevision(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	profile := &v1alpha1.LandlockProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+		Spec: v1alpha1.LandlockProfileSpec{
+			ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+				"main": {"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}}},
+			},
+			UpdateStrategy: &v1alpha1.UpdateStrategy{
+				Type: v1alpha1.CanaryUpdateStrategyType,
+				Canary: &v1alpha1.CanaryStrategy{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"track": "canary"}},
+				},
+			},
+		},
+		Status: v1alpha1.LandlockProfileStatus{CurrentRevision: 2, StableRevision: 1},
+	}
+
+	plugin := &Plugin{
+		LogLevel: "info",
+		Logger:   logger,
+		// The stable revision does not exist
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(profile).Build(),
+	}
+
+	pod := &api.PodSandbox{
+		Name:      "testpod",
+		Namespace: "default",
+		Uid:       "uid",
+		Labels: map[string]string{
+			constants.PodProfileLabel: "nginx",
+		},
+	}
+
+	container := &api.Container{
+		Name: "main",
+	}
+
+	adj, updates, err := plugin.CreateContainer(context.Background(), pod, container)
+	require.ErrorContains(t, err, "failed to get revision 1 of LandlockProfile 'nginx'")
+	assert.Nil(t, adj)
+	assert.Nil(t, updates)
+}
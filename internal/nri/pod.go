@@ -0,0 +1,119 @@
+package nri
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/containerd/nri/pkg/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
+)
+
+// appliedProfileTimeout bounds the time spent annotating a pod with the
+// applied profile.
+const appliedProfileTimeout = 10 * time.Second
+
+// recordAppliedProfile annotates the pod with the version of the profile
+// applied to the container, allowing the controller to detect the pods
+// running an outdated version of their LandlockProfile.
+//
+// Each container has its own annotation, hence a merge patch is used to
+// avoid conflicts between containers created at the same time. The patch
+// includes the UID of the pod, so that it fails when the pod has been
+// replaced by another one with the same name.
+//
+// The pod is patched in the background, so that the request of the runtime
+// never waits for the API server. Recording is best effort, failures are
+// only logged: the annotation is used just for reporting purposes. Nothing
+// is recorded when the RecordAppliedProfiles setting is disabled.
+func (p *Plugin) recordAppliedProfile(
+	ctx context.Context,
+	pod *api.PodSandbox,
+	containerName string,
+	profile *podlockv1alpha1.LandlockProfile,
+) {
+	if !p.settings().RecordAppliedProfiles {
+		return
+	}
+
+	// The API server is probably unreachable, the patch would fail anyway
+	if !p.cacheSynced() {
+		p.Logger.DebugContext(ctx, "cache not synced with the API server, not recording applied profile",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.String("container name", containerName),
+		)
+		return
+	}
+
+	target := &api.PodSandbox{
+		Name:      pod.GetName(),
+		Namespace: pod.GetNamespace(),
+		Uid:       pod.GetUid(),
+	}
+	p.recording.Go(func() {
+		patchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), appliedProfileTimeout)
+		defer cancel()
+
+		if err := p.patchAppliedProfile(patchCtx, target, containerName, profile); err != nil {
+			p.Logger.WarnContext(patchCtx, "failed to record applied profile",
+				slog.String("pod", target.GetName()),
+				slog.String("namespace", target.GetNamespace()),
+				slog.String("container name", containerName),
+				slog.Any("err", err),
+			)
+		}
+	})
+}
+
+func (p *Plugin) patchAppliedProfile(
+	ctx context.Context,
+	pod *api.PodSandbox,
+	containerName string,
+	profile *podlockv1alpha1.LandlockProfile,
+) error {
+	applied, err := seal.NewAppliedProfile(profile, containerName)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(applied)
+	if err != nil {
+		return fmt.Errorf("cannot marshal applied profile: %w", err)
+	}
+
+	metadata := map[string]any{
+		"annotations": map[string]string{
+			seal.AppliedProfileAnnotation(containerName): string(value),
+		},
+	}
+	if uid := pod.GetUid(); uid != "" {
+		metadata["uid"] = uid
+	}
+
+	patch, err := json.Marshal(map[string]any{"metadata": metadata})
+	if err != nil {
+		return fmt.Errorf("cannot marshal pod patch: %w", err)
+	}
+
+	target := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.GetName(),
+			Namespace: pod.GetNamespace(),
+		},
+	}
+	if err = p.Client.Patch(ctx, target, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return fmt.Errorf("failed to annotate pod '%s/%s' with the applied profile: %w",
+			pod.GetNamespace(), pod.GetName(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package nri
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
+)
+
+func TestPatchAppliedProfile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	existingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "testpod",
+			Namespace:   "default",
+			UID:         types.UID("pod-uid"),
+			Annotations: map[string]string{"hello": "world"},
+		},
+	}
+
+	profile := &v1alpha1.LandlockProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "profile", Namespace: "default", Generation: 3},
+		Spec: v1alpha1.LandlockProfileSpec{
+			ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+				"main": {"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}}},
+			},
+		},
+	}
+
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existingPod).Build()
+	plugin := &Plugin{
+		Logger: slog.New(slog.DiscardHandler),
+		Client: kubeClient,
+	}
+
+	pod := &api.PodSandbox{
+		Name:      "testpod",
+		Namespace: "default",
+		Uid:       "pod-uid",
+	}
+
+	ctx := context.Background()
+	require.NoError(t, plugin.patchAppliedProfile(ctx, pod, "main", profile))
+	require.NoError(t, plugin.patchAppliedProfile(ctx, pod, "sidecar", profile))
+
+	updatedPod := &corev1.Pod{}
+	require.NoError(t, kubeClient.Get(ctx, client.ObjectKeyFromObject(existingPod), updatedPod))
+	assert.Equal(t, "world", updatedPod.Annotations["hello"])
+
+	mainApplied, err := seal.NewAppliedProfile(profile, "main")
+	require.NoError(t, err)
+	sidecarApplied, err := seal.NewAppliedProfile(profile, "sidecar")
+	require.NoError(t, err)
+	assert.Equal(t,
+		map[string]seal.AppliedProfile{"main": *mainApplied, "sidecar": *sidecarApplied},
+		seal.AppliedProfilesFromAnnotations(updatedPod.Annotations))
+
+	missingPod := &api.PodSandbox{
+		Name:      "missing",
+		Namespace: "default",
+	}
+	require.Error(t, plugin.patchAppliedProfile(ctx, missingPod, "main", profile))
+}
+
+func TestRecordAppliedProfileInBackground(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	existingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "testpod", Namespace: "default", UID: types.UID("pod-uid")},
+	}
+	profile := &v1alpha1.LandlockProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "profile", Namespace: "default", Generation: 3},
+	}
+
+	// The patch is held until the runtime request has been answered
+	unblock := make(chan struct{})
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existingPod).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				<-unblock
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).Build()
+	plugin := &Plugin{
+		Logger: slog.New(slog.DiscardHandler),
+		Client: kubeClient,
+	}
+
+	pod := &api.PodSandbox{Name: "testpod", Namespace: "default", Uid: "pod-uid"}
+	plugin.recordAppliedProfile(context.Background(), pod, "main", profile)
+	close(unblock)
+	plugin.recording.Wait()
+
+	updatedPod := &corev1.Pod{}
+	require.NoError(t, kubeClient.Get(context.Background(), client.ObjectKeyFromObject(existingPod), updatedPod))
+	assert.Contains(t, seal.AppliedProfilesFromAnnotations(updatedPod.Annotations), "main")
+}
+
+func TestRecordAppliedProfile_Disabled(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	existingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "testpod", Namespace: "default", UID: types.UID("pod-uid")},
+	}
+	profile := &v1alpha1.LandlockProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "profile", Namespace: "default", Generation: 3},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existingPod).Build()
+	plugin := &Plugin{
+		Logger: slog.New(slog.DiscardHandler),
+		Client: kubeClient,
+	}
+	cfg := DefaultConfig()
+	cfg.RecordAppliedProfiles = false
+	plugin.ApplyConfig(cfg)
+
+	pod := &api.PodSandbox{Name: "testpod", Namespace: "default", Uid: "pod-uid"}
+	plugin.recordAppliedProfile(context.Background(), pod, "main", profile)
+	plugin.recording.Wait()
+
+	updatedPod := &corev1.Pod{}
+	require.NoError(t, kubeClient.Get(context.Background(), client.ObjectKeyFromObject(existingPod), updatedPod))
+	assert.Empty(t, updatedPod.Annotations)
+}
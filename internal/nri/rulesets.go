@@ -0,0 +1,82 @@
+package nri
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/containerd/nri/pkg/api"
+
+	"github.com/flavio/podlock/internal/seal"
+)
+
+// compileRulesets compiles the rulesets of the binaries of the profile of the
+// container and stores them inside of its rulesets dir, so that seal does not
+// compile them each time a binary is started.
+//
+// The rulesets are compiled from the host, through the root filesystem of
+// the process of the container, which is created but not started yet.
+// Nothing from the container is run. Compiling is best effort: seal compiles
+// the missing rulesets itself, without storing them.
+func (p *Plugin) compileRulesets(ctx context.Context, pod *api.PodSandbox, ctr *api.Container, profileFile *seal.ProfileFile) {
+	if ctr.GetPid() == 0 {
+		return
+	}
+
+	root, err := seal.OpenRootFS(containerRootPath(ctr.GetPid()))
+	if err != nil {
+		p.Logger.WarnContext(ctx, "cannot compile the rulesets of the container",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.String("container name", ctr.GetName()),
+			slog.Any("err", err),
+		)
+		return
+	}
+	defer root.Close()
+
+	addLinkedLibraries := linkedLibrariesRequested(ctr.GetEnv())
+	cache := &seal.RulesetCache{Dir: p.rulesetsDirOnHost(pod.GetId(), ctr.GetName())}
+	for binary, profile := range profileFile.Profiles {
+		// The fingerprint must be the one computed by seal
+		binaryToRun := SwappedBinaryPathInsideContainer(binary)
+		fingerprint, err := seal.ProfileFingerprint(&profile, binaryToRun, addLinkedLibraries)
+		var ruleset *seal.CompiledRuleset
+		if err == nil {
+			ruleset, err = seal.CompileRulesetInRoot(root, &profile, binaryToRun, addLinkedLibraries, fingerprint)
+		}
+		if err == nil {
+			err = cache.Store(ruleset)
+		}
+		if err != nil {
+			p.Logger.WarnContext(ctx, "cannot compile the ruleset of the binary, seal compiles it when the binary is started",
+				slog.String("pod", pod.GetName()),
+				slog.String("namespace", pod.GetNamespace()),
+				slog.String("container name", ctr.GetName()),
+				slog.String("binary", binary),
+				slog.Any("err", err),
+			)
+			continue
+		}
+
+		p.Logger.DebugContext(ctx, "compiled ruleset",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.String("container name", ctr.GetName()),
+			slog.String("binary", binary),
+			slog.String("fingerprint", fingerprint),
+		)
+	}
+}
+
+// linkedLibrariesRequested tells whether seal grants access to the linked
+// libraries of the binaries, given the environment of the container. Like
+// getenv, the first definition of the variable is used.
+func linkedLibrariesRequested(env []string) bool {
+	for _, kv := range env {
+		if name, value, _ := strings.Cut(kv, "="); name == seal.AddLinkedLibrariesEnvVar {
+			return value != ""
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package nri
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+func TestStartContainer_CompilesRulesets(t *testing.T) {
+	original := hostProcDir
+	hostProcDir = t.TempDir()
+	t.Cleanup(func() { hostProcDir = original })
+	createContainerRoot(t, "1234", true)
+
+	// The paths are resolved inside of the root of the container: the
+	// absolute symlink leads to a file there, while /tmp is a directory on
+	// the host
+	root := filepath.Join(hostProcDir, "1234", "root")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "etc", "nginx"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "etc", "nginx", "nginx.conf"), []byte("conf"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "tmp"), []byte("tmp"), 0o600))
+	require.NoError(t, os.Symlink("/tmp", filepath.Join(root, "etc", "nginx-tmp")))
+
+	profile := &v1alpha1.LandlockProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+		Spec: v1alpha1.LandlockProfileSpec{
+			ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+				"main": {"/sbin/nginx": {
+					ReadOnly:  []string{"/etc/nginx", "/etc/nginx-tmp", "/missing"},
+					ReadWrite: []string{"/etc/nginx/nginx.conf"},
+				}},
+			},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	plugin := &Plugin{
+		RunDir:                 t.TempDir(),
+		LogLevel:               "info",
+		Logger:                 slog.New(slog.DiscardHandler),
+		Client:                 fake.NewClientBuilder().WithScheme(scheme).WithObjects(profile).Build(),
+		SwapVerificationPolicy: SwapVerificationPolicyDisabled,
+	}
+	pod := &api.PodSandbox{
+		Id:        "pod-1",
+		Name:      "testpod",
+		Namespace: "default",
+		Uid:       "pod-uid",
+		Labels:    map[string]string{constants.PodProfileLabel: "nginx"},
+	}
+	ctr := &api.Container{Id: "ctr-1", PodSandboxId: "pod-1", Name: "main", Pid: 1234}
+
+	_, _, err := plugin.CreateContainer(context.Background(), pod, ctr)
+	require.NoError(t, err)
+	require.NoError(t, plugin.StartContainer(context.Background(), pod, ctr))
+	plugin.recording.Wait()
+
+	dir := plugin.rulesetsDirOnHost(pod.GetId(), ctr.GetName())
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm(), "the rulesets dir must not be writable by the container")
+
+	// The ruleset is found by seal, which computes the same fingerprint
+	binaryProfile := profile.Spec.ProfilesByContainer["main"]["/sbin/nginx"]
+	fingerprint, err := seal.ProfileFingerprint(&binaryProfile, SwappedBinaryPathInsideContainer("/sbin/nginx"), false)
+	require.NoError(t, err)
+	ruleset, err := (&seal.RulesetCache{Dir: dir}).Load(fingerprint)
+	require.NoError(t, err)
+
+	assert.Equal(t, seal.CompiledPaths{Files: []string{"/etc/nginx-tmp"}, Dirs: []string{"/etc/nginx"}}, ruleset.ReadOnly)
+	assert.Equal(t, seal.CompiledPaths{Files: []string{"/etc/nginx/nginx.conf"}}, ruleset.ReadWrite)
+	assert.Empty(t, ruleset.LinkedLibraries)
+}
+
+func TestLinkedLibrariesRequested(t *testing.T) {
+	assert.False(t, linkedLibrariesRequested(nil))
+	assert.False(t, linkedLibrariesRequested([]string{"FOO=bar", seal.AddLinkedLibrariesEnvVar + "="}))
+	assert.True(t, linkedLibrariesRequested([]string{seal.AddLinkedLibrariesEnvVar + "=1"}))
+	assert.False(t, linkedLibrariesRequested([]string{
+		seal.AddLinkedLibrariesEnvVar + "=",
+		seal.AddLinkedLibrariesEnvVar + "=1",
+	}), "only the first definition is used")
+}
@@ -0,0 +1,248 @@
+package nri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/nri/pkg/api"
+	"golang.org/x/sys/unix"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/flavio/podlock/internal/seal"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+// SwapVerificationPolicy tells what happens to a container whose binaries
+// have not been swapped with seal by swap-oci-hook, hence running without
+// Landlock sandbox.
+type SwapVerificationPolicy string
+
+const (
+	// SwapVerificationPolicyDeny prevents the container from being started.
+	SwapVerificationPolicyDeny SwapVerificationPolicy = "Deny"
+	// SwapVerificationPolicyWarn starts the container, a Warning Event is
+	// recorded on the pod.
+	SwapVerificationPolicyWarn SwapVerificationPolicy = "Warn"
+	// SwapVerificationPolicyDisabled does not verify the swaps.
+	SwapVerificationPolicyDisabled SwapVerificationPolicy = "Disabled"
+)
+
+// hostProcDir is the directory where the procfs of the host is mounted
+// inside of the plugin container. It is changed only by the tests.
+var hostProcDir = "/host/proc"
+
+// ParseSwapVerificationPolicy converts the string into a
+// SwapVerificationPolicy.
+func ParseSwapVerificationPolicy(value string) (SwapVerificationPolicy, error) {
+	switch policy := SwapVerificationPolicy(value); policy {
+	case SwapVerificationPolicyDeny, SwapVerificationPolicyWarn, SwapVerificationPolicyDisabled:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid swap verification policy '%s', must be one of %s, %s, %s",
+			value, SwapVerificationPolicyDeny, SwapVerificationPolicyWarn, SwapVerificationPolicyDisabled)
+	}
+}
+
+// StartContainer verifies that the binaries of the profile of the container
+// have been swapped with seal, then it compiles their rulesets. The container
+// is created at this point, hence swap-oci-hook has already run.
+//
+// The containers whose swaps cannot be verified, e.g. because the runtime
+// does not report their PID, are handled according to the
+// SwapVerificationPolicy like the ones whose swaps are known to be missing.
+func (p *Plugin) StartContainer(ctx context.Context, pod *api.PodSandbox, ctr *api.Container) error {
+	if pod.GetId() == "" || ctr.GetName() == "" {
+		return nil
+	}
+
+	policy := p.settings().SwapVerificationPolicy
+	profileFile, err := p.readProfileFile(pod.GetId(), ctr.GetName())
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// The container is not sealed
+		return nil
+	case err != nil && policy == SwapVerificationPolicyDisabled:
+		return nil
+	case err != nil:
+		p.Logger.WarnContext(ctx, "cannot verify binary swaps, failed to read the profile of the container",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.String("container name", ctr.GetName()),
+			slog.String("policy", string(policy)),
+			slog.Any("err", err),
+		)
+		swapVerificationErrors.WithLabelValues(pod.GetNamespace(), "").Inc()
+		return p.swapVerificationError(ctx, pod, ctr, policy, err)
+	}
+
+	if policy != SwapVerificationPolicyDisabled {
+		if err = p.verifySwaps(ctx, pod, ctr, profileFile, policy); err != nil {
+			return err
+		}
+	}
+	p.compileRulesets(ctx, pod, ctr, profileFile)
+
+	return nil
+}
+
+// verifySwaps verifies the binary swaps of the container according to the
+// policy.
+func (p *Plugin) verifySwaps(
+	ctx context.Context,
+	pod *api.PodSandbox,
+	ctr *api.Container,
+	profileFile *seal.ProfileFile,
+	policy SwapVerificationPolicy,
+) error {
+	profileName := profileFile.Source.Name
+
+	missing, err := verifySwappedBinaries(ctr.GetPid(), slices.Sorted(maps.Keys(profileFile.Profiles)))
+	if err != nil {
+		p.Logger.WarnContext(ctx, "cannot verify binary swaps",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.String("container name", ctr.GetName()),
+			slog.String("profile name", profileName),
+			slog.String("policy", string(policy)),
+			slog.Any("err", err),
+		)
+		swapVerificationErrors.WithLabelValues(pod.GetNamespace(), profileName).Inc()
+		return p.swapVerificationError(ctx, pod, ctr, policy, err)
+	}
+	if len(missing) == 0 {
+		p.Logger.DebugContext(ctx, "binary swaps verified",
+			slog.String("pod", pod.GetName()),
+			slog.String("namespace", pod.GetNamespace()),
+			slog.String("container name", ctr.GetName()),
+			slog.String("profile name", profileName),
+		)
+		return nil
+	}
+
+	swapVerificationFailures.WithLabelValues(pod.GetNamespace(), profileName).Inc()
+	p.Logger.ErrorContext(ctx, "binaries not swapped with seal",
+		slog.String("pod", pod.GetName()),
+		slog.String("namespace", pod.GetNamespace()),
+		slog.String("container name", ctr.GetName()),
+		slog.String("profile name", profileName),
+		slog.Any("binaries", missing),
+		slog.String("policy", string(policy)),
+	)
+
+	if policy == SwapVerificationPolicyWarn {
+		p.recordPodEvent(ctx, pod, corev1.EventTypeWarning, constants.SwapVerificationFailedEventReason, "StartContainer",
+			fmt.Sprintf("Container %s started without Landlock sandbox for binaries not swapped with seal: %s",
+				ctr.GetName(), strings.Join(missing, ", ")))
+		return nil
+	}
+
+	p.recordPodEvent(ctx, pod, corev1.EventTypeWarning, constants.SwapVerificationFailedEventReason, "StartContainer",
+		fmt.Sprintf("Container %s not started, binaries not swapped with seal: %s",
+			ctr.GetName(), strings.Join(missing, ", ")))
+	return fmt.Errorf("binaries of container '%s' not swapped with seal: %s", ctr.GetName(), strings.Join(missing, ", "))
+}
+
+// swapVerificationError handles a container whose swaps cannot be verified
+// according to the policy: the container is not started under the Deny
+// policy, a Warning Event is recorded on the pod in both cases.
+func (p *Plugin) swapVerificationError(
+	ctx context.Context,
+	pod *api.PodSandbox,
+	ctr *api.Container,
+	policy SwapVerificationPolicy,
+	err error,
+) error {
+	if policy == SwapVerificationPolicyWarn {
+		p.recordPodEvent(ctx, pod, corev1.EventTypeWarning, constants.SwapVerificationFailedEventReason, "StartContainer",
+			fmt.Sprintf("Container %s started, its Landlock sandbox cannot be verified: %v", ctr.GetName(), err))
+		return nil
+	}
+
+	p.recordPodEvent(ctx, pod, corev1.EventTypeWarning, constants.SwapVerificationFailedEventReason, "StartContainer",
+		fmt.Sprintf("Container %s not started, its Landlock sandbox cannot be verified: %v", ctr.GetName(), err))
+	return fmt.Errorf("cannot verify the binary swaps of container '%s': %w", ctr.GetName(), err)
+}
+
+// containerRootPath returns the root directory of the process with the given
+// PID, as seen through the procfs of the host.
+func containerRootPath(pid uint32) string {
+	return filepath.Join(hostProcDir, strconv.FormatUint(uint64(pid), 10), "root")
+}
+
+// readProfileFile reads the profile file of the container from the host.
+func (p *Plugin) readProfileFile(podID, containerName string) (*seal.ProfileFile, error) {
+	f, err := os.Open(p.landlockProfilePathOnHost(podID, containerName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return seal.ParseProfileFile(f)
+}
+
+// verifySwappedBinaries returns the binaries that are not overmounted by
+// seal inside of the mount namespace of the process. A swapped binary is the
+// seal binary mounted into the container, hence it has the same device and
+// inode numbers.
+func verifySwappedBinaries(pid uint32, binaries []string) ([]string, error) {
+	if pid == 0 {
+		return nil, errors.New("the runtime did not report the PID of the container")
+	}
+
+	root, err := unix.Open(containerRootPath(pid), unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open root of process %d: %w", pid, err)
+	}
+	defer unix.Close(root)
+
+	sealStat, err := statInRoot(root, SealBinaryPathContainer())
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat seal binary of process %d: %w", pid, err)
+	}
+
+	missing := []string{}
+	for _, binary := range binaries {
+		stat, err := statInRoot(root, binary)
+		if errors.Is(err, unix.ENOENT) {
+			missing = append(missing, binary)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat '%s' of process %d: %w", binary, pid, err)
+		}
+		if stat.Dev != sealStat.Dev || stat.Ino != sealStat.Ino {
+			missing = append(missing, binary)
+		}
+	}
+
+	return missing, nil
+}
+
+// statInRoot returns the status of the path, resolved inside of root like
+// swap-oci-hook does after changing root. The last component of the path is
+// not followed when it is a symlink, since the hook overmounts the symlink
+// itself.
+func statInRoot(root int, path string) (unix.Stat_t, error) {
+	var stat unix.Stat_t
+
+	fd, err := unix.Openat2(root, path, &unix.OpenHow{
+		Flags:   unix.O_PATH | unix.O_NOFOLLOW | unix.O_CLOEXEC,
+		Resolve: unix.RESOLVE_IN_ROOT | unix.RESOLVE_NO_MAGICLINKS,
+	})
+	if err != nil {
+		return stat, err
+	}
+	defer unix.Close(fd)
+
+	err = unix.Fstat(fd, &stat)
+	return stat, err
+}
@@ -0,0 +1,187 @@
+package nri
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/nri/pkg/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+func TestParseSwapVerificationPolicy(t *testing.T) {
+	for _, value := range []string{"Deny", "Warn", "Disabled"} {
+		policy, err := ParseSwapVerificationPolicy(value)
+		require.NoError(t, err)
+		assert.Equal(t, SwapVerificationPolicy(value), policy)
+	}
+
+	_, err := ParseSwapVerificationPolicy("warn")
+	require.Error(t, err)
+}
+
+// createContainerRoot creates the root filesystem of the process, as seen
+// through the procfs of the host. The swapped binaries are hard links to the
+// seal binary, since they share its inode like the overmounts do.
+func createContainerRoot(t *testing.T, pid string, swapped bool) {
+	t.Helper()
+
+	root := filepath.Join(hostProcDir, pid, "root")
+	sealPath := filepath.Join(root, SealBinaryPathContainer())
+	require.NoError(t, os.MkdirAll(filepath.Dir(sealPath), 0o750))
+	require.NoError(t, os.WriteFile(sealPath, []byte("seal"), 0o600))
+
+	// The binary is reached through an absolute symlink, resolved inside of the root
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "usr", "sbin"), 0o750))
+	require.NoError(t, os.Symlink("/usr/sbin", filepath.Join(root, "sbin")))
+
+	binary := filepath.Join(root, "usr", "sbin", "nginx")
+	if swapped {
+		require.NoError(t, os.Link(sealPath, binary))
+	} else {
+		require.NoError(t, os.WriteFile(binary, []byte("nginx"), 0o600))
+	}
+}
+
+func TestStartContainer_SwapVerification(t *testing.T) {
+	profile := &v1alpha1.LandlockProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+		Spec: v1alpha1.LandlockProfileSpec{
+			ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+				"main": {"/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		policy     SwapVerificationPolicy
+		swapped    bool
+		sealed     bool
+		pid        uint32
+		wantErr    string
+		wantEvent  string
+		wantFailed bool
+	}{
+		{
+			name:    "swapped binaries",
+			swapped: true,
+			sealed:  true,
+			pid:     1234,
+		},
+		{
+			name:       "missing swaps denied by default",
+			sealed:     true,
+			pid:        1234,
+			wantErr:    "not swapped with seal: /sbin/nginx",
+			wantEvent:  "/sbin/nginx",
+			wantFailed: true,
+		},
+		{
+			name:       "missing swaps with warn policy",
+			policy:     SwapVerificationPolicyWarn,
+			sealed:     true,
+			pid:        1234,
+			wantEvent:  "/sbin/nginx",
+			wantFailed: true,
+		},
+		{
+			name:   "disabled",
+			policy: SwapVerificationPolicyDisabled,
+			sealed: true,
+			pid:    1234,
+		},
+		{
+			name: "container not sealed",
+			pid:  1234,
+		},
+		{
+			name:      "PID not reported by the runtime denied by default",
+			sealed:    true,
+			wantErr:   "did not report the PID of the container",
+			wantEvent: "did not report the PID of the container",
+		},
+		{
+			name:      "PID not reported by the runtime with warn policy",
+			policy:    SwapVerificationPolicyWarn,
+			sealed:    true,
+			wantEvent: "did not report the PID of the container",
+		},
+		{
+			name:   "PID not reported by the runtime with disabled policy",
+			policy: SwapVerificationPolicyDisabled,
+			sealed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := hostProcDir
+			hostProcDir = t.TempDir()
+			t.Cleanup(func() { hostProcDir = original })
+			createContainerRoot(t, "1234", tt.swapped)
+
+			scheme := runtime.NewScheme()
+			require.NoError(t, v1alpha1.AddToScheme(scheme))
+			require.NoError(t, eventsv1.AddToScheme(scheme))
+			kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(profile.DeepCopy()).Build()
+
+			plugin := &Plugin{
+				RunDir:                 t.TempDir(),
+				LogLevel:               "info",
+				Logger:                 slog.New(slog.DiscardHandler),
+				Client:                 kubeClient,
+				SwapVerificationPolicy: tt.policy,
+			}
+			pod := &api.PodSandbox{
+				Id:        "pod-1",
+				Name:      "testpod",
+				Namespace: "default",
+				Uid:       "pod-uid",
+			}
+			ctr := &api.Container{Id: "ctr-1", PodSandboxId: "pod-1", Name: "main", Pid: tt.pid}
+			if tt.sealed {
+				pod.Labels = map[string]string{constants.PodProfileLabel: "nginx"}
+				_, _, err := plugin.CreateContainer(context.Background(), pod, ctr)
+				require.NoError(t, err)
+			}
+
+			failures := testutil.ToFloat64(swapVerificationFailures.WithLabelValues("default", "nginx"))
+			err := plugin.StartContainer(context.Background(), pod, ctr)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			wantFailures := failures
+			if tt.wantFailed {
+				wantFailures++
+			}
+			assert.InDelta(t, wantFailures, testutil.ToFloat64(swapVerificationFailures.WithLabelValues("default", "nginx")), 0)
+
+			plugin.recording.Wait()
+			events := &eventsv1.EventList{}
+			require.NoError(t, kubeClient.List(context.Background(), events))
+			found := false
+			for _, event := range events.Items {
+				if event.Reason == constants.SwapVerificationFailedEventReason {
+					found = true
+					assert.Contains(t, event.Note, tt.wantEvent)
+				}
+			}
+			assert.Equal(t, tt.wantEvent != "", found)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package profileref
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+)
+
+// CanaryStrategy returns the canary configuration of the LandlockProfile, or
+// nil when the profile does not use the Canary update strategy.
+func CanaryStrategy(profile *v1alpha1.LandlockProfile) *v1alpha1.CanaryStrategy {
+	strategy := profile.Spec.UpdateStrategy
+	if strategy == nil || strategy.Type != v1alpha1.CanaryUpdateStrategyType {
+		return nil
+	}
+	return strategy.Canary
+}
+
+// InCanary returns true when the pod with the given UID and labels is
+// selected to test the new revision of a LandlockProfile.
+//
+// The pods matching the selector are always selected. The other pods are
+// selected by hashing their UID, so that the same pod is always either
+// inside or outside of the percentage.
+func InCanary(canary *v1alpha1.CanaryStrategy, uid string, podLabels map[string]string) (bool, error) {
+	if canary.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(canary.Selector)
+		if err != nil {
+			return false, fmt.Errorf("invalid canary selector: %w", err)
+		}
+		if !selector.Empty() && selector.Matches(labels.Set(podLabels)) {
+			return true, nil
+		}
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(uid))
+	return int32(hash.Sum32()%100) < canary.Percentage, nil //nolint:gosec // the result is below 100
+}
+
+// StableRevision returns the revision of the LandlockProfile to use for a pod
+// not pinning any revision, or zero when the pod uses the current profiles.
+//
+// With the Canary update strategy, only the pods selected for the canary use
+// the current profiles. The other ones use the stable revision, which is
+// promoted by the controller once the canary succeeds.
+func StableRevision(profile *v1alpha1.LandlockProfile, uid string, podLabels map[string]string) (int64, error) {
+	canary := CanaryStrategy(profile)
+	if canary == nil || profile.Status.StableRevision == 0 {
+		return 0, nil
+	}
+
+	selected, err := InCanary(canary, uid, podLabels)
+	if err != nil || selected {
+		return 0, err
+	}
+
+	return profile.Status.StableRevision, nil
+}
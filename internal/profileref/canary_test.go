@@ -0,0 +1,113 @@
+package profileref
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+)
+
+func TestInCanary(t *testing.T) {
+	canaryLabels := map[string]string{"track": "canary"}
+
+	tests := []struct {
+		name     string
+		canary   v1alpha1.CanaryStrategy
+		expected int
+	}{
+		{
+			name:     "no pod selected",
+			canary:   v1alpha1.CanaryStrategy{},
+			expected: 0,
+		},
+		{
+			name:     "all the pods selected",
+			canary:   v1alpha1.CanaryStrategy{Percentage: 100},
+			expected: 1000,
+		},
+		{
+			name: "pods selected by label",
+			canary: v1alpha1.CanaryStrategy{
+				Selector: &metav1.LabelSelector{MatchLabels: canaryLabels},
+			},
+			expected: 500,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected := 0
+			for i := range 1000 {
+				podLabels := map[string]string{}
+				if i%2 == 0 {
+					podLabels = canaryLabels
+				}
+				inCanary, err := InCanary(&tt.canary, fmt.Sprintf("uid-%d", i), podLabels)
+				require.NoError(t, err)
+				if inCanary {
+					selected++
+				}
+			}
+			assert.Equal(t, tt.expected, selected)
+		})
+	}
+}
+
+func TestInCanary_Percentage(t *testing.T) {
+	canary := &v1alpha1.CanaryStrategy{Percentage: 20}
+
+	selected := 0
+	for i := range 1000 {
+		uid := fmt.Sprintf("uid-%d", i)
+		inCanary, err := InCanary(canary, uid, nil)
+		require.NoError(t, err)
+
+		again, err := InCanary(canary, uid, nil)
+		require.NoError(t, err)
+		assert.Equal(t, inCanary, again, "the selection of a pod must not change")
+
+		if inCanary {
+			selected++
+		}
+	}
+
+	assert.InDelta(t, 200, selected, 50)
+}
+
+func TestStableRevision(t *testing.T) {
+	profile := &v1alpha1.LandlockProfile{
+		Spec: v1alpha1.LandlockProfileSpec{
+			UpdateStrategy: &v1alpha1.UpdateStrategy{
+				Type: v1alpha1.CanaryUpdateStrategyType,
+				Canary: &v1alpha1.CanaryStrategy{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"track": "canary"}},
+				},
+			},
+		},
+		Status: v1alpha1.LandlockProfileStatus{CurrentRevision: 3, StableRevision: 2},
+	}
+
+	revision, err := StableRevision(profile, "uid", map[string]string{"track": "canary"})
+	require.NoError(t, err)
+	assert.Zero(t, revision, "pods inside of the canary use the current profiles")
+
+	revision, err = StableRevision(profile, "uid", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), revision)
+
+	profile.Spec.UpdateStrategy.Type = v1alpha1.ImmediateUpdateStrategyType
+	revision, err = StableRevision(profile, "uid", nil)
+	require.NoError(t, err)
+	assert.Zero(t, revision, "the stable revision is used only by the Canary update strategy")
+
+	profile.Spec.UpdateStrategy.Type = v1alpha1.CanaryUpdateStrategyType
+	profile.Spec.UpdateStrategy.Canary.Selector.MatchExpressions = []metav1.LabelSelectorRequirement{
+		{Key: "track", Operator: "Invalid"},
+	}
+	_, err = StableRevision(profile, "uid", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,3 @@
+// Package profileref parses the references to LandlockProfiles made by pods
+// and selects the revision of the LandlockProfile each pod uses.
+package profileref
@@ -0,0 +1,123 @@
+package profileref
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+// ErrInvalidReference is returned when a pod references a LandlockProfile
+// using an invalid syntax.
+var ErrInvalidReference = errors.New("invalid LandlockProfile reference")
+
+// Reference is the LandlockProfile, and optionally its revision, used by a pod.
+type Reference struct {
+	// Name is the name of the LandlockProfile.
+	Name string
+	// Revision is the number of the pinned LandlockProfileRevision. It is
+	// zero when the pod uses the current version of the LandlockProfile.
+	Revision int64
+}
+
+// Pinned returns true when the pod uses a specific revision of the
+// LandlockProfile.
+func (r Reference) Pinned() bool {
+	return r.Revision > 0
+}
+
+// RevisionName returns the name of the pinned LandlockProfileRevision of the
+// LandlockProfile with the given UID.
+func (r Reference) RevisionName(profileUID types.UID) string {
+	return RevisionName(r.Name, profileUID, r.Revision)
+}
+
+// RevisionName returns the name of the given revision of a LandlockProfile.
+// Revision names are unique, since the revision number is always the last
+// dash-separated part of the name. The name includes a hash of the UID of
+// the LandlockProfile, so that the revisions of a recreated profile do not
+// collide with the ones of the deleted profile that have not been garbage
+// collected yet. Long profile names are truncated to leave room for the
+// suffix, the hash still tells apart the profiles sharing the same prefix.
+func RevisionName(profileName string, profileUID types.UID, revision int64) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(profileUID))
+	suffix := fmt.Sprintf("-%08x-%d", hash.Sum32(), revision)
+	return truncateName(profileName, validation.DNS1123SubdomainMaxLength-len(suffix)) + suffix
+}
+
+// RevisionLabelValue returns the value of the RevisionProfileLabel set on the
+// revisions of the LandlockProfile. Label values are shorter than object
+// names, long profile names are truncated: the revisions of the profiles
+// sharing the same prefix are told apart by their owner.
+func RevisionLabelValue(profileName string) string {
+	return truncateName(profileName, validation.LabelValueMaxLength)
+}
+
+// truncateName truncates the name of an object to the given length. The
+// truncated name ends with an alphanumeric character, like the names and the
+// label values must.
+func truncateName(name string, length int) string {
+	if len(name) <= length {
+		return name
+	}
+	return strings.TrimRight(name[:length], "-.")
+}
+
+// FromPod returns the LandlockProfile referenced by a pod with the given
+// labels and annotations. False is returned when the pod does not use
+// PodLock.
+//
+// The revision can be pinned either by appending it to the value of the
+// profile label, e.g. "nginx_3", or by using the revision annotation. The two
+// cannot be used together with different revisions.
+func FromPod(labels, annotations map[string]string) (Reference, bool, error) {
+	value, found := labels[constants.PodProfileLabel]
+	if !found {
+		return Reference{}, false, nil
+	}
+
+	ref := Reference{Name: value}
+
+	if name, revision, pinned := strings.Cut(value, constants.ProfileRevisionSeparator); pinned {
+		parsed, err := parseRevision(revision)
+		if err != nil {
+			return Reference{}, true, fmt.Errorf("%w: label '%s': %w", ErrInvalidReference, value, err)
+		}
+		ref.Name = name
+		ref.Revision = parsed
+	}
+
+	if ref.Name == "" {
+		return Reference{}, true, fmt.Errorf("%w: label '%s': missing profile name", ErrInvalidReference, value)
+	}
+
+	if annotation, found := annotations[constants.PodProfileRevisionAnnotation]; found {
+		parsed, err := parseRevision(annotation)
+		if err != nil {
+			return Reference{}, true, fmt.Errorf("%w: annotation '%s': %w", ErrInvalidReference, annotation, err)
+		}
+		if ref.Pinned() && ref.Revision != parsed {
+			return Reference{}, true, fmt.Errorf(
+				"%w: the label pins revision %d, the annotation pins revision %d",
+				ErrInvalidReference, ref.Revision, parsed)
+		}
+		ref.Revision = parsed
+	}
+
+	return ref, true, nil
+}
+
+func parseRevision(value string) (int64, error) {
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision < 1 {
+		return 0, fmt.Errorf("revision '%s' is not a positive number", value)
+	}
+	return revision, nil
+}
@@ -0,0 +1,120 @@
+package profileref
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+func TestFromPod(t *testing.T) {
+	tests := []struct {
+		name          string
+		labels        map[string]string
+		annotations   map[string]string
+		expected      Reference
+		expectedFound bool
+		expectErr     bool
+	}{
+		{
+			name:          "no label",
+			labels:        map[string]string{"app": "nginx"},
+			expectedFound: false,
+		},
+		{
+			name:          "current version",
+			labels:        map[string]string{constants.PodProfileLabel: "nginx"},
+			expected:      Reference{Name: "nginx"},
+			expectedFound: true,
+		},
+		{
+			name:          "revision pinned by the label",
+			labels:        map[string]string{constants.PodProfileLabel: "nginx.web_3"},
+			expected:      Reference{Name: "nginx.web", Revision: 3},
+			expectedFound: true,
+		},
+		{
+			name:          "revision pinned by the annotation",
+			labels:        map[string]string{constants.PodProfileLabel: "nginx"},
+			annotations:   map[string]string{constants.PodProfileRevisionAnnotation: "2"},
+			expected:      Reference{Name: "nginx", Revision: 2},
+			expectedFound: true,
+		},
+		{
+			name:          "same revision pinned by the label and the annotation",
+			labels:        map[string]string{constants.PodProfileLabel: "nginx_2"},
+			annotations:   map[string]string{constants.PodProfileRevisionAnnotation: "2"},
+			expected:      Reference{Name: "nginx", Revision: 2},
+			expectedFound: true,
+		},
+		{
+			name:          "different revisions pinned by the label and the annotation",
+			labels:        map[string]string{constants.PodProfileLabel: "nginx_2"},
+			annotations:   map[string]string{constants.PodProfileRevisionAnnotation: "3"},
+			expectedFound: true,
+			expectErr:     true,
+		},
+		{
+			name:          "invalid revision inside of the label",
+			labels:        map[string]string{constants.PodProfileLabel: "nginx_latest"},
+			expectedFound: true,
+			expectErr:     true,
+		},
+		{
+			name:          "zero revision inside of the annotation",
+			labels:        map[string]string{constants.PodProfileLabel: "nginx"},
+			annotations:   map[string]string{constants.PodProfileRevisionAnnotation: "0"},
+			expectedFound: true,
+			expectErr:     true,
+		},
+		{
+			name:          "missing profile name",
+			labels:        map[string]string{constants.PodProfileLabel: "_1"},
+			expectedFound: true,
+			expectErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, found, err := FromPod(tt.labels, tt.annotations)
+			assert.Equal(t, tt.expectedFound, found)
+			if tt.expectErr {
+				require.ErrorIs(t, err, ErrInvalidReference)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ref)
+			assert.Equal(t, tt.expected.Revision > 0, ref.Pinned())
+		})
+	}
+}
+
+func TestRevisionName(t *testing.T) {
+	name := RevisionName("nginx", "uid-1", 3)
+	assert.Regexp(t, `^nginx-[0-9a-f]{8}-3$`, name)
+	assert.Equal(t, name, Reference{Name: "nginx", Revision: 3}.RevisionName("uid-1"))
+
+	// A recreated profile gets different revision names
+	assert.NotEqual(t, name, RevisionName("nginx", "uid-2", 3))
+}
+
+func TestRevisionName_LongProfileName(t *testing.T) {
+	// The longest name of an object, with a dot where it is truncated
+	profileName := strings.Repeat("a", 223) + "." + strings.Repeat("b", 29)
+	require.Len(t, profileName, validation.DNS1123SubdomainMaxLength)
+
+	for _, revision := range []int64{1, 1<<63 - 1} {
+		name := RevisionName(profileName, "uid-1", revision)
+		assert.Empty(t, validation.IsDNS1123Subdomain(name), name)
+		assert.NotEqual(t, name, RevisionName(profileName, "uid-2", revision))
+	}
+	assert.NotEqual(t, RevisionName(profileName, "uid-1", 1), RevisionName(profileName, "uid-1", 2))
+
+	assert.Empty(t, validation.IsValidLabelValue(RevisionLabelValue(profileName)))
+	assert.Equal(t, "nginx", RevisionLabelValue("nginx"))
+}
@@ -0,0 +1,4 @@
+// Package profilevalidation validates the spec of LandlockProfiles. The
+// checks are shared by the validating webhook and by the controller, which
+// validates the profiles before creating their revisions.
+package profilevalidation
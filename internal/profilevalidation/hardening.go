@@ -0,0 +1,154 @@
+package profilevalidation
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
+)
+
+const (
+	fieldCapabilities = "capabilities"
+	fieldDrop         = "drop"
+	fieldRlimits      = "rlimits"
+	fieldSeccomp      = "seccomp"
+	fieldEnvironment  = "environment"
+	fieldSHA256       = "sha256"
+)
+
+func validateCapabilities(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if profile.Capabilities == nil {
+		return allErrs
+	}
+
+	dropPath := fldPath.Child(fieldCapabilities).Child(fieldDrop)
+	for i, name := range profile.Capabilities.Drop {
+		if strings.EqualFold(name, seal.AllCapabilities) {
+			continue
+		}
+		if _, err := seal.ParseCapability(name); err != nil {
+			allErrs = append(allErrs, field.Invalid(dropPath.Index(i), name, "unknown capability"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateRlimits(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	seen := sets.New[string]()
+	for i, rlimit := range profile.Rlimits {
+		rlimitPath := fldPath.Child(fieldRlimits).Index(i)
+
+		if _, err := seal.ParseRlimitType(rlimit.Type); err != nil {
+			allErrs = append(allErrs, field.Invalid(rlimitPath.Child("type"), rlimit.Type, "unknown rlimit type"))
+		}
+		if seen.Has(rlimit.Type) {
+			allErrs = append(allErrs, field.Duplicate(rlimitPath.Child("type"), rlimit.Type))
+		}
+		seen.Insert(rlimit.Type)
+
+		if rlimit.Soft < v1alpha1.RlimitUnlimited {
+			allErrs = append(allErrs, field.Invalid(rlimitPath.Child("soft"), rlimit.Soft,
+				"must be greater than or equal to 0, or -1 for unlimited"))
+		}
+		if rlimit.Hard < v1alpha1.RlimitUnlimited {
+			allErrs = append(allErrs, field.Invalid(rlimitPath.Child("hard"), rlimit.Hard,
+				"must be greater than or equal to 0, or -1 for unlimited"))
+		}
+		if seal.RlimitValue(rlimit.Soft) > seal.RlimitValue(rlimit.Hard) {
+			allErrs = append(allErrs, field.Invalid(rlimitPath.Child("soft"), rlimit.Soft, "must be less than or equal to the hard limit"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateSeccomp(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	policy := profile.Seccomp
+	if policy == nil {
+		return allErrs
+	}
+	seccompPath := fldPath.Child(fieldSeccomp)
+
+	if len(policy.Allow) > 0 && (policy.Preset != "" || len(policy.Deny) > 0) {
+		allErrs = append(allErrs, field.Forbidden(seccompPath.Child("allow"), "cannot be used together with preset or deny"))
+	}
+
+	if policy.Preset != "" && !seal.IsKnownSeccompPreset(policy.Preset) {
+		allErrs = append(allErrs, field.NotSupported(seccompPath.Child("preset"), policy.Preset, []string{seal.SeccompPresetBaseline}))
+	}
+
+	switch policy.Action {
+	case "", seal.SeccompActionErrno, seal.SeccompActionKill:
+	default:
+		allErrs = append(allErrs, field.NotSupported(seccompPath.Child("action"), policy.Action,
+			[]string{seal.SeccompActionErrno, seal.SeccompActionKill}))
+	}
+
+	for i, name := range policy.Allow {
+		if !seal.IsKnownSyscall(name) {
+			allErrs = append(allErrs, field.Invalid(seccompPath.Child("allow").Index(i), name, "unknown system call"))
+		}
+	}
+	for i, name := range policy.Deny {
+		if !seal.IsKnownSyscall(name) {
+			allErrs = append(allErrs, field.Invalid(seccompPath.Child("deny").Index(i), name, "unknown system call"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateEnvironment(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	policy := profile.Environment
+	if policy == nil {
+		return allErrs
+	}
+	envPath := fldPath.Child(fieldEnvironment)
+
+	for i, pattern := range policy.Allow {
+		if err := seal.ValidateEnvPattern(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(envPath.Child("allow").Index(i), pattern, err.Error()))
+		}
+	}
+	for i, pattern := range policy.Deny {
+		if err := seal.ValidateEnvPattern(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(envPath.Child("deny").Index(i), pattern, err.Error()))
+		}
+	}
+
+	for name := range policy.Set {
+		setPath := envPath.Child("set").Key(name)
+		if err := seal.ValidateEnvName(name); err != nil {
+			allErrs = append(allErrs, field.Invalid(setPath, name, err.Error()))
+			continue
+		}
+		if strings.HasPrefix(name, seal.SealEnvVarPrefix) || name == seal.DomainEnvVar {
+			allErrs = append(allErrs, field.Forbidden(setPath, "environment variable is reserved to seal"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateSHA256(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if profile.SHA256 != "" && !seal.IsValidSHA256Digest(profile.SHA256) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child(fieldSHA256), profile.SHA256,
+			"must be a lowercase hex encoded sha256 digest"))
+	}
+
+	return allErrs
+}
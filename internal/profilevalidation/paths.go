@@ -1,4 +1,4 @@
-package v1alpha1
+package profilevalidation
 
 import (
 	"fmt"
@@ -16,7 +16,7 @@ const (
 	fieldReadWrite     = "readWrite"
 )
 
-func (v *LandlockProfileCustomValidator) validateBinaryPath(path string, fldPath *field.Path) field.ErrorList {
+func validateBinaryPath(path string, fldPath *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
 
 	if !filepath.IsAbs(path) {
@@ -30,47 +30,47 @@ func (v *LandlockProfileCustomValidator) validateBinaryPath(path string, fldPath
 	return allErrs
 }
 
-func (v *LandlockProfileCustomValidator) validateReadOnlyPaths(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
+func validateReadOnlyPaths(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
 
 	for i, path := range profile.ReadOnly {
-		allErrs = append(allErrs, v.validateBinaryPath(path, fldPath.Child(fieldReadOnly).Index(i))...)
+		allErrs = append(allErrs, validateBinaryPath(path, fldPath.Child(fieldReadOnly).Index(i))...)
 	}
 
 	return allErrs
 }
 
-func (v *LandlockProfileCustomValidator) validateReadWritePaths(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
+func validateReadWritePaths(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
 
 	for i, path := range profile.ReadWrite {
-		allErrs = append(allErrs, v.validateBinaryPath(path, fldPath.Child(fieldReadWrite).Index(i))...)
+		allErrs = append(allErrs, validateBinaryPath(path, fldPath.Child(fieldReadWrite).Index(i))...)
 	}
 
 	return allErrs
 }
 
-func (v *LandlockProfileCustomValidator) validateReadExecPaths(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
+func validateReadExecPaths(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
 
 	for i, path := range profile.ReadExec {
-		allErrs = append(allErrs, v.validateBinaryPath(path, fldPath.Child(fieldReadExec).Index(i))...)
+		allErrs = append(allErrs, validateBinaryPath(path, fldPath.Child(fieldReadExec).Index(i))...)
 	}
 
 	return allErrs
 }
 
-func (v *LandlockProfileCustomValidator) validateReadWriteExecPaths(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
+func validateReadWriteExecPaths(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
 
 	for i, path := range profile.ReadWriteExec {
-		allErrs = append(allErrs, v.validateBinaryPath(path, fldPath.Child(fieldReadWriteExec).Index(i))...)
+		allErrs = append(allErrs, validateBinaryPath(path, fldPath.Child(fieldReadWriteExec).Index(i))...)
 	}
 
 	return allErrs
 }
 
-func (v *LandlockProfileCustomValidator) validateNoOverlappingPaths(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
+func validateNoOverlappingPaths(profile v1alpha1.Profile, fldPath *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
 
 	readOnly := sets.New(profile.ReadOnly...)
@@ -0,0 +1,37 @@
+package profilevalidation
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+)
+
+// ValidateLandlockProfile returns the errors found inside of the spec of the
+// profile.
+func ValidateLandlockProfile(profile *v1alpha1.LandlockProfile) field.ErrorList {
+	var allErrs field.ErrorList
+
+	specPath := field.NewPath("spec", "profilesByContainer")
+	for containerName, profileByBinary := range profile.Spec.ProfilesByContainer {
+		containerPath := specPath.Key(containerName)
+		for binaryPath, binProfile := range profileByBinary {
+			binaryPathField := containerPath.Key(binaryPath)
+
+			allErrs = append(allErrs, validateBinaryPath(binaryPath, binaryPathField)...)
+			allErrs = append(allErrs, validateNoOverlappingPaths(binProfile, binaryPathField)...)
+			allErrs = append(allErrs, validateReadOnlyPaths(binProfile, binaryPathField)...)
+			allErrs = append(allErrs, validateReadWritePaths(binProfile, binaryPathField)...)
+			allErrs = append(allErrs, validateReadExecPaths(binProfile, binaryPathField)...)
+			allErrs = append(allErrs, validateReadWriteExecPaths(binProfile, binaryPathField)...)
+			allErrs = append(allErrs, validateCapabilities(binProfile, binaryPathField)...)
+			allErrs = append(allErrs, validateRlimits(binProfile, binaryPathField)...)
+			allErrs = append(allErrs, validateSeccomp(binProfile, binaryPathField)...)
+			allErrs = append(allErrs, validateEnvironment(binProfile, binaryPathField)...)
+			allErrs = append(allErrs, validateSHA256(binProfile, binaryPathField)...)
+		}
+	}
+
+	allErrs = append(allErrs, validateUpdateStrategy(profile.Spec.UpdateStrategy, field.NewPath("spec", "updateStrategy"))...)
+
+	return allErrs
+}
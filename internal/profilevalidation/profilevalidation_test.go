@@ -0,0 +1,66 @@
+package profilevalidation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+)
+
+func TestValidateLandlockProfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       v1alpha1.LandlockProfileSpec
+		wantFields []string
+	}{
+		{
+			name: "valid profile",
+			spec: v1alpha1.LandlockProfileSpec{
+				ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+					"nginx": {
+						"/usr/sbin/nginx": {
+							ReadOnly: []string{"/etc/nginx"},
+							ReadExec: []string{"/lib", "/lib64"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid binary and paths",
+			spec: v1alpha1.LandlockProfileSpec{
+				ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+					"nginx": {
+						"usr/sbin/nginx": {
+							ReadOnly: []string{"etc/nginx"},
+						},
+					},
+				},
+			},
+			wantFields: []string{
+				"spec.profilesByContainer[nginx][usr/sbin/nginx]",
+				"spec.profilesByContainer[nginx][usr/sbin/nginx].readOnly[0]",
+			},
+		},
+		{
+			name: "canary strategy without settings",
+			spec: v1alpha1.LandlockProfileSpec{
+				UpdateStrategy: &v1alpha1.UpdateStrategy{Type: v1alpha1.CanaryUpdateStrategyType},
+			},
+			wantFields: []string{"spec.updateStrategy.canary"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateLandlockProfile(&v1alpha1.LandlockProfile{Spec: tt.spec})
+
+			var fields []string
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			assert.ElementsMatch(t, tt.wantFields, fields, errs.ToAggregate())
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package profilevalidation
+
+import (
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+)
+
+func validateUpdateStrategy(
+	strategy *v1alpha1.UpdateStrategy,
+	fldPath *field.Path,
+) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if strategy == nil || strategy.Type != v1alpha1.CanaryUpdateStrategyType {
+		return allErrs
+	}
+
+	canaryPath := fldPath.Child("canary")
+	canary := strategy.Canary
+	if canary == nil {
+		return append(allErrs, field.Required(canaryPath, "required by the Canary update strategy"))
+	}
+
+	if canary.Percentage == 0 && canary.Selector == nil {
+		allErrs = append(allErrs, field.Required(canaryPath,
+			"either percentage or selector must be set, otherwise no pod is selected for the canary"))
+	}
+
+	if canary.Selector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(
+			canary.Selector,
+			metav1validation.LabelSelectorValidationOptions{},
+			canaryPath.Child("selector"),
+		)...)
+	}
+
+	if canary.AnalysisPeriod != nil && canary.ProgressDeadline != nil &&
+		canary.ProgressDeadline.Duration < canary.AnalysisPeriod.Duration {
+		allErrs = append(allErrs, field.Invalid(canaryPath.Child("progressDeadline"),
+			canary.ProgressDeadline.Duration.String(), "must not be shorter than analysisPeriod"))
+	}
+
+	return allErrs
+}
@@ -0,0 +1,83 @@
+package seal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/pkg/constants"
+)
+
+// AppliedProfile records the profile applied by the NRI plugin to a container.
+// It is stored inside of an annotation of the pod, so that the controller can
+// find the pods running an outdated version of their LandlockProfile.
+type AppliedProfile struct {
+	// Generation is the generation of the LandlockProfile applied to the
+	// container.
+	Generation int64 `json:"generation"`
+	// Hash is the hash of the profiles of the binaries of the container, as
+	// computed by ContainerProfileHash.
+	Hash string `json:"hash"`
+}
+
+// NewAppliedProfile returns the AppliedProfile of the given container.
+func NewAppliedProfile(profile *podlockv1alpha1.LandlockProfile, containerName string) (*AppliedProfile, error) {
+	hash, err := ContainerProfileHash(profile.Spec.ProfilesByContainer[containerName])
+	if err != nil {
+		return nil, err
+	}
+
+	return &AppliedProfile{
+		Generation: profile.GetGeneration(),
+		Hash:       hash,
+	}, nil
+}
+
+// ContainerProfileHash returns the hash of the profiles of the binaries of a
+// container. Unlike the generation of the LandlockProfile, the hash does not
+// change when the profiles of the other containers are changed.
+func ContainerProfileHash(profileByBinary podlockv1alpha1.ProfileByBinary) (string, error) {
+	if profileByBinary == nil {
+		profileByBinary = podlockv1alpha1.ProfileByBinary{}
+	}
+
+	// The keys of the maps are sorted by the encoder, the output is stable
+	data, err := json.Marshal(profileByBinary)
+	if err != nil {
+		return "", fmt.Errorf("cannot marshal container profile: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AppliedProfileAnnotation returns the pod annotation holding the
+// AppliedProfile of the given container.
+func AppliedProfileAnnotation(containerName string) string {
+	return constants.AppliedProfileAnnotationPrefix + containerName
+}
+
+// AppliedProfilesFromAnnotations returns the AppliedProfile of each container,
+// keyed by container name, found inside of the given pod annotations.
+// Malformed annotations are ignored.
+func AppliedProfilesFromAnnotations(annotations map[string]string) map[string]AppliedProfile {
+	applied := map[string]AppliedProfile{}
+
+	for key, value := range annotations {
+		containerName, found := strings.CutPrefix(key, constants.AppliedProfileAnnotationPrefix)
+		if !found || containerName == "" {
+			continue
+		}
+
+		var appliedProfile AppliedProfile
+		if err := json.Unmarshal([]byte(value), &appliedProfile); err != nil {
+			continue
+		}
+		applied[containerName] = appliedProfile
+	}
+
+	return applied
+}
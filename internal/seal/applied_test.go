@@ -0,0 +1,85 @@
+package seal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+func TestContainerProfileHash(t *testing.T) {
+	nginx := podlockv1alpha1.ProfileByBinary{
+		"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}},
+	}
+
+	hash, err := ContainerProfileHash(nginx)
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	same, err := ContainerProfileHash(podlockv1alpha1.ProfileByBinary{
+		"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, hash, same)
+
+	changed, err := ContainerProfileHash(podlockv1alpha1.ProfileByBinary{
+		"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx", "/var/www"}},
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, changed)
+
+	empty, err := ContainerProfileHash(podlockv1alpha1.ProfileByBinary{})
+	require.NoError(t, err)
+	missing, err := ContainerProfileHash(nil)
+	require.NoError(t, err)
+	assert.Equal(t, empty, missing)
+}
+
+func TestNewAppliedProfile(t *testing.T) {
+	profile := &podlockv1alpha1.LandlockProfile{
+		ObjectMeta: metav1.ObjectMeta{Generation: 4},
+		Spec: podlockv1alpha1.LandlockProfileSpec{
+			ProfilesByContainer: map[string]podlockv1alpha1.ProfileByBinary{
+				"main":    {"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}}},
+				"sidecar": {"/usr/bin/envoy": {ReadOnly: []string{"/etc/envoy"}}},
+			},
+		},
+	}
+
+	applied, err := NewAppliedProfile(profile, "main")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), applied.Generation)
+
+	expectedHash, err := ContainerProfileHash(profile.Spec.ProfilesByContainer["main"])
+	require.NoError(t, err)
+	assert.Equal(t, expectedHash, applied.Hash)
+
+	// Changing another container does not change the hash
+	profile.Spec.ProfilesByContainer["sidecar"] = podlockv1alpha1.ProfileByBinary{}
+	profile.Generation = 5
+	updated, err := NewAppliedProfile(profile, "main")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), updated.Generation)
+	assert.Equal(t, applied.Hash, updated.Hash)
+}
+
+func TestAppliedProfilesFromAnnotations(t *testing.T) {
+	mainValue, err := json.Marshal(AppliedProfile{Generation: 2, Hash: "abc"})
+	require.NoError(t, err)
+
+	annotations := map[string]string{
+		AppliedProfileAnnotation("main"):    string(mainValue),
+		AppliedProfileAnnotation("sidecar"): "not json",
+		AppliedProfileAnnotation(""):        string(mainValue),
+		"example.com/other":                 string(mainValue),
+	}
+
+	assert.Equal(t,
+		map[string]AppliedProfile{"main": {Generation: 2, Hash: "abc"}},
+		AppliedProfilesFromAnnotations(annotations))
+	assert.Empty(t, AppliedProfilesFromAnnotations(nil))
+}
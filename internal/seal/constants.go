@@ -5,5 +5,18 @@ const (
 	LogLevelEnvVar           = "SEAL_LOG_LEVEL"
 	LogFormatEnvVar          = "SEAL_LOG_FORMAT"
 	AddLinkedLibrariesEnvVar = "SEAL_ADD_LINKED_LIBRARIES"
+	NestingPolicyEnvVar      = "SEAL_NESTING_POLICY"
+	TerminationLogEnvVar     = "SEAL_TERMINATION_LOG_PATH"
+	RulesetCacheDirEnvVar    = "SEAL_RULESET_CACHE_DIR"
 	SealEnvVarPrefix         = "SEAL_"
+
+	// DomainEnvVar is set by seal on the sealed process to record the PodLock
+	// domain it is running inside of. It intentionally does not start with
+	// SealEnvVarPrefix, so that it is inherited by the children of the sealed
+	// process.
+	DomainEnvVar = "PODLOCK_DOMAIN"
+
+	// DefaultTerminationLogPath is the default value of the
+	// terminationMessagePath of Kubernetes containers.
+	DefaultTerminationLogPath = "/dev/termination-log"
 )
@@ -0,0 +1,92 @@
+package seal
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+// envPatternWildcard is the suffix used by the environment policy to match
+// all the variables starting with a given prefix.
+const envPatternWildcard = "*"
+
+// ValidateEnvPattern returns an error when the given entry of an allow or
+// deny list is not a valid environment variable name or prefix pattern.
+func ValidateEnvPattern(pattern string) error {
+	name := strings.TrimSuffix(pattern, envPatternWildcard)
+	if name == "" && pattern != envPatternWildcard {
+		return fmt.Errorf("invalid environment variable pattern '%s'", pattern)
+	}
+	if strings.ContainsAny(name, "=*") || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("invalid environment variable pattern '%s'", pattern)
+	}
+	return nil
+}
+
+// ValidateEnvName returns an error when the given string cannot be used as
+// the name of an environment variable.
+func ValidateEnvName(name string) error {
+	if name == "" || strings.ContainsRune(name, '=') || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("invalid environment variable name '%s'", name)
+	}
+	return nil
+}
+
+// matchesEnvPattern returns true when the name of the variable matches one
+// of the given patterns.
+func matchesEnvPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, found := strings.CutSuffix(pattern, envPatternWildcard); found {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+			continue
+		}
+		if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyEnvironmentPolicy filters the given environment, in the form
+// "key=value", according to the policy.
+//
+// Variables not matching the allow list, when one is defined, and the ones
+// matching the deny list are removed. The variables defined by the policy
+// are then forced to their values.
+// It returns the new environment and the sorted names of the variables
+// that have been removed.
+func ApplyEnvironmentPolicy(env []string, policy *podlockv1alpha1.EnvironmentPolicy) ([]string, []string) {
+	if policy == nil {
+		return env, nil
+	}
+
+	var (
+		result  []string
+		removed []string
+	)
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+
+		if _, forced := policy.Set[name]; forced {
+			continue
+		}
+		if (len(policy.Allow) > 0 && !matchesEnvPattern(name, policy.Allow)) ||
+			matchesEnvPattern(name, policy.Deny) {
+			removed = append(removed, name)
+			continue
+		}
+		result = append(result, kv)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(policy.Set)) {
+		result = append(result, name+"="+policy.Set[name])
+	}
+
+	slices.Sort(removed)
+	return result, slices.Compact(removed)
+}
@@ -0,0 +1,113 @@
+package seal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+func TestValidateEnvPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{pattern: "LD_PRELOAD"},
+		{pattern: "LD_*"},
+		{pattern: "*"},
+		{pattern: "", wantErr: true},
+		{pattern: "LD_*_PATH", wantErr: true},
+		{pattern: "**", wantErr: true},
+		{pattern: "FOO=bar", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			err := ValidateEnvPattern(tt.pattern)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestApplyEnvironmentPolicy(t *testing.T) {
+	env := []string{
+		"PATH=/usr/bin",
+		"HOME=/root",
+		"LD_PRELOAD=/tmp/evil.so",
+		"LD_LIBRARY_PATH=/tmp",
+		"PYTHONPATH=/tmp",
+		"NODE_OPTIONS=--require /tmp/evil.js",
+		"APP_PORT=8080",
+	}
+
+	tests := []struct {
+		name        string
+		policy      *podlockv1alpha1.EnvironmentPolicy
+		wantEnv     []string
+		wantRemoved []string
+	}{
+		{
+			name:    "no policy",
+			policy:  nil,
+			wantEnv: env,
+		},
+		{
+			name: "deny list",
+			policy: &podlockv1alpha1.EnvironmentPolicy{
+				Deny: []string{"LD_*", "PYTHONPATH", "NODE_OPTIONS"},
+			},
+			wantEnv:     []string{"PATH=/usr/bin", "HOME=/root", "APP_PORT=8080"},
+			wantRemoved: []string{"LD_LIBRARY_PATH", "LD_PRELOAD", "NODE_OPTIONS", "PYTHONPATH"},
+		},
+		{
+			name: "allow list",
+			policy: &podlockv1alpha1.EnvironmentPolicy{
+				Allow: []string{"PATH", "APP_*"},
+			},
+			wantEnv:     []string{"PATH=/usr/bin", "APP_PORT=8080"},
+			wantRemoved: []string{"HOME", "LD_LIBRARY_PATH", "LD_PRELOAD", "NODE_OPTIONS", "PYTHONPATH"},
+		},
+		{
+			name: "deny wins over allow",
+			policy: &podlockv1alpha1.EnvironmentPolicy{
+				Allow: []string{"*"},
+				Deny:  []string{"LD_PRELOAD"},
+			},
+			wantEnv: []string{
+				"PATH=/usr/bin",
+				"HOME=/root",
+				"LD_LIBRARY_PATH=/tmp",
+				"PYTHONPATH=/tmp",
+				"NODE_OPTIONS=--require /tmp/evil.js",
+				"APP_PORT=8080",
+			},
+			wantRemoved: []string{"LD_PRELOAD"},
+		},
+		{
+			name: "forced values",
+			policy: &podlockv1alpha1.EnvironmentPolicy{
+				Allow: []string{"PATH"},
+				Set: map[string]string{
+					"PATH":     "/bin",
+					"APP_MODE": "production",
+				},
+			},
+			wantEnv:     []string{"APP_MODE=production", "PATH=/bin"},
+			wantRemoved: []string{"APP_PORT", "HOME", "LD_LIBRARY_PATH", "LD_PRELOAD", "NODE_OPTIONS", "PYTHONPATH"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotEnv, gotRemoved := ApplyEnvironmentPolicy(env, tt.policy)
+			assert.Equal(t, tt.wantEnv, gotEnv)
+			assert.Equal(t, tt.wantRemoved, gotRemoved)
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package seal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	ll "github.com/landlock-lsm/go-landlock/landlock/syscall"
+	"golang.org/x/sys/unix"
+	"kernel.org/pub/linux/libs/security/libcap/psx"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+// AllCapabilities is the special capability name used to refer to all
+// the capabilities.
+const AllCapabilities = "ALL"
+
+const capabilityPrefix = "CAP_"
+
+//nolint:gochecknoglobals // lookup table
+var capabilities = map[string]int{
+	"CAP_CHOWN":              unix.CAP_CHOWN,
+	"CAP_DAC_OVERRIDE":       unix.CAP_DAC_OVERRIDE,
+	"CAP_DAC_READ_SEARCH":    unix.CAP_DAC_READ_SEARCH,
+	"CAP_FOWNER":             unix.CAP_FOWNER,
+	"CAP_FSETID":             unix.CAP_FSETID,
+	"CAP_KILL":               unix.CAP_KILL,
+	"CAP_SETGID":             unix.CAP_SETGID,
+	"CAP_SETUID":             unix.CAP_SETUID,
+	"CAP_SETPCAP":            unix.CAP_SETPCAP,
+	"CAP_LINUX_IMMUTABLE":    unix.CAP_LINUX_IMMUTABLE,
+	"CAP_NET_BIND_SERVICE":   unix.CAP_NET_BIND_SERVICE,
+	"CAP_NET_BROADCAST":      unix.CAP_NET_BROADCAST,
+	"CAP_NET_ADMIN":          unix.CAP_NET_ADMIN,
+	"CAP_NET_RAW":            unix.CAP_NET_RAW,
+	"CAP_IPC_LOCK":           unix.CAP_IPC_LOCK,
+	"CAP_IPC_OWNER":          unix.CAP_IPC_OWNER,
+	"CAP_SYS_MODULE":         unix.CAP_SYS_MODULE,
+	"CAP_SYS_RAWIO":          unix.CAP_SYS_RAWIO,
+	"CAP_SYS_CHROOT":         unix.CAP_SYS_CHROOT,
+	"CAP_SYS_PTRACE":         unix.CAP_SYS_PTRACE,
+	"CAP_SYS_PACCT":          unix.CAP_SYS_PACCT,
+	"CAP_SYS_ADMIN":          unix.CAP_SYS_ADMIN,
+	"CAP_SYS_BOOT":           unix.CAP_SYS_BOOT,
+	"CAP_SYS_NICE":           unix.CAP_SYS_NICE,
+	"CAP_SYS_RESOURCE":       unix.CAP_SYS_RESOURCE,
+	"CAP_SYS_TIME":           unix.CAP_SYS_TIME,
+	"CAP_SYS_TTY_CONFIG":     unix.CAP_SYS_TTY_CONFIG,
+	"CAP_MKNOD":              unix.CAP_MKNOD,
+	"CAP_LEASE":              unix.CAP_LEASE,
+	"CAP_AUDIT_WRITE":        unix.CAP_AUDIT_WRITE,
+	"CAP_AUDIT_CONTROL":      unix.CAP_AUDIT_CONTROL,
+	"CAP_SETFCAP":            unix.CAP_SETFCAP,
+	"CAP_MAC_OVERRIDE":       unix.CAP_MAC_OVERRIDE,
+	"CAP_MAC_ADMIN":          unix.CAP_MAC_ADMIN,
+	"CAP_SYSLOG":             unix.CAP_SYSLOG,
+	"CAP_WAKE_ALARM":         unix.CAP_WAKE_ALARM,
+	"CAP_BLOCK_SUSPEND":      unix.CAP_BLOCK_SUSPEND,
+	"CAP_AUDIT_READ":         unix.CAP_AUDIT_READ,
+	"CAP_PERFMON":            unix.CAP_PERFMON,
+	"CAP_BPF":                unix.CAP_BPF,
+	"CAP_CHECKPOINT_RESTORE": unix.CAP_CHECKPOINT_RESTORE,
+}
+
+//nolint:gochecknoglobals // lookup table
+var rlimits = map[string]int{
+	"RLIMIT_AS":      unix.RLIMIT_AS,
+	"RLIMIT_CORE":    unix.RLIMIT_CORE,
+	"RLIMIT_CPU":     unix.RLIMIT_CPU,
+	"RLIMIT_DATA":    unix.RLIMIT_DATA,
+	"RLIMIT_FSIZE":   unix.RLIMIT_FSIZE,
+	"RLIMIT_MEMLOCK": unix.RLIMIT_MEMLOCK,
+	"RLIMIT_NOFILE":  unix.RLIMIT_NOFILE,
+	"RLIMIT_NPROC":   unix.RLIMIT_NPROC,
+	"RLIMIT_STACK":   unix.RLIMIT_STACK,
+}
+
+// ParseCapability returns the number of the given capability. The name is
+// case insensitive and can be specified with or without the "CAP_" prefix.
+func ParseCapability(name string) (int, error) {
+	normalized := strings.ToUpper(name)
+	if !strings.HasPrefix(normalized, capabilityPrefix) {
+		normalized = capabilityPrefix + normalized
+	}
+
+	capability, found := capabilities[normalized]
+	if !found {
+		return 0, fmt.Errorf("unknown capability '%s'", name)
+	}
+
+	return capability, nil
+}
+
+// ParseRlimitType returns the resource number of the given rlimit type.
+func ParseRlimitType(name string) (int, error) {
+	resource, found := rlimits[name]
+	if !found {
+		return 0, fmt.Errorf("unknown rlimit type '%s'", name)
+	}
+
+	return resource, nil
+}
+
+// capabilitiesToDrop returns the sorted list of capabilities referenced by
+// the given names.
+func capabilitiesToDrop(names []string) ([]int, error) {
+	var result []int
+
+	for _, name := range names {
+		if strings.EqualFold(name, AllCapabilities) {
+			result = result[:0]
+			for capability := 0; capability <= unix.CAP_LAST_CAP; capability++ {
+				result = append(result, capability)
+			}
+			return result, nil
+		}
+
+		capability, err := ParseCapability(name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, capability)
+	}
+
+	slices.Sort(result)
+	return slices.Compact(result), nil
+}
+
+// ErrBoundingSetNotDropped is returned when a process running as root lacks
+// CAP_SETPCAP, hence it cannot remove the capabilities from its bounding set.
+// The binaries it executes would regain them.
+var ErrBoundingSetNotDropped = errors.New("cannot drop the capabilities from the bounding set without CAP_SETPCAP")
+
+//nolint:gochecknoglobals // see dropFromCapabilitySets
+var (
+	capsetMutex  sync.Mutex
+	capsetHeader unix.CapUserHeader
+	capsetData   [2]unix.CapUserData
+)
+
+// ApplyProcessHardening drops the capabilities and sets the resource limits
+// defined inside of the profile.
+//
+// Capabilities are a per-thread attribute, they are changed on all the
+// threads of the process at the same time.
+func ApplyProcessHardening(profile *podlockv1alpha1.Profile, logger *slog.Logger) error {
+	if profile.Capabilities != nil {
+		if err := dropCapabilities(profile.Capabilities, logger); err != nil {
+			return err
+		}
+	}
+
+	if err := setRlimits(profile.Rlimits, logger); err != nil {
+		return err
+	}
+
+	noNewPrivs, err := NoNewPrivs()
+	if err != nil {
+		return err
+	}
+	logger.Info("process hardening applied", slog.Bool("noNewPrivs", noNewPrivs))
+
+	return nil
+}
+
+// NoNewPrivs reports whether the no_new_privs bit is set on the current thread.
+// The bit is set as part of the Landlock enforcement.
+func NoNewPrivs() (bool, error) {
+	value, err := unix.PrctlRetInt(unix.PR_GET_NO_NEW_PRIVS, 0, 0, 0, 0)
+	if err != nil {
+		return false, fmt.Errorf("cannot read no_new_privs: %w", err)
+	}
+	return value == 1, nil
+}
+
+func dropCapabilities(caps *podlockv1alpha1.Capabilities, logger *slog.Logger) error {
+	toDrop, err := capabilitiesToDrop(caps.Drop)
+	if err != nil {
+		return err
+	}
+
+	// Remove the capabilities from the bounding set first, this requires
+	// CAP_SETPCAP, which could be dropped later on.
+	//
+	// When executing a binary, the kernel grants to root all the capabilities
+	// of the bounding set: a process running as root without CAP_SETPCAP is
+	// refused. Processes not running as root usually lack CAP_SETPCAP, the
+	// bounding set is left untouched then: they do not gain the capabilities
+	// back from the bounding set, unless a binary has file capabilities or
+	// the setuid bit.
+	canDropBounding, err := hasEffectiveCapability(unix.CAP_SETPCAP)
+	if err != nil {
+		return err
+	}
+	var notDropped []int
+	for _, capability := range toDrop {
+		inBoundingSet, err := unix.PrctlRetInt(unix.PR_CAPBSET_READ, uintptr(capability), 0, 0, 0)
+		if err != nil {
+			if errors.Is(err, unix.EINVAL) {
+				// The capability is not supported by the running kernel
+				continue
+			}
+			return fmt.Errorf("cannot read bounding set for capability %d: %w", capability, err)
+		}
+		if inBoundingSet == 0 {
+			continue
+		}
+		if !canDropBounding {
+			notDropped = append(notDropped, capability)
+			continue
+		}
+
+		if err := ll.AllThreadsPrctl(unix.PR_CAPBSET_DROP, uintptr(capability), 0, 0, 0); err != nil {
+			return fmt.Errorf("cannot drop capability %d from the bounding set: %w", capability, err)
+		}
+	}
+
+	if len(notDropped) > 0 {
+		if os.Geteuid() == 0 {
+			return fmt.Errorf("%w: capabilities %v", ErrBoundingSetNotDropped, notDropped)
+		}
+		logger.Warn("CAP_SETPCAP is not available, the capabilities are not dropped from the bounding set",
+			slog.Any("capabilities", notDropped),
+		)
+	}
+
+	if len(toDrop) > 0 {
+		if err := dropFromCapabilitySets(toDrop); err != nil {
+			return err
+		}
+	}
+
+	if caps.ClearAmbient {
+		if err := ll.AllThreadsPrctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+			return fmt.Errorf("cannot clear the ambient capabilities: %w", err)
+		}
+	}
+
+	logger.Info("capabilities dropped",
+		slog.Any("capabilities", caps.Drop),
+		slog.Bool("clearAmbient", caps.ClearAmbient),
+	)
+
+	return nil
+}
+
+// hasEffectiveCapability reports whether the capability is in the effective
+// set of the current thread.
+func hasEffectiveCapability(capability int) (bool, error) {
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&header, &data[0]); err != nil {
+		return false, fmt.Errorf("cannot read capabilities: %w", err)
+	}
+
+	return data[capability/32].Effective&(1<<(uint(capability)%32)) != 0, nil
+}
+
+// dropFromCapabilitySets removes the given capabilities from the effective,
+// permitted and inheritable sets of all the threads. Removing a capability
+// from the inheritable set removes it from the ambient set too.
+//
+// The arguments of capset are package level variables: the system call is
+// performed by each thread after this function hands over the pointers, so
+// the memory they reference must never be moved.
+func dropFromCapabilitySets(toDrop []int) error {
+	capsetMutex.Lock()
+	defer capsetMutex.Unlock()
+
+	capsetHeader = unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	capsetData = [2]unix.CapUserData{}
+
+	if err := unix.Capget(&capsetHeader, &capsetData[0]); err != nil {
+		return fmt.Errorf("cannot read capabilities: %w", err)
+	}
+
+	for _, capability := range toDrop {
+		mask := ^uint32(1 << (uint(capability) % 32))
+		idx := capability / 32
+		capsetData[idx].Effective &= mask
+		capsetData[idx].Permitted &= mask
+		capsetData[idx].Inheritable &= mask
+	}
+
+	if _, _, errno := psx.Syscall3(
+		unix.SYS_CAPSET,
+		uintptr(unsafe.Pointer(&capsetHeader)),
+		uintptr(unsafe.Pointer(&capsetData[0])),
+		0,
+	); errno != 0 {
+		return fmt.Errorf("cannot set capabilities: %w", errno)
+	}
+
+	return nil
+}
+
+// RlimitValue converts the value of a limit defined inside of a profile into
+// the one passed to setrlimit, where RlimitUnlimited is RLIM_INFINITY.
+func RlimitValue(value int64) uint64 {
+	if value == podlockv1alpha1.RlimitUnlimited {
+		return unix.RLIM_INFINITY
+	}
+	return uint64(value)
+}
+
+func setRlimits(limits []podlockv1alpha1.Rlimit, logger *slog.Logger) error {
+	for _, limit := range limits {
+		resource, err := ParseRlimitType(limit.Type)
+		if err != nil {
+			return err
+		}
+		if limit.Soft < podlockv1alpha1.RlimitUnlimited || limit.Hard < podlockv1alpha1.RlimitUnlimited {
+			return fmt.Errorf("invalid %s: limits cannot be negative, except for %d meaning unlimited",
+				limit.Type, podlockv1alpha1.RlimitUnlimited)
+		}
+
+		// syscall.Setrlimit must be used instead of unix.Setrlimit, otherwise
+		// syscall.Exec restores the original RLIMIT_NOFILE soft limit
+		// of the process.
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{
+			Cur: RlimitValue(limit.Soft),
+			Max: RlimitValue(limit.Hard),
+		}); err != nil {
+			return fmt.Errorf("cannot set %s: %w", limit.Type, err)
+		}
+
+		logger.Info("rlimit set",
+			slog.String("type", limit.Type),
+			slog.Int64("soft", limit.Soft),
+			slog.Int64("hard", limit.Hard),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package seal
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+func TestParseCapability(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:  "with prefix",
+			input: "CAP_NET_RAW",
+			want:  unix.CAP_NET_RAW,
+		},
+		{
+			name:  "without prefix",
+			input: "SYS_ADMIN",
+			want:  unix.CAP_SYS_ADMIN,
+		},
+		{
+			name:  "lower case",
+			input: "cap_bpf",
+			want:  unix.CAP_BPF,
+		},
+		{
+			name:    "unknown",
+			input:   "CAP_DOES_NOT_EXIST",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCapability(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseRlimitType(t *testing.T) {
+	resource, err := ParseRlimitType("RLIMIT_NOFILE")
+	require.NoError(t, err)
+	assert.Equal(t, unix.RLIMIT_NOFILE, resource)
+
+	_, err = ParseRlimitType("rlimit_nofile")
+	require.Error(t, err)
+}
+
+func TestCapabilitiesToDrop(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []string
+		want    []int
+		wantErr bool
+	}{
+		{
+			name:  "empty",
+			input: nil,
+			want:  nil,
+		},
+		{
+			name:  "sorted and deduplicated",
+			input: []string{"SYS_ADMIN", "CAP_NET_RAW", "NET_RAW"},
+			want:  []int{unix.CAP_NET_RAW, unix.CAP_SYS_ADMIN},
+		},
+		{
+			name:    "unknown capability",
+			input:   []string{"NET_RAW", "FOO"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := capabilitiesToDrop(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCapabilitiesToDropAll(t *testing.T) {
+	got, err := capabilitiesToDrop([]string{"NET_RAW", "all"})
+	require.NoError(t, err)
+	assert.Len(t, got, unix.CAP_LAST_CAP+1)
+	assert.Equal(t, 0, got[0])
+	assert.Equal(t, unix.CAP_LAST_CAP, got[len(got)-1])
+}
+
+func TestHasEffectiveCapability(t *testing.T) {
+	status, err := os.ReadFile("/proc/thread-self/status")
+	require.NoError(t, err)
+
+	var effective uint64
+	for line := range strings.Lines(string(status)) {
+		if value, found := strings.CutPrefix(line, "CapEff:"); found {
+			effective, err = strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+			require.NoError(t, err)
+		}
+	}
+
+	for _, capability := range []int{unix.CAP_SETPCAP, unix.CAP_NET_RAW, unix.CAP_SYS_ADMIN} {
+		got, err := hasEffectiveCapability(capability)
+		require.NoError(t, err)
+		assert.Equal(t, effective&(1<<capability) != 0, got, "capability %d", capability)
+	}
+}
+
+func TestRlimitValue(t *testing.T) {
+	assert.Equal(t, uint64(1024), RlimitValue(1024))
+	assert.Equal(t, uint64(0), RlimitValue(0))
+	assert.Equal(t, uint64(unix.RLIM_INFINITY), RlimitValue(podlockv1alpha1.RlimitUnlimited))
+}
@@ -0,0 +1,65 @@
+package seal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+)
+
+// ErrDigestMismatch is returned when the digest of a binary does not match
+// the one defined inside of its profile.
+var ErrDigestMismatch = errors.New("binary digest mismatch")
+
+//nolint:gochecknoglobals // compiled once
+var sha256DigestRegexp = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+// IsValidSHA256Digest returns true when the given string is a lowercase hex
+// encoded sha256 digest.
+func IsValidSHA256Digest(digest string) bool {
+	return sha256DigestRegexp.MatchString(digest)
+}
+
+// OpenVerifiedBinary opens the binary at the given path and ensures its
+// sha256 digest matches the expected one.
+//
+// The returned file must be used to start the binary, see ExecPath. This
+// ensures the verified binary is the one being executed, even if the file at
+// the given path is replaced after the check.
+func OpenVerifiedBinary(path, expectedDigest string) (*os.File, error) {
+	if !IsValidSHA256Digest(expectedDigest) {
+		return nil, fmt.Errorf("invalid sha256 digest '%s'", expectedDigest)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open binary '%s': %w", path, err)
+	}
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("cannot compute digest of binary '%s': %w", path, err)
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+	if digest != expectedDigest {
+		file.Close()
+		return nil, fmt.Errorf("%w: '%s' has digest %s, expected %s", ErrDigestMismatch, path, digest, expectedDigest)
+	}
+
+	return file, nil
+}
+
+// ExecPath returns the path to pass to execve to start the binary referenced
+// by the given file. Like fexecve(3), it relies on the /proc/self/fd magic
+// links, which refer to the open file rather than to its original path.
+//
+// The file is opened with O_CLOEXEC, hence this works only with binaries and
+// not with scripts: the interpreter would not be able to open the script.
+func ExecPath(file *os.File) string {
+	return fmt.Sprintf("/proc/self/fd/%d", file.Fd())
+}
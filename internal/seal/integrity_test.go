@@ -0,0 +1,75 @@
+package seal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenVerifiedBinary(t *testing.T) {
+	content := []byte("#!/bin/true\n")
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+
+	path := filepath.Join(t.TempDir(), "binary")
+	require.NoError(t, os.WriteFile(path, content, 0o755))
+
+	tests := []struct {
+		name    string
+		path    string
+		digest  string
+		wantErr bool
+		errIs   error
+	}{
+		{
+			name:   "matching digest",
+			path:   path,
+			digest: digest,
+		},
+		{
+			name:    "digest mismatch",
+			path:    path,
+			digest:  "0000000000000000000000000000000000000000000000000000000000000000",
+			wantErr: true,
+			errIs:   ErrDigestMismatch,
+		},
+		{
+			name:    "invalid digest",
+			path:    path,
+			digest:  "ABCD",
+			wantErr: true,
+		},
+		{
+			name:    "missing binary",
+			path:    filepath.Join(t.TempDir(), "missing"),
+			digest:  digest,
+			wantErr: true,
+			errIs:   os.ErrNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := OpenVerifiedBinary(tt.path, tt.digest)
+			if tt.wantErr {
+				require.Error(t, err)
+				if tt.errIs != nil {
+					require.ErrorIs(t, err, tt.errIs)
+				}
+				return
+			}
+			require.NoError(t, err)
+			defer file.Close()
+
+			// The exec path must refer to the verified file
+			target, err := os.Readlink(ExecPath(file))
+			require.NoError(t, err)
+			assert.Equal(t, path, target)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -179,3 +180,312 @@ func detectFileType(path string) (string, error) {
 		return FileTypeUnknown, nil
 	}
 }
+
+// maxLdSoConfDepth is the maximum depth of the include directives of
+// /etc/ld.so.conf that are followed.
+const maxLdSoConfDepth = 8
+
+// dynamicObject holds the information the dynamic loader uses to load the
+// dependencies of an ELF file.
+type dynamicObject struct {
+	class       elf.Class
+	machine     elf.Machine
+	soname      string
+	interpreter string
+	needed      []string
+	rpath       []string
+	runpath     []string
+}
+
+// readDynamicObject reads the ELF file at the given path. Nil is returned when
+// the file is not an ELF file, e.g. a script.
+func (r *RootFS) readDynamicObject(path string) (*dynamicObject, error) {
+	f, err := r.openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(elf.ELFMAG))
+	if _, err = f.ReadAt(magic, 0); err != nil || string(magic) != elf.ELFMAG {
+		return nil, nil //nolint:nilnil // not being an ELF file is not an error
+	}
+
+	elfFile, err := elf.NewFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not read ELF file '%s': %w", path, err)
+	}
+
+	object := &dynamicObject{class: elfFile.Class, machine: elfFile.Machine}
+	if object.needed, err = elfFile.ImportedLibraries(); err != nil {
+		return nil, fmt.Errorf("could not get imported libraries of '%s': %w", path, err)
+	}
+	if soname, sonameErr := elfFile.DynString(elf.DT_SONAME); sonameErr == nil && len(soname) > 0 {
+		object.soname = soname[0]
+	}
+	for tag, dirs := range map[elf.DynTag]*[]string{elf.DT_RPATH: &object.rpath, elf.DT_RUNPATH: &object.runpath} {
+		values, dynErr := elfFile.DynString(tag)
+		if dynErr != nil {
+			return nil, fmt.Errorf("could not read the search paths of '%s': %w", path, dynErr)
+		}
+		for _, value := range values {
+			*dirs = append(*dirs, strings.Split(value, ":")...)
+		}
+	}
+
+	for _, prog := range elfFile.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+		data := make([]byte, prog.Filesz)
+		if _, err = prog.ReadAt(data, 0); err != nil {
+			return nil, fmt.Errorf("could not read the interpreter of '%s': %w", path, err)
+		}
+		object.interpreter = strings.TrimRight(string(data), "\x00")
+	}
+
+	return object, nil
+}
+
+// searchDirs returns the directories where the dependencies of the object are
+// looked up before the system ones, origin being the directory of the
+// object. Like the GNU dynamic loader, DT_RPATH is used only when the object
+// has no DT_RUNPATH, together with the DT_RPATH of the executable.
+func (o *dynamicObject) searchDirs(origin string, executable *dynamicObject) []string {
+	dirs := o.runpath
+	if len(dirs) == 0 {
+		dirs = o.rpath
+		if o != executable && len(executable.runpath) == 0 {
+			dirs = slices.Concat(dirs, executable.rpath)
+		}
+	}
+
+	expanded := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		dir = strings.ReplaceAll(dir, "${ORIGIN}", origin)
+		expanded = append(expanded, strings.ReplaceAll(dir, "$ORIGIN", origin))
+	}
+	return expanded
+}
+
+// systemLibraryDirs returns the directories where the dynamic loader of the
+// executable looks up the libraries by default.
+func (r *RootFS) systemLibraryDirs(executable *dynamicObject) []string {
+	interpreter := filepath.Base(executable.interpreter)
+	if arch, found := strings.CutPrefix(interpreter, "ld-musl-"); found {
+		// The musl loader reads the directories from a file named after it
+		data, err := r.readFile("/etc/ld-musl-" + strings.TrimSuffix(arch, ".so.1") + ".path")
+		if err == nil {
+			return strings.FieldsFunc(string(data), func(c rune) bool { return c == ':' || c == '\n' })
+		}
+		return []string{"/lib", "/usr/local/lib", "/usr/lib"}
+	}
+
+	// The GNU loader looks up the directories of /etc/ld.so.conf through
+	// /etc/ld.so.cache, which is generated from it
+	dirs := r.ldSoConfDirs("/etc/ld.so.conf", 0)
+	if executable.class == elf.ELFCLASS64 {
+		dirs = append(dirs, "/lib64", "/usr/lib64")
+	}
+	return append(dirs, "/lib", "/usr/lib")
+}
+
+// ldSoConfDirs returns the library directories listed by the ld.so.conf file
+// at the given path, following its include directives.
+func (r *RootFS) ldSoConfDirs(path string, depth int) []string {
+	if depth > maxLdSoConfDepth {
+		return nil
+	}
+	data, err := r.readFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var dirs []string
+	for line := range strings.Lines(string(data)) {
+		line, _, _ = strings.Cut(line, "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "include":
+			for _, pattern := range fields[1:] {
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(filepath.Dir(path), pattern)
+				}
+				for _, included := range r.glob(pattern) {
+					dirs = append(dirs, r.ldSoConfDirs(included, depth+1)...)
+				}
+			}
+		case "hwcap":
+		default:
+			for _, field := range fields {
+				dirs = append(dirs, strings.FieldsFunc(field, func(c rune) bool { return c == ':' || c == ',' })...)
+			}
+		}
+	}
+
+	return dirs
+}
+
+// glob returns the sorted paths matching the pattern, which can have
+// wildcards only inside of its last component.
+func (r *RootFS) glob(pattern string) []string {
+	dir, base := filepath.Split(pattern)
+	names, err := r.readDirNames(dir)
+	if err != nil {
+		return nil
+	}
+
+	var matches []string
+	for _, name := range names {
+		if matched, _ := filepath.Match(base, name); matched {
+			matches = append(matches, filepath.Join(dir, name))
+		}
+	}
+	slices.Sort(matches)
+	return matches
+}
+
+// findLibrary looks up the library with the given name inside of the
+// directories, skipping the ones built for a different architecture than the
+// executable. It returns the real path of the library.
+func (r *RootFS) findLibrary(name string, dirs []string, executable *dynamicObject) (string, *dynamicObject, error) {
+	candidates := []string{name}
+	if !strings.Contains(name, "/") {
+		candidates = candidates[:0]
+		for _, dir := range dirs {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+
+	for _, candidate := range candidates {
+		object, err := r.readDynamicObject(candidate)
+		if err != nil || object == nil || object.class != executable.class || object.machine != executable.machine {
+			continue
+		}
+		path, err := r.realPath(candidate)
+		if err != nil {
+			return "", nil, err
+		}
+		return path, object, nil
+	}
+
+	return "", nil, fmt.Errorf("library '%s' not found", name)
+}
+
+// ResolveLinkedLibraries returns the real paths of the shared libraries loaded
+// together with the binary: its dynamic loader and the libraries named by the
+// DT_NEEDED entries, recursively. The paths are resolved inside of root.
+//
+// Unlike DiscoverLinkedLibraries, the dynamic loader is not run: the libraries
+// are looked up like it does, inside of the DT_RPATH and DT_RUNPATH
+// directories, the ones of /etc/ld.so.conf and the default ones. This makes it
+// safe to resolve the libraries of an untrusted root filesystem.
+// Nil is returned when the binary is not a dynamically linked ELF file.
+func ResolveLinkedLibraries(root *RootFS, binaryPath string) ([]string, error) {
+	executable, err := root.readDynamicObject(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	if executable == nil || (len(executable.needed) == 0 && executable.interpreter == "") {
+		return nil, nil
+	}
+
+	binaryRealPath, err := root.realPath(binaryPath)
+	if err != nil {
+		return nil, err
+	}
+	systemDirs := root.systemLibraryDirs(executable)
+
+	var libs []string
+	loaded := map[string]bool{}
+	if executable.interpreter != "" {
+		interpreter, realPathErr := root.realPath(executable.interpreter)
+		if realPathErr != nil {
+			return nil, fmt.Errorf("could not resolve the interpreter of binary '%s': %w", binaryPath, realPathErr)
+		}
+		libs = append(libs, interpreter)
+		loaded[filepath.Base(executable.interpreter)] = true
+		if object, readErr := root.readDynamicObject(interpreter); readErr == nil && object != nil && object.soname != "" {
+			loaded[object.soname] = true
+		}
+	}
+
+	type dependent struct {
+		object *dynamicObject
+		origin string
+	}
+	queue := []dependent{{object: executable, origin: filepath.Dir(binaryRealPath)}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		dirs := slices.Concat(current.object.searchDirs(current.origin, executable), systemDirs)
+		for _, name := range current.object.needed {
+			if loaded[name] {
+				continue
+			}
+			loaded[name] = true
+
+			path, object, findErr := root.findLibrary(name, dirs, executable)
+			if findErr != nil {
+				return nil, fmt.Errorf("could not resolve the libraries of binary '%s': %w", binaryPath, findErr)
+			}
+			if object.soname != "" {
+				loaded[object.soname] = true
+			}
+			if slices.Contains(libs, path) {
+				continue
+			}
+			libs = append(libs, path)
+			queue = append(queue, dependent{object: object, origin: filepath.Dir(path)})
+		}
+	}
+
+	return libs, nil
+}
+
+// VerifyLinkedLibraries ensures the given libraries are loaded together with
+// the binary, as resolved by ResolveLinkedLibraries. The real paths of the
+// libraries are compared, so that another file cannot pass for a dependency
+// by having the same name.
+func VerifyLinkedLibraries(binaryPath string, libs []string) error {
+	if len(libs) == 0 {
+		return nil
+	}
+
+	root, err := OpenRootFS("/")
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	absBinaryPath, err := filepath.Abs(binaryPath)
+	if err != nil {
+		return fmt.Errorf("could not resolve binary '%s': %w", binaryPath, err)
+	}
+	resolved, err := ResolveLinkedLibraries(root, absBinaryPath)
+	if err != nil {
+		return err
+	}
+
+	var unexpected []string
+	for _, lib := range libs {
+		absLib, absErr := filepath.Abs(lib)
+		if absErr != nil {
+			return fmt.Errorf("could not resolve library '%s': %w", lib, absErr)
+		}
+		if path, realPathErr := root.realPath(absLib); realPathErr != nil || !slices.Contains(resolved, path) {
+			unexpected = append(unexpected, lib)
+		}
+	}
+
+	if len(unexpected) > 0 {
+		return fmt.Errorf("libraries %v are not dependencies of binary '%s'", unexpected, binaryPath)
+	}
+
+	return nil
+}
@@ -106,6 +106,75 @@ func TestDiscoverLinkedLibrariesDynamicallyLinkedBin(t *testing.T) {
 	assert.NotEmpty(t, libs, "expected to find linked libraries for 'cp' binary")
 }
 
+func TestVerifyLinkedLibraries(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	binaryPath, err := exec.LookPath("cp")
+	require.NoError(t, err)
+	otherBinary, err := exec.LookPath("ls")
+	require.NoError(t, err)
+
+	libs, err := DiscoverLinkedLibraries(context.Background(), binaryPath, logger)
+	require.NoError(t, err)
+	require.NotEmpty(t, libs)
+
+	// The discovered libraries are accepted, regardless of their order
+	require.NoError(t, VerifyLinkedLibraries(binaryPath, libs))
+	reversed := slices.Clone(libs)
+	slices.Reverse(reversed)
+	require.NoError(t, VerifyLinkedLibraries(binaryPath, reversed))
+	require.NoError(t, VerifyLinkedLibraries(binaryPath, nil))
+
+	// Files that are not shared objects, or that the binary does not depend
+	// on, are rejected
+	require.Error(t, VerifyLinkedLibraries(binaryPath, append(slices.Clone(libs), "../../README.md")))
+	require.Error(t, VerifyLinkedLibraries(binaryPath, append(slices.Clone(libs), otherBinary)))
+	require.Error(t, VerifyLinkedLibraries("../../README.md", libs))
+
+	// The real paths are compared: a symlink to a dependency is accepted,
+	// while a copy of it, having the same DT_SONAME, is rejected
+	tmpDir := t.TempDir()
+	link := filepath.Join(tmpDir, "link.so")
+	require.NoError(t, os.Symlink(libs[len(libs)-1], link))
+	require.NoError(t, VerifyLinkedLibraries(binaryPath, []string{link}))
+
+	data, err := os.ReadFile(libs[len(libs)-1])
+	require.NoError(t, err)
+	impostor := filepath.Join(tmpDir, filepath.Base(libs[len(libs)-1]))
+	require.NoError(t, os.WriteFile(impostor, data, 0o600))
+	require.Error(t, VerifyLinkedLibraries(binaryPath, []string{impostor}))
+}
+
+func TestResolveLinkedLibraries(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	binaryPath, err := exec.LookPath("cp")
+	require.NoError(t, err)
+
+	root, err := OpenRootFS("/")
+	require.NoError(t, err)
+	defer root.Close()
+
+	// The libraries are the ones loaded by the dynamic loader
+	discovered, err := DiscoverLinkedLibraries(context.Background(), binaryPath, logger)
+	require.NoError(t, err)
+	want := make([]string, 0, len(discovered))
+	for _, lib := range discovered {
+		realPath, err := filepath.EvalSymlinks(lib)
+		require.NoError(t, err)
+		want = append(want, realPath)
+	}
+
+	libs, err := ResolveLinkedLibraries(root, binaryPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, want, libs)
+
+	// Files that are not ELF binaries have no linked libraries
+	readme, err := filepath.Abs("../../README.md")
+	require.NoError(t, err)
+	libs, err = ResolveLinkedLibraries(root, readme)
+	require.NoError(t, err)
+	assert.Empty(t, libs)
+}
+
 func TestDiscoverLinkedLibrariesStaticallyLinkedBin(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	binaryPath := "../../bin/seal"
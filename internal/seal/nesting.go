@@ -0,0 +1,157 @@
+package seal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+// MaxLandlockDomains is the maximum number of Landlock domains the kernel
+// allows to stack on top of each other.
+const MaxLandlockDomains = 16
+
+// NestingPolicy defines what seal does when it is started by a process that
+// is already running inside of a PodLock domain.
+type NestingPolicy string
+
+const (
+	// NestingPolicyStack always applies a new Landlock domain on top of the
+	// existing one. This is the default behavior.
+	NestingPolicyStack NestingPolicy = "stack"
+
+	// NestingPolicyFail refuses to run the binary when seal is already running
+	// inside of a PodLock domain.
+	NestingPolicyFail NestingPolicy = "fail"
+)
+
+// ParseNestingPolicy converts the given string into a NestingPolicy.
+// An empty string results in the default policy.
+func ParseNestingPolicy(s string) (NestingPolicy, error) {
+	switch policy := NestingPolicy(s); policy {
+	case NestingPolicyStack, NestingPolicyFail:
+		return policy, nil
+	case "":
+		return NestingPolicyStack, nil
+	default:
+		return "", fmt.Errorf("invalid nesting policy: %s", s)
+	}
+}
+
+var (
+	// ErrNestingRefused is returned when the nesting policy does not allow
+	// seal to run inside of an existing PodLock domain.
+	ErrNestingRefused = errors.New("already running inside of a PodLock domain")
+
+	// ErrTooManyDomains is returned when stacking a new Landlock domain would
+	// exceed the limit imposed by the kernel.
+	ErrTooManyDomains = errors.New("maximum number of nested Landlock domains reached")
+)
+
+// Domain describes the PodLock domain a process is running inside of.
+//
+// The information is propagated to the sealed process through the
+// DomainEnvVar environment variable, since all the variables starting with
+// SealEnvVarPrefix are removed before the target binary is started.
+type Domain struct {
+	// Depth is the number of Landlock domains applied by seal.
+	Depth int
+	// Policy is the nesting policy in effect when the domain was created.
+	Policy NestingPolicy
+	// Fingerprint identifies the profile used to create the domain.
+	Fingerprint string
+}
+
+// String returns the value of the DomainEnvVar environment variable
+// describing the domain, in the form "<depth>:<policy>:<fingerprint>".
+func (d *Domain) String() string {
+	return fmt.Sprintf("%d:%s:%s", d.Depth, d.Policy, d.Fingerprint)
+}
+
+// ParseDomain parses the value of the DomainEnvVar environment variable.
+// It returns nil when the value is empty, meaning the process is not running
+// inside of a PodLock domain.
+func ParseDomain(value string) (*Domain, error) {
+	if value == "" {
+		return nil, nil //nolint:nilnil // no domain is not an error
+	}
+
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid PodLock domain marker '%s'", value)
+	}
+
+	depth, err := strconv.Atoi(parts[0])
+	if err != nil || depth < 1 {
+		return nil, fmt.Errorf("invalid depth in PodLock domain marker '%s'", value)
+	}
+
+	policy, err := ParseNestingPolicy(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid PodLock domain marker '%s': %w", value, err)
+	}
+
+	return &Domain{
+		Depth:       depth,
+		Policy:      policy,
+		Fingerprint: parts[2],
+	}, nil
+}
+
+// ProfileFingerprint returns a digest identifying the profile applied to
+// the given binary.
+func ProfileFingerprint(profile *podlockv1alpha1.Profile, binaryToRun string, addLinkedLibraries bool) (string, error) {
+	data, err := json.Marshal(struct {
+		Profile            *podlockv1alpha1.Profile `json:"profile"`
+		BinaryToRun        string                   `json:"binaryToRun"`
+		AddLinkedLibraries bool                     `json:"addLinkedLibraries"`
+	}{
+		Profile:            profile,
+		BinaryToRun:        binaryToRun,
+		AddLinkedLibraries: addLinkedLibraries,
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot compute profile fingerprint: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// DecideNesting evaluates the nesting policy against the domain seal is
+// currently running inside of. The current domain is nil when seal is not
+// running inside of a PodLock domain.
+//
+// The current domain is read from the environment, hence it is only a hint:
+// it never prevents a new Landlock domain from being applied. The policy
+// must come from the configuration of seal, never from the domain marker.
+//
+// It returns the domain the sealed process will be running inside of.
+func DecideNesting(current *Domain, fingerprint string, policy NestingPolicy) (*Domain, error) {
+	if current == nil {
+		return &Domain{
+			Depth:       1,
+			Policy:      policy,
+			Fingerprint: fingerprint,
+		}, nil
+	}
+
+	if policy == NestingPolicyFail {
+		return nil, ErrNestingRefused
+	}
+
+	if current.Depth >= MaxLandlockDomains {
+		return nil, ErrTooManyDomains
+	}
+
+	return &Domain{
+		Depth:       current.Depth + 1,
+		Policy:      policy,
+		Fingerprint: fingerprint,
+	}, nil
+}
@@ -0,0 +1,172 @@
+package seal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+func TestParseDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    *Domain
+		wantErr bool
+	}{
+		{
+			name:  "empty value",
+			value: "",
+			want:  nil,
+		},
+		{
+			name:  "valid marker",
+			value: "2:fail:abcd",
+			want: &Domain{
+				Depth:       2,
+				Policy:      NestingPolicyFail,
+				Fingerprint: "abcd",
+			},
+		},
+		{
+			name:    "missing fields",
+			value:   "1:abcd",
+			wantErr: true,
+		},
+		{
+			name:    "invalid depth",
+			value:   "zero:stack:abcd",
+			wantErr: true,
+		},
+		{
+			name:    "negative depth",
+			value:   "-1:stack:abcd",
+			wantErr: true,
+		},
+		{
+			name:    "invalid policy",
+			value:   "1:ignore:abcd",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDomain(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDomainStringRoundTrip(t *testing.T) {
+	domain := &Domain{
+		Depth:       3,
+		Policy:      NestingPolicyFail,
+		Fingerprint: "deadbeef",
+	}
+
+	parsed, err := ParseDomain(domain.String())
+	require.NoError(t, err)
+	assert.Equal(t, domain, parsed)
+}
+
+func TestProfileFingerprint(t *testing.T) {
+	profile := &podlockv1alpha1.Profile{
+		ReadOnly: []string{"/etc"},
+	}
+	otherProfile := &podlockv1alpha1.Profile{
+		ReadWrite: []string{"/etc"},
+	}
+
+	fingerprint, err := ProfileFingerprint(profile, "/bin/ls", false)
+	require.NoError(t, err)
+
+	sameFingerprint, err := ProfileFingerprint(profile, "/bin/ls", false)
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, sameFingerprint)
+
+	otherBinary, err := ProfileFingerprint(profile, "/bin/cat", false)
+	require.NoError(t, err)
+	assert.NotEqual(t, fingerprint, otherBinary)
+
+	otherLdd, err := ProfileFingerprint(profile, "/bin/ls", true)
+	require.NoError(t, err)
+	assert.NotEqual(t, fingerprint, otherLdd)
+
+	otherRules, err := ProfileFingerprint(otherProfile, "/bin/ls", false)
+	require.NoError(t, err)
+	assert.NotEqual(t, fingerprint, otherRules)
+}
+
+func TestDecideNesting(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     *Domain
+		fingerprint string
+		policy      NestingPolicy
+		wantDomain  *Domain
+		wantErr     error
+	}{
+		{
+			name:        "not inside a domain",
+			current:     nil,
+			fingerprint: "aaa",
+			policy:      NestingPolicyFail,
+			wantDomain:  &Domain{Depth: 1, Policy: NestingPolicyFail, Fingerprint: "aaa"},
+		},
+		{
+			name:        "stack identical profile",
+			current:     &Domain{Depth: 1, Policy: NestingPolicyStack, Fingerprint: "aaa"},
+			fingerprint: "aaa",
+			policy:      NestingPolicyStack,
+			wantDomain:  &Domain{Depth: 2, Policy: NestingPolicyStack, Fingerprint: "aaa"},
+		},
+		{
+			name:        "stack different profile",
+			current:     &Domain{Depth: 1, Policy: NestingPolicyStack, Fingerprint: "aaa"},
+			fingerprint: "bbb",
+			policy:      NestingPolicyStack,
+			wantDomain:  &Domain{Depth: 2, Policy: NestingPolicyStack, Fingerprint: "bbb"},
+		},
+		{
+			name:        "fail when nested",
+			current:     &Domain{Depth: 1, Policy: NestingPolicyFail, Fingerprint: "aaa"},
+			fingerprint: "aaa",
+			policy:      NestingPolicyFail,
+			wantErr:     ErrNestingRefused,
+		},
+		{
+			name:        "too many domains",
+			current:     &Domain{Depth: MaxLandlockDomains, Policy: NestingPolicyStack, Fingerprint: "aaa"},
+			fingerprint: "bbb",
+			policy:      NestingPolicyStack,
+			wantErr:     ErrTooManyDomains,
+		},
+		{
+			name:        "identical profile at maximum depth",
+			current:     &Domain{Depth: MaxLandlockDomains, Policy: NestingPolicyStack, Fingerprint: "aaa"},
+			fingerprint: "aaa",
+			policy:      NestingPolicyStack,
+			wantErr:     ErrTooManyDomains,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain, err := DecideNesting(tt.current, tt.fingerprint, tt.policy)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantDomain, domain)
+		})
+	}
+}
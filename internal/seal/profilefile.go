@@ -0,0 +1,177 @@
+package seal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+const (
+	// ProfileFileGroup is the group of the apiVersion of the profile files.
+	ProfileFileGroup = "profile.podlock.kubewarden.io"
+
+	// ProfileFileMajorVersion is the major version of the profile files
+	// supported by this version of seal. The major version is bumped only by
+	// changes that cannot be understood by older seal binaries.
+	ProfileFileMajorVersion = 1
+
+	// ProfileFileMinorVersion is the minor version of the profile files
+	// written by this version of PodLock. The minor version is bumped when
+	// new optional fields are added.
+	ProfileFileMinorVersion = 1
+)
+
+// ErrUnsupportedProfileFileVersion is returned when the profile file has been
+// written using a major version not supported by seal.
+var ErrUnsupportedProfileFileVersion = errors.New("unsupported profile file version")
+
+// ProfileFileAPIVersion returns the apiVersion of the profile files written by
+// this version of PodLock.
+func ProfileFileAPIVersion() string {
+	return fmt.Sprintf("%s/v%d.%d", ProfileFileGroup, ProfileFileMajorVersion, ProfileFileMinorVersion)
+}
+
+// ProfileFile is the file written by the NRI plugin for each container and
+// read by seal. Its JSON schema is published as part of the documentation.
+type ProfileFile struct {
+	// APIVersion is the version of the format of the file, in the form
+	// "<group>/v<major>.<minor>".
+	APIVersion string `json:"apiVersion"`
+	// Generation is the generation of the LandlockProfile the file has been
+	// created from.
+	Generation int64 `json:"generation"`
+	// Revision is the number of the LandlockProfileRevision the file has been
+	// created from, when the pod pins a revision of the LandlockProfile.
+	// Added by v1.1.
+	Revision int64 `json:"revision,omitempty"`
+	// Source identifies the LandlockProfile the file has been created from.
+	Source ProfileFileSource `json:"source"`
+	// Profiles are the profiles of the binaries of the container.
+	Profiles podlockv1alpha1.ProfileByBinary `json:"profiles"`
+}
+
+// ProfileFileSource identifies the LandlockProfile a profile file has been
+// created from.
+type ProfileFileSource struct {
+	Namespace       string `json:"namespace"`
+	Name            string `json:"name"`
+	UID             string `json:"uid"`
+	ResourceVersion string `json:"resourceVersion"`
+	Container       string `json:"container"`
+}
+
+// NewProfileFile returns the profile file for the given container.
+func NewProfileFile(profile *podlockv1alpha1.LandlockProfile, containerName string) *ProfileFile {
+	return &ProfileFile{
+		APIVersion: ProfileFileAPIVersion(),
+		Generation: profile.GetGeneration(),
+		Source: ProfileFileSource{
+			Namespace:       profile.GetNamespace(),
+			Name:            profile.GetName(),
+			UID:             string(profile.GetUID()),
+			ResourceVersion: profile.GetResourceVersion(),
+			Container:       containerName,
+		},
+		Profiles: profile.Spec.ProfilesByContainer[containerName],
+	}
+}
+
+// NewProfileFileFromRevision returns the profile file for the given container,
+// created from a revision of the LandlockProfile.
+func NewProfileFileFromRevision(
+	profile *podlockv1alpha1.LandlockProfile,
+	revision *podlockv1alpha1.LandlockProfileRevision,
+	containerName string,
+) *ProfileFile {
+	profileFile := NewProfileFile(profile, containerName)
+	profileFile.Revision = revision.Revision
+	profileFile.Profiles = revision.ProfilesByContainer[containerName]
+	return profileFile
+}
+
+// ParseProfileFile decodes a profile file. Unknown fields are rejected: they
+// are written by newer versions of PodLock and might restrict the binaries
+// further, ignoring them would weaken the sandbox.
+//
+// Files written by older versions of PodLock, which contain just the profiles
+// of the binaries, are supported too. They are recognized by the lack of
+// the apiVersion field, which cannot clash with the absolute path of a binary.
+func ParseProfileFile(r io.Reader) (*ProfileFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The version is checked first, the layout of the rest of the file
+	// depends on it
+	var header struct {
+		APIVersion string `json:"apiVersion"`
+	}
+	if err = json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	if header.APIVersion == "" {
+		profiles := podlockv1alpha1.ProfileByBinary{}
+		if err = decodeStrict(data, &profiles); err != nil {
+			return nil, err
+		}
+		return &ProfileFile{Profiles: profiles}, nil
+	}
+
+	major, err := parseProfileFileAPIVersion(header.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	if major != ProfileFileMajorVersion {
+		return nil, fmt.Errorf(
+			"%w '%s': this seal binary supports only %s/v%d.x, it might be older than the PodLock NRI plugin",
+			ErrUnsupportedProfileFileVersion, header.APIVersion, ProfileFileGroup, ProfileFileMajorVersion)
+	}
+
+	profileFile := &ProfileFile{}
+	if err = decodeStrict(data, profileFile); err != nil {
+		return nil, fmt.Errorf("%w '%s': %w, this seal binary might be older than the PodLock NRI plugin",
+			ErrUnsupportedProfileFileVersion, header.APIVersion, err)
+	}
+
+	return profileFile, nil
+}
+
+// decodeStrict decodes the JSON data into v, failing on unknown fields.
+func decodeStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// parseProfileFileAPIVersion returns the major version of the given apiVersion.
+func parseProfileFileAPIVersion(apiVersion string) (int, error) {
+	invalid := fmt.Errorf("%w: invalid apiVersion '%s'", ErrUnsupportedProfileFileVersion, apiVersion)
+
+	group, version, found := strings.Cut(apiVersion, "/")
+	if !found || group != ProfileFileGroup {
+		return 0, invalid
+	}
+
+	majorStr, minorStr, found := strings.Cut(strings.TrimPrefix(version, "v"), ".")
+	if !found || !strings.HasPrefix(version, "v") {
+		return 0, invalid
+	}
+
+	major, err := strconv.Atoi(majorStr)
+	if err != nil || major < 0 {
+		return 0, invalid
+	}
+	if minor, err := strconv.Atoi(minorStr); err != nil || minor < 0 {
+		return 0, invalid
+	}
+
+	return major, nil
+}
@@ -0,0 +1,172 @@
+package seal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+func TestNewProfileFile(t *testing.T) {
+	profile := &podlockv1alpha1.LandlockProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "default",
+			Name:            "nginx",
+			UID:             "1234",
+			ResourceVersion: "42",
+			Generation:      3,
+		},
+		Spec: podlockv1alpha1.LandlockProfileSpec{
+			ProfilesByContainer: map[string]podlockv1alpha1.ProfileByBinary{
+				"nginx": {
+					"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, &ProfileFile{
+		APIVersion: "profile.podlock.kubewarden.io/v1.1",
+		Generation: 3,
+		Source: ProfileFileSource{
+			Namespace:       "default",
+			Name:            "nginx",
+			UID:             "1234",
+			ResourceVersion: "42",
+			Container:       "nginx",
+		},
+		Profiles: podlockv1alpha1.ProfileByBinary{
+			"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}},
+		},
+	}, NewProfileFile(profile, "nginx"))
+}
+
+func TestNewProfileFileFromRevision(t *testing.T) {
+	profile := &podlockv1alpha1.LandlockProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "default",
+			Name:       "nginx",
+			UID:        "1234",
+			Generation: 3,
+		},
+		Spec: podlockv1alpha1.LandlockProfileSpec{
+			ProfilesByContainer: map[string]podlockv1alpha1.ProfileByBinary{
+				"nginx": {
+					"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx", "/var/www"}},
+				},
+			},
+		},
+	}
+	revision := &podlockv1alpha1.LandlockProfileRevision{
+		Revision: 2,
+		ProfilesByContainer: map[string]podlockv1alpha1.ProfileByBinary{
+			"nginx": {
+				"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}},
+			},
+		},
+	}
+
+	profileFile := NewProfileFileFromRevision(profile, revision, "nginx")
+	assert.Equal(t, int64(3), profileFile.Generation)
+	assert.Equal(t, int64(2), profileFile.Revision)
+	assert.Equal(t, "nginx", profileFile.Source.Name)
+	assert.Equal(t, podlockv1alpha1.ProfileByBinary{
+		"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}},
+	}, profileFile.Profiles)
+}
+
+func TestParseProfileFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *ProfileFile
+		wantErr error
+	}{
+		{
+			name: "current version",
+			data: `{
+				"apiVersion": "profile.podlock.kubewarden.io/v1.0",
+				"generation": 2,
+				"source": {"namespace": "default", "name": "nginx", "container": "nginx"},
+				"profiles": {"/usr/sbin/nginx": {"readOnly": ["/etc/nginx"]}}
+			}`,
+			want: &ProfileFile{
+				APIVersion: "profile.podlock.kubewarden.io/v1.0",
+				Generation: 2,
+				Source:     ProfileFileSource{Namespace: "default", Name: "nginx", Container: "nginx"},
+				Profiles: podlockv1alpha1.ProfileByBinary{
+					"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}},
+				},
+			},
+		},
+		{
+			name: "newer minor version without new fields",
+			data: `{
+				"apiVersion": "profile.podlock.kubewarden.io/v1.5",
+				"generation": 1,
+				"profiles": {"/usr/sbin/nginx": {"readOnly": ["/etc/nginx"]}}
+			}`,
+			want: &ProfileFile{
+				APIVersion: "profile.podlock.kubewarden.io/v1.5",
+				Generation: 1,
+				Profiles: podlockv1alpha1.ProfileByBinary{
+					"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}},
+				},
+			},
+		},
+		{
+			name: "newer minor version with unknown fields",
+			data: `{
+				"apiVersion": "profile.podlock.kubewarden.io/v1.5",
+				"generation": 1,
+				"profiles": {"/usr/sbin/nginx": {"readOnly": ["/etc/nginx"], "newField": true}}
+			}`,
+			wantErr: ErrUnsupportedProfileFileVersion,
+		},
+		{
+			name:    "unknown envelope field",
+			data:    `{"apiVersion": "profile.podlock.kubewarden.io/v1.1", "seccomp": {}, "profiles": {}}`,
+			wantErr: ErrUnsupportedProfileFileVersion,
+		},
+		{
+			name: "legacy file without envelope",
+			data: `{"/usr/sbin/nginx": {"readOnly": ["/etc/nginx"]}}`,
+			want: &ProfileFile{
+				Profiles: podlockv1alpha1.ProfileByBinary{
+					"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}},
+				},
+			},
+		},
+		{
+			name:    "unknown major version",
+			data:    `{"apiVersion": "profile.podlock.kubewarden.io/v2.0", "profiles": ["/usr/sbin/nginx"]}`,
+			wantErr: ErrUnsupportedProfileFileVersion,
+		},
+		{
+			name:    "unknown group",
+			data:    `{"apiVersion": "example.com/v1.0", "profiles": {}}`,
+			wantErr: ErrUnsupportedProfileFileVersion,
+		},
+		{
+			name:    "malformed version",
+			data:    `{"apiVersion": "profile.podlock.kubewarden.io/v1", "profiles": {}}`,
+			wantErr: ErrUnsupportedProfileFileVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProfileFile(strings.NewReader(tt.data))
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package seal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxSymlinks is the maximum number of symlinks followed while resolving a
+// path, like the kernel does.
+const maxSymlinks = 40
+
+// maxConfigFileSize is the maximum size of the configuration files read from
+// a root filesystem, e.g. /etc/ld.so.conf.
+const maxConfigFileSize = 1 << 20
+
+// RootFS resolves paths inside of a root directory, like the processes using
+// it as root directory do: absolute symlinks and ".." never lead outside of
+// it. It allows the PodLock NRI plugin to read the root filesystem of a
+// container through the procfs of the host.
+type RootFS struct {
+	fd int
+}
+
+// OpenRootFS opens the given directory as root filesystem.
+func OpenRootFS(dir string) (*RootFS, error) {
+	fd, err := unix.Open(dir, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open root filesystem '%s': %w", dir, err)
+	}
+
+	return &RootFS{fd: fd}, nil
+}
+
+// Close releases the root directory.
+func (r *RootFS) Close() error {
+	return unix.Close(r.fd)
+}
+
+func (r *RootFS) openat(path string, flags uint64) (int, error) {
+	for {
+		fd, err := unix.Openat2(r.fd, path, &unix.OpenHow{
+			Flags:   flags | unix.O_CLOEXEC,
+			Resolve: unix.RESOLVE_IN_ROOT | unix.RESOLVE_NO_MAGICLINKS,
+		})
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+			// The resolution raced with a rename inside of the root
+			continue
+		}
+		if err != nil {
+			return -1, &os.PathError{Op: "openat2", Path: path, Err: err}
+		}
+		return fd, nil
+	}
+}
+
+// stat returns the status of the file at the given path, following symlinks.
+func (r *RootFS) stat(path string) (unix.Stat_t, error) {
+	var stat unix.Stat_t
+
+	fd, err := r.openat(path, unix.O_PATH)
+	if err != nil {
+		return stat, err
+	}
+	defer unix.Close(fd)
+
+	if err = unix.Fstat(fd, &stat); err != nil {
+		return stat, &os.PathError{Op: "fstat", Path: path, Err: err}
+	}
+	return stat, nil
+}
+
+// openFile opens the regular file at the given path for reading. The file is
+// opened without blocking, so that a FIFO planted inside of the root
+// filesystem is rejected instead of blocking the caller.
+func (r *RootFS) openFile(path string) (*os.File, error) {
+	fd, err := r.openat(path, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_NOCTTY)
+	if err != nil {
+		return nil, err
+	}
+
+	var stat unix.Stat_t
+	if err = unix.Fstat(fd, &stat); err != nil {
+		unix.Close(fd)
+		return nil, &os.PathError{Op: "fstat", Path: path, Err: err}
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFREG {
+		unix.Close(fd)
+		return nil, fmt.Errorf("'%s' is not a regular file", path)
+	}
+
+	return os.NewFile(uintptr(fd), path), nil
+}
+
+// readFile returns the content of the regular file at the given path.
+func (r *RootFS) readFile(path string) ([]byte, error) {
+	f, err := r.openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxConfigFileSize))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read '%s': %w", path, err)
+	}
+	return data, nil
+}
+
+// readDirNames returns the names of the entries of the directory at the given
+// path.
+func (r *RootFS) readDirNames(path string) ([]string, error) {
+	fd, err := r.openat(path, unix.O_RDONLY|unix.O_DIRECTORY)
+	if err != nil {
+		return nil, err
+	}
+	dir := os.NewFile(uintptr(fd), path)
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read directory '%s': %w", path, err)
+	}
+	return names, nil
+}
+
+// realPath returns the absolute path of the file at the given path once all
+// the symlinks are resolved, like filepath.EvalSymlinks does. Relative paths
+// are resolved from the root directory.
+func (r *RootFS) realPath(path string) (string, error) {
+	resolved := "/"
+	pending := strings.Split(path, "/")
+	links := 0
+
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, name)
+		target, isLink, err := r.readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if !isLink {
+			resolved = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", &os.PathError{Op: "realpath", Path: path, Err: unix.ELOOP}
+		}
+		if filepath.IsAbs(target) {
+			resolved = "/"
+		}
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+
+	return resolved, nil
+}
+
+// readlink returns the target of the symlink at the given path. The path must
+// not contain symlinks but its last component.
+func (r *RootFS) readlink(path string) (string, bool, error) {
+	fd, err := r.openat(path, unix.O_PATH|unix.O_NOFOLLOW)
+	if err != nil {
+		return "", false, err
+	}
+	defer unix.Close(fd)
+
+	var stat unix.Stat_t
+	if err = unix.Fstat(fd, &stat); err != nil {
+		return "", false, &os.PathError{Op: "fstat", Path: path, Err: err}
+	}
+	if stat.Mode&unix.S_IFMT != unix.S_IFLNK {
+		return "", false, nil
+	}
+
+	buf := make([]byte, unix.PathMax)
+	n, err := unix.Readlinkat(fd, "", buf)
+	if err != nil {
+		return "", false, &os.PathError{Op: "readlink", Path: path, Err: err}
+	}
+	return string(buf[:n]), true, nil
+}
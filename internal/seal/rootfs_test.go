@@ -0,0 +1,76 @@
+package seal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func newTestRootFS(t *testing.T) *RootFS {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "usr", "lib"), 0o750))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "etc"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "usr", "lib", "libc.so"), []byte("libc"), 0o600))
+	require.NoError(t, os.Symlink("usr/lib", filepath.Join(dir, "lib")))
+	require.NoError(t, os.Symlink("/lib/libc.so", filepath.Join(dir, "usr", "lib", "libc.so.6")))
+	require.NoError(t, os.Symlink("../../../../../../etc", filepath.Join(dir, "escape")))
+	require.NoError(t, os.Symlink("loop", filepath.Join(dir, "loop")))
+	require.NoError(t, unix.Mkfifo(filepath.Join(dir, "fifo"), 0o600))
+
+	root, err := OpenRootFS(dir)
+	require.NoError(t, err)
+	t.Cleanup(func() { root.Close() })
+
+	return root
+}
+
+func TestRootFSRealPath(t *testing.T) {
+	root := newTestRootFS(t)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/usr/lib/libc.so", want: "/usr/lib/libc.so"},
+		{path: "/lib/libc.so.6", want: "/usr/lib/libc.so"},
+		{path: "/lib/../lib/./libc.so.6", want: "/usr/lib/libc.so"},
+		{path: "lib/libc.so", want: "/usr/lib/libc.so"},
+		// Symlinks never lead outside of the root
+		{path: "/escape", want: "/etc"},
+		{path: "/../../lib", want: "/usr/lib"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := root.realPath(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := root.realPath("/loop")
+	require.ErrorIs(t, err, unix.ELOOP)
+}
+
+func TestRootFSOpenFile(t *testing.T) {
+	root := newTestRootFS(t)
+
+	data, err := root.readFile("/lib/libc.so.6")
+	require.NoError(t, err)
+	assert.Equal(t, "libc", string(data))
+
+	// The host /etc/passwd is never reached
+	_, err = root.readFile("/escape/passwd")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// Opening a FIFO does not block
+	_, err = root.openFile("/fifo")
+	require.ErrorContains(t, err, "not a regular file")
+	_, err = root.openFile("/usr")
+	require.ErrorContains(t, err, "not a regular file")
+}
@@ -11,6 +11,10 @@ import (
 	ll "github.com/landlock-lsm/go-landlock/landlock/syscall"
 )
 
+// LandlockABI is the version of the Landlock ABI used by seal to enforce the
+// profiles. Seal refuses to run the binary when the kernel does not support it.
+const LandlockABI = 3
+
 const (
 	accessFileR   landlock.AccessFSSet = ll.AccessFSReadFile
 	accessFileRX  landlock.AccessFSSet = ll.AccessFSExecute | ll.AccessFSReadFile
@@ -23,6 +27,22 @@ const (
 	accessDirRWX landlock.AccessFSSet = accessDirRX | accessDirRW
 )
 
+// MinimumLandlockABI returns the minimum version of the Landlock ABI the
+// kernel of the node must support to enforce the given LandlockProfile.
+// Zero is returned when the LandlockProfile does not restrict any binary.
+func MinimumLandlockABI(profile *podlockv1alpha1.LandlockProfile) int {
+	for _, profileByBinary := range profile.Spec.ProfilesByContainer {
+		if len(profileByBinary) > 0 {
+			// The rulesets are always built using all the access rights
+			// of LandlockABI, the write access rights need Refer (ABI 2)
+			// and Truncate (ABI 3)
+			return LandlockABI
+		}
+	}
+
+	return 0
+}
+
 func ProfileToLandlockRules(profile *podlockv1alpha1.Profile, logger *slog.Logger) []landlock.Rule {
 	var rules []landlock.Rule
 
@@ -70,7 +90,13 @@ func processPaths(
 	fileAccessMode landlock.AccessFSSet,
 	logger *slog.Logger,
 ) []landlock.Rule {
-	var rules []landlock.Rule
+	files, dirs := classifyPaths(paths, logger)
+	return pathRules(files, dirs, dirAccessMode, fileAccessMode)
+}
+
+// classifyPaths splits the given paths into files and directories. Paths that
+// cannot be stat'ed are skipped.
+func classifyPaths(paths []string, logger *slog.Logger) ([]string, []string) {
 	var files []string
 	var dirs []string
 
@@ -87,6 +113,18 @@ func processPaths(
 		}
 	}
 
+	return files, dirs
+}
+
+// pathRules returns the Landlock rules granting the given access modes to
+// the files and directories.
+func pathRules(
+	files, dirs []string,
+	dirAccessMode landlock.AccessFSSet,
+	fileAccessMode landlock.AccessFSSet,
+) []landlock.Rule {
+	var rules []landlock.Rule
+
 	if len(files) > 0 {
 		rules = append(rules, landlock.PathAccess(
 			fileAccessMode,
@@ -10,6 +10,8 @@ import (
 	"github.com/landlock-lsm/go-landlock/landlock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
 )
 
 func TestProcessPaths(t *testing.T) {
@@ -126,3 +128,46 @@ func TestRulesForBinaryToRun(t *testing.T) {
 		})
 	}
 }
+
+func TestMinimumLandlockABI(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles map[string]podlockv1alpha1.ProfileByBinary
+		expected int
+	}{
+		{
+			name:     "no containers",
+			profiles: nil,
+			expected: 0,
+		},
+		{
+			name:     "containers without binaries",
+			profiles: map[string]podlockv1alpha1.ProfileByBinary{"main": {}},
+			expected: 0,
+		},
+		{
+			name: "read only profile",
+			profiles: map[string]podlockv1alpha1.ProfileByBinary{
+				"main": {"/usr/bin/cat": {ReadOnly: []string{"/etc"}}},
+			},
+			expected: LandlockABI,
+		},
+		{
+			name: "read write profile",
+			profiles: map[string]podlockv1alpha1.ProfileByBinary{
+				"init": {},
+				"main": {"/usr/sbin/nginx": {ReadWrite: []string{"/var/cache/nginx"}}},
+			},
+			expected: LandlockABI,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := &podlockv1alpha1.LandlockProfile{
+				Spec: podlockv1alpha1.LandlockProfileSpec{ProfilesByContainer: tt.profiles},
+			}
+			assert.Equal(t, tt.expected, MinimumLandlockABI(profile))
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package seal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/landlock-lsm/go-landlock/landlock"
+	"golang.org/x/sys/unix"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+// RulesetVersion is the version of the format of the compiled rulesets.
+const RulesetVersion = 1
+
+const rulesetFileMode = 0o644
+
+// ErrInvalidRuleset is returned when a compiled ruleset does not match the
+// profile it is supposed to be compiled from.
+var ErrInvalidRuleset = errors.New("invalid compiled ruleset")
+
+// CompiledPaths holds the paths of a profile, split by their type.
+type CompiledPaths struct {
+	Files []string `json:"files,omitempty"`
+	Dirs  []string `json:"dirs,omitempty"`
+}
+
+// CompiledRuleset holds the outcome of the expensive steps performed to build
+// the Landlock rules of a profile: checking the type of each path and
+// discovering the libraries linked to the binary.
+//
+// The compiled rulesets of a container are computed by the PodLock NRI
+// plugin before the container is started, so that seal does not compute
+// them each time a binary is started.
+type CompiledRuleset struct {
+	Version         int           `json:"version"`
+	Fingerprint     string        `json:"fingerprint"`
+	ReadOnly        CompiledPaths `json:"readOnly"`
+	ReadWrite       CompiledPaths `json:"readWrite"`
+	ReadExec        CompiledPaths `json:"readExec"`
+	ReadWriteExec   CompiledPaths `json:"readWriteExec"`
+	LinkedLibraries []string      `json:"linkedLibraries,omitempty"`
+}
+
+// CompileRuleset compiles the profile applied to the given binary.
+// The fingerprint must be the one computed by ProfileFingerprint.
+func CompileRuleset(
+	ctx context.Context,
+	profile *podlockv1alpha1.Profile,
+	binaryToRun string,
+	addLinkedLibraries bool,
+	fingerprint string,
+	logger *slog.Logger,
+	discoverLinkedLibsFn LinkedLibsFunc,
+) (*CompiledRuleset, error) {
+	ruleset := &CompiledRuleset{
+		Version:       RulesetVersion,
+		Fingerprint:   fingerprint,
+		ReadOnly:      compilePaths(profile.ReadOnly),
+		ReadWrite:     compilePaths(profile.ReadWrite),
+		ReadExec:      compilePaths(profile.ReadExec),
+		ReadWriteExec: compilePaths(profile.ReadWriteExec),
+	}
+
+	if addLinkedLibraries {
+		linkedLibs, err := discoverLinkedLibsFn(ctx, binaryToRun, logger)
+		if err != nil {
+			return nil, err
+		}
+		logger.DebugContext(ctx, "Detected linked libraries", slog.Any("libraries", linkedLibs))
+		ruleset.LinkedLibraries = linkedLibs
+	}
+
+	return ruleset, nil
+}
+
+// compilePaths classifies the given paths. Missing paths are not reported,
+// since they are checked again by LandlockRules.
+func compilePaths(paths []string) CompiledPaths {
+	files, dirs := classifyPaths(paths, slog.New(slog.DiscardHandler))
+	return CompiledPaths{Files: files, Dirs: dirs}
+}
+
+// CompileRulesetInRoot compiles the profile applied to the given binary,
+// resolving the paths inside of root. The fingerprint must be the one
+// computed by ProfileFingerprint.
+//
+// Nothing from root is run: the linked libraries are resolved by
+// ResolveLinkedLibraries, so that the root filesystem of a container can be
+// compiled from the host.
+func CompileRulesetInRoot(
+	root *RootFS,
+	profile *podlockv1alpha1.Profile,
+	binaryToRun string,
+	addLinkedLibraries bool,
+	fingerprint string,
+) (*CompiledRuleset, error) {
+	ruleset := &CompiledRuleset{
+		Version:       RulesetVersion,
+		Fingerprint:   fingerprint,
+		ReadOnly:      root.compilePaths(profile.ReadOnly),
+		ReadWrite:     root.compilePaths(profile.ReadWrite),
+		ReadExec:      root.compilePaths(profile.ReadExec),
+		ReadWriteExec: root.compilePaths(profile.ReadWriteExec),
+	}
+
+	if addLinkedLibraries {
+		linkedLibs, err := ResolveLinkedLibraries(root, binaryToRun)
+		if err != nil {
+			return nil, err
+		}
+		ruleset.LinkedLibraries = linkedLibs
+	}
+
+	return ruleset, nil
+}
+
+// compilePaths classifies the given paths, resolved inside of the root
+// filesystem, like compilePaths does.
+func (r *RootFS) compilePaths(paths []string) CompiledPaths {
+	var compiled CompiledPaths
+	for _, path := range paths {
+		stat, err := r.stat(path)
+		if err != nil {
+			continue
+		}
+		if stat.Mode&unix.S_IFMT == unix.S_IFDIR {
+			compiled.Dirs = append(compiled.Dirs, path)
+		} else {
+			compiled.Files = append(compiled.Files, path)
+		}
+	}
+	return compiled
+}
+
+// LandlockRules returns the Landlock rules of the compiled ruleset.
+//
+// The ruleset is checked against the profile, since the cache could have been
+// tampered with. The paths that did not exist when the ruleset was compiled
+// are checked again, since they could have been created in the meantime.
+func (r *CompiledRuleset) LandlockRules(
+	profile *podlockv1alpha1.Profile,
+	binaryToRun string,
+	logger *slog.Logger,
+) ([]landlock.Rule, error) {
+	var rules []landlock.Rule
+
+	for _, class := range []struct {
+		name       string
+		paths      []string
+		compiled   CompiledPaths
+		dirAccess  landlock.AccessFSSet
+		fileAccess landlock.AccessFSSet
+	}{
+		{"readOnly", profile.ReadOnly, r.ReadOnly, accessDirR, accessFileR},
+		{"readWrite", profile.ReadWrite, r.ReadWrite, accessDirRW, accessFileRW},
+		{"readExec", profile.ReadExec, r.ReadExec, accessDirRX, accessFileRX},
+		{"readWriteExec", profile.ReadWriteExec, r.ReadWriteExec, accessDirRWX, accessFileRWX},
+	} {
+		files, dirs, err := resolveCompiledPaths(class.paths, class.compiled, logger)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", class.name, err)
+		}
+		rules = append(rules, pathRules(files, dirs, class.dirAccess, class.fileAccess)...)
+	}
+
+	files, dirs := classifyPaths(append([]string{binaryToRun}, r.LinkedLibraries...), logger)
+	if len(dirs) > 0 {
+		return nil, fmt.Errorf("%w: linked libraries cannot be directories: %v", ErrInvalidRuleset, dirs)
+	}
+	rules = append(rules, pathRules(files, nil, accessDirRX, accessFileRX)...)
+
+	return rules, nil
+}
+
+// resolveCompiledPaths ensures the compiled paths are part of the profile and
+// classifies the paths of the profile that are missing from the compiled ones.
+func resolveCompiledPaths(paths []string, compiled CompiledPaths, logger *slog.Logger) ([]string, []string, error) {
+	var missing []string
+	for _, path := range paths {
+		if !slices.Contains(compiled.Files, path) && !slices.Contains(compiled.Dirs, path) {
+			missing = append(missing, path)
+		}
+	}
+
+	for _, path := range slices.Concat(compiled.Files, compiled.Dirs) {
+		if !slices.Contains(paths, path) {
+			return nil, nil, fmt.Errorf("%w: path '%s' is not part of the profile", ErrInvalidRuleset, path)
+		}
+	}
+
+	files := slices.Clone(compiled.Files)
+	dirs := slices.Clone(compiled.Dirs)
+	if len(missing) > 0 {
+		missingFiles, missingDirs := classifyPaths(missing, logger)
+		files = append(files, missingFiles...)
+		dirs = append(dirs, missingDirs...)
+	}
+
+	return files, dirs, nil
+}
+
+// RulesetCache stores compiled rulesets inside of a directory, using their
+// fingerprint as file name.
+type RulesetCache struct {
+	Dir string
+	// ReadOnly prevents LandlockRulesFromCache from storing the rulesets it
+	// compiles, e.g. because they are stored by the PodLock NRI plugin
+	ReadOnly bool
+}
+
+func (c *RulesetCache) path(fingerprint string) string {
+	return filepath.Join(c.Dir, fingerprint+".json")
+}
+
+// Load returns the compiled ruleset with the given fingerprint. An error
+// wrapping os.ErrNotExist is returned when the ruleset has not been cached yet.
+func (c *RulesetCache) Load(fingerprint string) (*CompiledRuleset, error) {
+	data, err := os.ReadFile(c.path(fingerprint))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read compiled ruleset: %w", err)
+	}
+
+	ruleset := &CompiledRuleset{}
+	if err = json.Unmarshal(data, ruleset); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRuleset, err)
+	}
+	if ruleset.Version != RulesetVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidRuleset, ruleset.Version)
+	}
+	if ruleset.Fingerprint != fingerprint {
+		return nil, fmt.Errorf("%w: fingerprint mismatch", ErrInvalidRuleset)
+	}
+
+	return ruleset, nil
+}
+
+// Store writes the compiled ruleset to the cache. The file is replaced
+// atomically, so that concurrent invocations never read a partial ruleset.
+func (c *RulesetCache) Store(ruleset *CompiledRuleset) error {
+	data, err := json.Marshal(ruleset)
+	if err != nil {
+		return fmt.Errorf("cannot marshal compiled ruleset: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.Dir, ".ruleset-*")
+	if err != nil {
+		return fmt.Errorf("cannot create compiled ruleset: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot write compiled ruleset: %w", err)
+	}
+	if err = tmp.Chmod(rulesetFileMode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot set permissions of compiled ruleset: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write compiled ruleset: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), c.path(ruleset.Fingerprint)); err != nil {
+		return fmt.Errorf("cannot store compiled ruleset: %w", err)
+	}
+
+	return nil
+}
+
+// verifyLinkedLibraries ensures the linked libraries of a cached ruleset are
+// dependencies of the binary. A ruleset planted inside of the cache could
+// otherwise grant the execution of any file.
+func (r *CompiledRuleset) verifyLinkedLibraries(binaryToRun string, addLinkedLibraries bool) error {
+	if !addLinkedLibraries && len(r.LinkedLibraries) > 0 {
+		return fmt.Errorf("%w: linked libraries are not requested", ErrInvalidRuleset)
+	}
+	if err := VerifyLinkedLibraries(binaryToRun, r.LinkedLibraries); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRuleset, err)
+	}
+	return nil
+}
+
+// LandlockRulesFromCache returns the Landlock rules for the profile applied
+// to the given binary. The compiled ruleset is loaded from the cache, or it is
+// compiled when missing or invalid, and stored unless the cache is read-only.
+// The cached ruleset is not trusted: its paths are checked against the profile
+// and its linked libraries against the dependencies of the binary.
+// The ruleset is always compiled when the cache is nil.
+func LandlockRulesFromCache(
+	ctx context.Context,
+	cache *RulesetCache,
+	profile *podlockv1alpha1.Profile,
+	binaryToRun string,
+	addLinkedLibraries bool,
+	fingerprint string,
+	logger *slog.Logger,
+	discoverLinkedLibsFn LinkedLibsFunc,
+) ([]landlock.Rule, error) {
+	if cache != nil {
+		ruleset, err := cache.Load(fingerprint)
+		if err == nil {
+			err = ruleset.verifyLinkedLibraries(binaryToRun, addLinkedLibraries)
+		}
+		if err == nil {
+			rules, rulesErr := ruleset.LandlockRules(profile, binaryToRun, logger)
+			if rulesErr == nil {
+				logger.DebugContext(ctx, "using cached compiled ruleset", slog.String("fingerprint", fingerprint))
+				return rules, nil
+			}
+			err = rulesErr
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.WarnContext(ctx, "discarding cached compiled ruleset", slog.Any("error", err))
+		}
+	}
+
+	ruleset, err := CompileRuleset(ctx, profile, binaryToRun, addLinkedLibraries, fingerprint, logger, discoverLinkedLibsFn)
+	if err != nil {
+		return nil, err
+	}
+
+	if cache != nil && !cache.ReadOnly {
+		if err = cache.Store(ruleset); err != nil {
+			logger.WarnContext(ctx, "cannot cache compiled ruleset", slog.Any("error", err))
+		}
+	}
+
+	return ruleset.LandlockRules(profile, binaryToRun, logger)
+}
@@ -0,0 +1,311 @@
+package seal
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/landlock-lsm/go-landlock/landlock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+type rulesetFixture struct {
+	profile *podlockv1alpha1.Profile
+	binary  string
+	lib     string
+	file    string
+	dir     string
+	missing string
+}
+
+func newRulesetFixture(t testing.TB) *rulesetFixture {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	fixture := &rulesetFixture{
+		binary:  filepath.Join(tmpDir, "binary"),
+		lib:     filepath.Join(tmpDir, "lib.so"),
+		file:    filepath.Join(tmpDir, "file"),
+		dir:     filepath.Join(tmpDir, "dir"),
+		missing: filepath.Join(tmpDir, "missing"),
+	}
+	require.NoError(t, os.WriteFile(fixture.binary, []byte("binary"), 0o755))
+	require.NoError(t, os.WriteFile(fixture.lib, []byte("library"), 0o644))
+	require.NoError(t, os.WriteFile(fixture.file, []byte("data"), 0o644))
+	require.NoError(t, os.Mkdir(fixture.dir, 0o755))
+
+	fixture.profile = &podlockv1alpha1.Profile{
+		ReadOnly:  []string{fixture.file, fixture.missing},
+		ReadWrite: []string{fixture.dir},
+	}
+
+	return fixture
+}
+
+func (f *rulesetFixture) discover(_ context.Context, _ string, _ *slog.Logger) ([]string, error) {
+	return []string{f.lib}, nil
+}
+
+func (f *rulesetFixture) wantRules() []landlock.Rule {
+	return []landlock.Rule{
+		landlock.PathAccess(accessFileR, f.file),
+		landlock.PathAccess(accessDirRW, f.dir),
+		landlock.PathAccess(accessFileRX, f.binary, f.lib),
+	}
+}
+
+func TestCompileRuleset(t *testing.T) {
+	fixture := newRulesetFixture(t)
+	logger := slog.New(slog.DiscardHandler)
+
+	ruleset, err := CompileRuleset(context.Background(), fixture.profile, fixture.binary, true, "abcd", logger, fixture.discover)
+	require.NoError(t, err)
+
+	assert.Equal(t, &CompiledRuleset{
+		Version:         RulesetVersion,
+		Fingerprint:     "abcd",
+		ReadOnly:        CompiledPaths{Files: []string{fixture.file}},
+		ReadWrite:       CompiledPaths{Dirs: []string{fixture.dir}},
+		LinkedLibraries: []string{fixture.lib},
+	}, ruleset)
+
+	rules, err := ruleset.LandlockRules(fixture.profile, fixture.binary, logger)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, fixture.wantRules(), rules)
+}
+
+func TestCompileRulesetInRoot(t *testing.T) {
+	fixture := newRulesetFixture(t)
+	binaryPath, err := exec.LookPath("cp")
+	require.NoError(t, err)
+
+	root, err := OpenRootFS("/")
+	require.NoError(t, err)
+	defer root.Close()
+
+	ruleset, err := CompileRulesetInRoot(root, fixture.profile, binaryPath, true, "abcd")
+	require.NoError(t, err)
+
+	libs, err := ResolveLinkedLibraries(root, binaryPath)
+	require.NoError(t, err)
+	assert.Equal(t, &CompiledRuleset{
+		Version:         RulesetVersion,
+		Fingerprint:     "abcd",
+		ReadOnly:        CompiledPaths{Files: []string{fixture.file}},
+		ReadWrite:       CompiledPaths{Dirs: []string{fixture.dir}},
+		LinkedLibraries: libs,
+	}, ruleset)
+
+	// The ruleset passes the verification of seal
+	require.NoError(t, ruleset.verifyLinkedLibraries(binaryPath, true))
+}
+
+func TestCompiledRulesetPicksUpCreatedPaths(t *testing.T) {
+	fixture := newRulesetFixture(t)
+	logger := slog.New(slog.DiscardHandler)
+
+	ruleset, err := CompileRuleset(context.Background(), fixture.profile, fixture.binary, false, "abcd", logger, fixture.discover)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(fixture.missing, []byte("data"), 0o644))
+
+	rules, err := ruleset.LandlockRules(fixture.profile, fixture.binary, logger)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []landlock.Rule{
+		landlock.PathAccess(accessFileR, fixture.file, fixture.missing),
+		landlock.PathAccess(accessDirRW, fixture.dir),
+		landlock.PathAccess(accessFileRX, fixture.binary),
+	}, rules)
+}
+
+func TestCompiledRulesetRejectsTampering(t *testing.T) {
+	fixture := newRulesetFixture(t)
+	logger := slog.New(slog.DiscardHandler)
+
+	tests := []struct {
+		name    string
+		ruleset *CompiledRuleset
+	}{
+		{
+			name: "path not part of the profile",
+			ruleset: &CompiledRuleset{
+				ReadWrite: CompiledPaths{Dirs: []string{fixture.dir, "/"}},
+			},
+		},
+		{
+			name: "path moved to another access class",
+			ruleset: &CompiledRuleset{
+				ReadWriteExec: CompiledPaths{Dirs: []string{fixture.dir}},
+			},
+		},
+		{
+			name: "directory listed as linked library",
+			ruleset: &CompiledRuleset{
+				LinkedLibraries: []string{fixture.dir},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.ruleset.LandlockRules(fixture.profile, fixture.binary, logger)
+			require.ErrorIs(t, err, ErrInvalidRuleset)
+		})
+	}
+}
+
+func TestRulesetCache(t *testing.T) {
+	cache := &RulesetCache{Dir: t.TempDir()}
+
+	_, err := cache.Load("abcd")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	ruleset := &CompiledRuleset{
+		Version:     RulesetVersion,
+		Fingerprint: "abcd",
+		ReadOnly:    CompiledPaths{Files: []string{"/etc/hosts"}},
+	}
+	require.NoError(t, cache.Store(ruleset))
+
+	loaded, err := cache.Load("abcd")
+	require.NoError(t, err)
+	assert.Equal(t, ruleset, loaded)
+
+	// A ruleset stored under the wrong name is rejected
+	require.NoError(t, os.Rename(cache.path("abcd"), cache.path("efgh")))
+	_, err = cache.Load("efgh")
+	require.ErrorIs(t, err, ErrInvalidRuleset)
+
+	// Unknown versions are rejected
+	data, err := json.Marshal(&CompiledRuleset{Version: RulesetVersion + 1, Fingerprint: "abcd"})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(cache.path("abcd"), data, 0o644))
+	_, err = cache.Load("abcd")
+	require.ErrorIs(t, err, ErrInvalidRuleset)
+}
+
+func TestLandlockRulesFromCache(t *testing.T) {
+	// The linked libraries of a cached ruleset are verified against the
+	// binary, which must then be a real, dynamically linked one
+	binaryPath, err := exec.LookPath("cp")
+	require.NoError(t, err)
+	otherBinary, err := exec.LookPath("ls")
+	require.NoError(t, err)
+
+	fixture := newRulesetFixture(t)
+	logger := slog.New(slog.DiscardHandler)
+	cache := &RulesetCache{Dir: t.TempDir()}
+
+	discoveries := 0
+	discover := func(ctx context.Context, binaryPath string, logger *slog.Logger) ([]string, error) {
+		discoveries++
+		return DiscoverLinkedLibraries(ctx, binaryPath, logger)
+	}
+
+	wantRules, err := LandlockRulesFromCache(context.Background(), nil, fixture.profile, binaryPath, true, "abcd", logger, discover)
+	require.NoError(t, err)
+	discoveries = 0
+
+	for range 3 {
+		rules, err := LandlockRulesFromCache(context.Background(), cache, fixture.profile, binaryPath, true, "abcd", logger, discover)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, wantRules, rules)
+	}
+	assert.Equal(t, 1, discoveries, "linked libraries must be discovered only once")
+
+	cached, err := cache.Load("abcd")
+	require.NoError(t, err)
+
+	for _, tampered := range []*CompiledRuleset{
+		{
+			Version:     RulesetVersion,
+			Fingerprint: "abcd",
+			ReadOnly:    CompiledPaths{Dirs: []string{"/"}},
+		},
+		{
+			Version:         RulesetVersion,
+			Fingerprint:     "abcd",
+			ReadOnly:        cached.ReadOnly,
+			ReadWrite:       cached.ReadWrite,
+			LinkedLibraries: append(slices.Clone(cached.LinkedLibraries), otherBinary),
+		},
+	} {
+		// A tampered ruleset is replaced
+		discoveries = 0
+		require.NoError(t, cache.Store(tampered))
+		rules, err := LandlockRulesFromCache(context.Background(), cache, fixture.profile, binaryPath, true, "abcd", logger, discover)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, wantRules, rules)
+		assert.Equal(t, 1, discoveries)
+
+		ruleset, err := cache.Load("abcd")
+		require.NoError(t, err)
+		assert.Equal(t, cached, ruleset)
+	}
+
+	// Linked libraries are rejected when they are not requested
+	discoveries = 0
+	_, err = LandlockRulesFromCache(context.Background(), cache, fixture.profile, binaryPath, false, "abcd", logger, discover)
+	require.NoError(t, err)
+	assert.Zero(t, discoveries)
+	ruleset, err := cache.Load("abcd")
+	require.NoError(t, err)
+	assert.Empty(t, ruleset.LinkedLibraries)
+}
+
+func TestLandlockRulesFromReadOnlyCache(t *testing.T) {
+	fixture := newRulesetFixture(t)
+	logger := slog.New(slog.DiscardHandler)
+	cache := &RulesetCache{Dir: t.TempDir(), ReadOnly: true}
+
+	rules, err := LandlockRulesFromCache(context.Background(), cache, fixture.profile, fixture.binary, true, "abcd", logger, fixture.discover)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, fixture.wantRules(), rules)
+
+	entries, err := os.ReadDir(cache.Dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the compiled ruleset must not be stored")
+}
+
+// benchmarkLandlockRules measures the time needed to build the Landlock rules
+// of a profile for the 'cp' binary, including the discovery of its linked
+// libraries.
+func benchmarkLandlockRules(b *testing.B, cache *RulesetCache) {
+	b.Helper()
+
+	binaryPath, err := exec.LookPath("cp")
+	if err != nil {
+		b.Skip("cp binary not found")
+	}
+
+	fixture := newRulesetFixture(b)
+	profile := &podlockv1alpha1.Profile{
+		ReadOnly:  []string{"/etc", "/usr/share", fixture.file, fixture.missing},
+		ReadWrite: []string{fixture.dir, os.TempDir()},
+		ReadExec:  []string{"/usr/lib", "/usr/bin"},
+	}
+	logger := slog.New(slog.DiscardHandler)
+	ctx := context.Background()
+
+	for b.Loop() {
+		if _, err := LandlockRulesFromCache(ctx, cache, profile, binaryPath, true, "abcd", logger, DiscoverLinkedLibraries); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLandlockRulesWithoutCache(b *testing.B) {
+	benchmarkLandlockRules(b, nil)
+}
+
+func BenchmarkLandlockRulesWithCache(b *testing.B) {
+	benchmarkLandlockRules(b, &RulesetCache{Dir: b.TempDir()})
+}
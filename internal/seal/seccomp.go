@@ -0,0 +1,314 @@
+package seal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"slices"
+	"unsafe"
+
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+const (
+	// SeccompPresetBaseline denies the system calls that are not needed by
+	// regular workloads.
+	SeccompPresetBaseline = "baseline"
+
+	// SeccompActionErrno makes a denied system call fail with EPERM.
+	SeccompActionErrno = "errno"
+
+	// SeccompActionKill terminates the process performing a denied system call.
+	SeccompActionKill = "kill"
+)
+
+const (
+	// offsets of the fields of struct seccomp_data
+	seccompDataNrOffset   = 0
+	seccompDataArchOffset = 4
+
+	// x32SyscallBit is set on the system calls performed using the x32 ABI.
+	x32SyscallBit = 0x40000000
+)
+
+//nolint:gochecknoglobals // lookup table
+var seccompPresets = map[string][]string{
+	SeccompPresetBaseline: {
+		"acct",
+		"add_key",
+		"bpf",
+		"delete_module",
+		"finit_module",
+		"fsconfig",
+		"fsmount",
+		"fsopen",
+		"fspick",
+		"init_module",
+		"iopl",
+		"ioperm",
+		"kcmp",
+		"kexec_file_load",
+		"kexec_load",
+		"keyctl",
+		"lookup_dcookie",
+		"mount",
+		"move_mount",
+		"name_to_handle_at",
+		"open_by_handle_at",
+		"open_tree",
+		"perf_event_open",
+		"pivot_root",
+		"process_vm_readv",
+		"process_vm_writev",
+		"ptrace",
+		"quotactl",
+		"reboot",
+		"request_key",
+		"setns",
+		"swapoff",
+		"swapon",
+		"umount2",
+		"unshare",
+		"userfaultfd",
+	},
+}
+
+// sealRuntimeSyscalls are the system calls seal performs after the seccomp
+// filter is loaded: the Go runtime keeps scheduling goroutines, handling
+// signals and managing memory until the binary is executed, and seal reports
+// a failed execution before exiting. They are added to every allow list,
+// hence the binary is allowed to perform them too.
+//
+//nolint:gochecknoglobals // lookup table
+var sealRuntimeSyscalls = []string{
+	"clock_gettime",
+	"clock_nanosleep",
+	"clone",
+	"clone3",
+	"close",
+	"epoll_ctl",
+	"epoll_pwait",
+	"epoll_wait",
+	"execve",
+	"exit",
+	"exit_group",
+	"fcntl",
+	"futex",
+	"getpid",
+	"getrandom",
+	"gettid",
+	"madvise",
+	"mmap",
+	"mprotect",
+	"munmap",
+	"nanosleep",
+	"openat",
+	"rt_sigaction",
+	"rt_sigprocmask",
+	"rt_sigreturn",
+	"sched_getaffinity",
+	"sched_yield",
+	"sigaltstack",
+	"tgkill",
+	"write",
+}
+
+// ErrUnsupportedArch is returned when seccomp filters cannot be built for the
+// architecture.
+var ErrUnsupportedArch = errors.New("seccomp filters are not supported on this architecture")
+
+// seccompArch describes an architecture supported by the seccomp filters.
+type seccompArch struct {
+	auditArch uint32
+	syscalls  map[string]uint32
+}
+
+//nolint:gochecknoglobals // lookup table
+var seccompArches = map[string]seccompArch{
+	"amd64": {auditArch: unix.AUDIT_ARCH_X86_64, syscalls: syscallsAMD64},
+	"arm64": {auditArch: unix.AUDIT_ARCH_AARCH64, syscalls: syscallsARM64},
+}
+
+// IsKnownSeccompPreset returns true when the given seccomp preset exists.
+func IsKnownSeccompPreset(name string) bool {
+	_, found := seccompPresets[name]
+	return found
+}
+
+// IsKnownSyscall returns true when the given system call exists on at least
+// one of the supported architectures.
+func IsKnownSyscall(name string) bool {
+	for _, arch := range seccompArches {
+		if _, found := arch.syscalls[name]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildSeccompFilter compiles the seccomp policy into a BPF program for the
+// given architecture.
+//
+// System calls that do not exist on the architecture are ignored, with the
+// exception of the ones that are explicitly allowed or denied by the policy.
+func BuildSeccompFilter(policy *podlockv1alpha1.Seccomp, goarch string) ([]bpf.Instruction, error) {
+	arch, found := seccompArches[goarch]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedArch, goarch)
+	}
+
+	if len(policy.Allow) > 0 && (policy.Preset != "" || len(policy.Deny) > 0) {
+		return nil, errors.New("seccomp allow list cannot be used together with a preset or a deny list")
+	}
+
+	denyAction := uint32(unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM))
+	switch policy.Action {
+	case SeccompActionErrno, "":
+	case SeccompActionKill:
+		denyAction = unix.SECCOMP_RET_KILL_PROCESS
+	default:
+		return nil, fmt.Errorf("unknown seccomp action '%s'", policy.Action)
+	}
+
+	var (
+		names         []string
+		matchAction   uint32
+		defaultAction uint32
+	)
+	if len(policy.Allow) > 0 {
+		// seal must keep running until it starts the binary
+		names = append(slices.Clone(policy.Allow), sealRuntimeSyscalls...)
+		matchAction = unix.SECCOMP_RET_ALLOW
+		defaultAction = denyAction
+	} else {
+		if policy.Preset != "" {
+			preset, found := seccompPresets[policy.Preset]
+			if !found {
+				return nil, fmt.Errorf("unknown seccomp preset '%s'", policy.Preset)
+			}
+			names = append(names, preset...)
+		}
+		names = append(names, policy.Deny...)
+		matchAction = denyAction
+		defaultAction = unix.SECCOMP_RET_ALLOW
+	}
+
+	numbers, err := syscallNumbers(names, policy, arch)
+	if err != nil {
+		return nil, err
+	}
+
+	program := []bpf.Instruction{
+		// Kill the process when the system call is performed using
+		// a different architecture
+		bpf.LoadAbsolute{Off: seccompDataArchOffset, Size: 4},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: arch.auditArch, SkipTrue: 1},
+		bpf.RetConstant{Val: unix.SECCOMP_RET_KILL_PROCESS},
+		bpf.LoadAbsolute{Off: seccompDataNrOffset, Size: 4},
+	}
+
+	if arch.auditArch == unix.AUDIT_ARCH_X86_64 {
+		// Deny the system calls performed using the x32 ABI, they would
+		// otherwise bypass the filter
+		program = append(program,
+			bpf.JumpIf{Cond: bpf.JumpGreaterOrEqual, Val: x32SyscallBit, SkipFalse: 1},
+			bpf.RetConstant{Val: denyAction},
+		)
+	}
+
+	for _, nr := range numbers {
+		program = append(program,
+			bpf.JumpIf{Cond: bpf.JumpNotEqual, Val: nr, SkipTrue: 1},
+			bpf.RetConstant{Val: matchAction},
+		)
+	}
+
+	return append(program, bpf.RetConstant{Val: defaultAction}), nil
+}
+
+// syscallNumbers converts the system call names into the sorted list of their
+// numbers on the given architecture.
+func syscallNumbers(names []string, policy *podlockv1alpha1.Seccomp, arch seccompArch) ([]uint32, error) {
+	var numbers []uint32
+
+	for _, name := range names {
+		nr, found := arch.syscalls[name]
+		if found {
+			numbers = append(numbers, nr)
+			continue
+		}
+
+		if !IsKnownSyscall(name) {
+			return nil, fmt.Errorf("unknown system call '%s'", name)
+		}
+		if slices.Contains(policy.Allow, name) || slices.Contains(policy.Deny, name) {
+			return nil, fmt.Errorf("system call '%s' does not exist on this architecture", name)
+		}
+	}
+
+	slices.Sort(numbers)
+	return slices.Compact(numbers), nil
+}
+
+// LoadSeccompFilter compiles the seccomp policy and loads it on all the
+// threads of the process. The filter is preserved by execve.
+func LoadSeccompFilter(policy *podlockv1alpha1.Seccomp, logger *slog.Logger) error {
+	program, err := BuildSeccompFilter(policy, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+
+	raw, err := bpf.Assemble(program)
+	if err != nil {
+		return fmt.Errorf("cannot assemble seccomp filter: %w", err)
+	}
+
+	filter := make([]unix.SockFilter, len(raw))
+	for i, instruction := range raw {
+		filter[i] = unix.SockFilter{
+			Code: instruction.Op,
+			Jt:   instruction.Jt,
+			Jf:   instruction.Jf,
+			K:    instruction.K,
+		}
+	}
+	fprog := unix.SockFprog{
+		Len:    uint16(len(filter)), //nolint:gosec // the number of instructions is bounded by the syscall tables
+		Filter: &filter[0],
+	}
+
+	// Loading a seccomp filter without CAP_SYS_ADMIN requires no_new_privs,
+	// which is already set when Landlock is enforced. The flag is propagated
+	// to the other threads together with the filter.
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("cannot set no_new_privs: %w", err)
+	}
+
+	logger.Info("loading seccomp filter",
+		slog.String("preset", policy.Preset),
+		slog.Any("allow", policy.Allow),
+		slog.Any("deny", policy.Deny),
+		slog.String("action", policy.Action),
+		slog.Int("instructions", len(filter)),
+	)
+
+	tid, _, errno := unix.Syscall(
+		unix.SYS_SECCOMP,
+		unix.SECCOMP_SET_MODE_FILTER,
+		unix.SECCOMP_FILTER_FLAG_TSYNC,
+		uintptr(unsafe.Pointer(&fprog)),
+	)
+	if errno != 0 {
+		return fmt.Errorf("cannot load seccomp filter: %w", errno)
+	}
+	if tid != 0 {
+		return fmt.Errorf("cannot load seccomp filter: thread %d cannot be synchronized", tid)
+	}
+	runtime.KeepAlive(filter)
+
+	return nil
+}
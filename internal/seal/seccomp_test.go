@@ -0,0 +1,174 @@
+package seal
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+)
+
+// runSeccompFilter evaluates the filter against a system call, the same way
+// the kernel would do. The BPF virtual machine loads data in big endian.
+func runSeccompFilter(t *testing.T, program []bpf.Instruction, auditArch, nr uint32) uint32 {
+	t.Helper()
+
+	vm, err := bpf.NewVM(program)
+	require.NoError(t, err)
+
+	data := make([]byte, 64)
+	binary.BigEndian.PutUint32(data[seccompDataNrOffset:], nr)
+	binary.BigEndian.PutUint32(data[seccompDataArchOffset:], auditArch)
+
+	action, err := vm.Run(data)
+	require.NoError(t, err)
+
+	return uint32(action) //nolint:gosec // the VM returns the 32 bits action
+}
+
+func TestBuildSeccompFilter(t *testing.T) {
+	errnoAction := uint32(unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM))
+
+	tests := []struct {
+		name        string
+		policy      *podlockv1alpha1.Seccomp
+		goarch      string
+		syscall     string
+		wantAction  uint32
+		otherAction uint32
+	}{
+		{
+			name:        "baseline preset",
+			policy:      &podlockv1alpha1.Seccomp{Preset: SeccompPresetBaseline},
+			goarch:      "amd64",
+			syscall:     "ptrace",
+			wantAction:  errnoAction,
+			otherAction: unix.SECCOMP_RET_ALLOW,
+		},
+		{
+			name:        "deny list with kill action",
+			policy:      &podlockv1alpha1.Seccomp{Deny: []string{"keyctl"}, Action: SeccompActionKill},
+			goarch:      "arm64",
+			syscall:     "keyctl",
+			wantAction:  unix.SECCOMP_RET_KILL_PROCESS,
+			otherAction: unix.SECCOMP_RET_ALLOW,
+		},
+		{
+			name:        "allow list",
+			policy:      &podlockv1alpha1.Seccomp{Allow: []string{"read", "write"}},
+			goarch:      "amd64",
+			syscall:     "write",
+			wantAction:  unix.SECCOMP_RET_ALLOW,
+			otherAction: errnoAction,
+		},
+		{
+			name:        "allow list always allows execve",
+			policy:      &podlockv1alpha1.Seccomp{Allow: []string{"read"}},
+			goarch:      "arm64",
+			syscall:     "execve",
+			wantAction:  unix.SECCOMP_RET_ALLOW,
+			otherAction: errnoAction,
+		},
+		{
+			name:        "allow list always allows the Go runtime of seal",
+			policy:      &podlockv1alpha1.Seccomp{Allow: []string{"read"}, Action: SeccompActionKill},
+			goarch:      "amd64",
+			syscall:     "futex",
+			wantAction:  unix.SECCOMP_RET_ALLOW,
+			otherAction: unix.SECCOMP_RET_KILL_PROCESS,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := BuildSeccompFilter(tt.policy, tt.goarch)
+			require.NoError(t, err)
+
+			arch := seccompArches[tt.goarch]
+			nr := arch.syscalls[tt.syscall]
+			assert.Equal(t, tt.wantAction, runSeccompFilter(t, program, arch.auditArch, nr))
+
+			// uname is never part of the test policies
+			otherNr := arch.syscalls["uname"]
+			assert.Equal(t, tt.otherAction, runSeccompFilter(t, program, arch.auditArch, otherNr))
+
+			// system calls performed with a different architecture kill the process
+			assert.Equal(t, uint32(unix.SECCOMP_RET_KILL_PROCESS), runSeccompFilter(t, program, 0, nr))
+		})
+	}
+}
+
+func TestBuildSeccompFilterDeniesX32(t *testing.T) {
+	program, err := BuildSeccompFilter(&podlockv1alpha1.Seccomp{Deny: []string{"ptrace"}}, "amd64")
+	require.NoError(t, err)
+
+	nr := x32SyscallBit | syscallsAMD64["getpid"]
+	assert.Equal(t,
+		uint32(unix.SECCOMP_RET_ERRNO|uint32(unix.EPERM)),
+		runSeccompFilter(t, program, unix.AUDIT_ARCH_X86_64, nr),
+	)
+}
+
+func TestBuildSeccompFilterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *podlockv1alpha1.Seccomp
+		goarch string
+	}{
+		{
+			name:   "unsupported architecture",
+			policy: &podlockv1alpha1.Seccomp{Deny: []string{"ptrace"}},
+			goarch: "riscv64",
+		},
+		{
+			name:   "allow and deny",
+			policy: &podlockv1alpha1.Seccomp{Allow: []string{"read"}, Deny: []string{"ptrace"}},
+			goarch: "amd64",
+		},
+		{
+			name:   "unknown preset",
+			policy: &podlockv1alpha1.Seccomp{Preset: "foo"},
+			goarch: "amd64",
+		},
+		{
+			name:   "unknown action",
+			policy: &podlockv1alpha1.Seccomp{Deny: []string{"ptrace"}, Action: "trap"},
+			goarch: "amd64",
+		},
+		{
+			name:   "unknown system call",
+			policy: &podlockv1alpha1.Seccomp{Deny: []string{"not_a_syscall"}},
+			goarch: "amd64",
+		},
+		{
+			name:   "system call missing on the architecture",
+			policy: &podlockv1alpha1.Seccomp{Deny: []string{"iopl"}},
+			goarch: "arm64",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BuildSeccompFilter(tt.policy, tt.goarch)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestSeccompPresetsContainKnownSyscalls(t *testing.T) {
+	for preset, syscalls := range seccompPresets {
+		for _, name := range syscalls {
+			assert.True(t, IsKnownSyscall(name), "preset %s contains unknown system call %s", preset, name)
+		}
+
+		// presets must build on all the supported architectures
+		for goarch := range seccompArches {
+			_, err := BuildSeccompFilter(&podlockv1alpha1.Seccomp{Preset: preset}, goarch)
+			require.NoError(t, err, "preset %s on %s", preset, goarch)
+		}
+	}
+}
@@ -6,12 +6,12 @@ import (
 
 	"github.com/go-logr/logr"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/profilevalidation"
 )
 
 // SetupRegistryWebhookWithManager registers the webhook for Registry in the manager.
@@ -69,7 +69,7 @@ var _ admission.Validator[*v1alpha1.LandlockProfile] = &LandlockProfileCustomVal
 func (v *LandlockProfileCustomValidator) ValidateCreate(_ context.Context, profile *v1alpha1.LandlockProfile) (admission.Warnings, error) {
 	v.logger.Info("Validation for LandlockProfile upon creation", "name", profile.GetName())
 
-	allErrs := v.validateProfile(profile)
+	allErrs := profilevalidation.ValidateLandlockProfile(profile)
 
 	if len(allErrs) > 0 {
 		return nil, apierrors.NewInvalid(
@@ -87,7 +87,7 @@ func (v *LandlockProfileCustomValidator) ValidateUpdate(_ context.Context, _, ne
 	profile := newObj
 	v.logger.Info("Validation for LandlockProfile upon update", "name", profile.GetName())
 
-	allErrs := v.validateProfile(profile)
+	allErrs := profilevalidation.ValidateLandlockProfile(profile)
 
 	if len(allErrs) > 0 {
 		return nil, apierrors.NewInvalid(
@@ -100,27 +100,6 @@ func (v *LandlockProfileCustomValidator) ValidateUpdate(_ context.Context, _, ne
 	return nil, nil
 }
 
-func (v *LandlockProfileCustomValidator) validateProfile(profile *v1alpha1.LandlockProfile) field.ErrorList {
-	var allErrs field.ErrorList
-
-	specPath := field.NewPath("spec", "profilesByContainer")
-	for containerName, profileByBinary := range profile.Spec.ProfilesByContainer {
-		containerPath := specPath.Key(containerName)
-		for binaryPath, binProfile := range profileByBinary {
-			binaryPathField := containerPath.Key(binaryPath)
-
-			allErrs = append(allErrs, v.validateBinaryPath(binaryPath, binaryPathField)...)
-			allErrs = append(allErrs, v.validateNoOverlappingPaths(binProfile, binaryPathField)...)
-			allErrs = append(allErrs, v.validateReadOnlyPaths(binProfile, binaryPathField)...)
-			allErrs = append(allErrs, v.validateReadWritePaths(binProfile, binaryPathField)...)
-			allErrs = append(allErrs, v.validateReadExecPaths(binProfile, binaryPathField)...)
-			allErrs = append(allErrs, v.validateReadWriteExecPaths(binProfile, binaryPathField)...)
-		}
-	}
-
-	return allErrs
-}
-
 // ValidateDelete implements admission.Validator so a webhook will be registered for the type LandlockProfile.
 func (v *LandlockProfileCustomValidator) ValidateDelete(_ context.Context, profile *v1alpha1.LandlockProfile) (admission.Warnings, error) {
 	v.logger.Info("Validation for LandlockProfile upon deletion", "name", profile.GetName())
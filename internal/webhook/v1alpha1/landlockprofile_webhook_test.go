@@ -3,6 +3,7 @@ package v1alpha1
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/assert"
@@ -10,6 +11,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
 )
 
 func TestLandlockProfileCustomValidator_ValidateCreate(t *testing.T) {
@@ -248,6 +250,343 @@ func TestLandlockProfileCustomValidator_ValidateCreate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "overlapping paths",
 		},
+		{
+			name: "valid capabilities and rlimits",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Capabilities: &v1alpha1.Capabilities{
+									Drop:         []string{"NET_RAW", "CAP_SYS_ADMIN", "all"},
+									ClearAmbient: true,
+								},
+								Rlimits: []v1alpha1.Rlimit{
+									{Type: "RLIMIT_NOFILE", Soft: 1024, Hard: 4096},
+									{Type: "RLIMIT_CORE", Soft: 0, Hard: 0},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown capability",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Capabilities: &v1alpha1.Capabilities{
+									Drop: []string{"CAP_NOT_REAL"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "unknown capability",
+		},
+		{
+			name: "unknown rlimit type",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Rlimits: []v1alpha1.Rlimit{
+									{Type: "RLIMIT_FOO", Soft: 1, Hard: 1},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "unknown rlimit type",
+		},
+		{
+			name: "duplicated rlimit type",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Rlimits: []v1alpha1.Rlimit{
+									{Type: "RLIMIT_NPROC", Soft: 1, Hard: 1},
+									{Type: "RLIMIT_NPROC", Soft: 2, Hard: 2},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "Duplicate value",
+		},
+		{
+			name: "soft rlimit greater than hard",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Rlimits: []v1alpha1.Rlimit{
+									{Type: "RLIMIT_NOFILE", Soft: 2048, Hard: 1024},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "must be less than or equal to the hard limit",
+		},
+		{
+			name: "unlimited rlimits",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Rlimits: []v1alpha1.Rlimit{
+									{Type: "RLIMIT_CORE", Soft: 0, Hard: v1alpha1.RlimitUnlimited},
+									{Type: "RLIMIT_STACK", Soft: v1alpha1.RlimitUnlimited, Hard: v1alpha1.RlimitUnlimited},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unlimited soft rlimit with a hard limit",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Rlimits: []v1alpha1.Rlimit{
+									{Type: "RLIMIT_CORE", Soft: v1alpha1.RlimitUnlimited, Hard: 1024},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "must be less than or equal to the hard limit",
+		},
+		{
+			name: "valid seccomp policy",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Seccomp: &v1alpha1.Seccomp{
+									Preset: "baseline",
+									Deny:   []string{"iopl", "chroot"},
+									Action: "kill",
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "seccomp allow list with deny list",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Seccomp: &v1alpha1.Seccomp{
+									Allow: []string{"read"},
+									Deny:  []string{"ptrace"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "cannot be used together with preset or deny",
+		},
+		{
+			name: "seccomp unknown system call",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Seccomp: &v1alpha1.Seccomp{
+									Allow: []string{"read", "not_a_syscall"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "unknown system call",
+		},
+		{
+			name: "seccomp unknown preset",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Seccomp: &v1alpha1.Seccomp{
+									Preset: "permissive",
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "Unsupported value",
+		},
+		{
+			name: "valid environment policy",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Environment: &v1alpha1.EnvironmentPolicy{
+									Allow: []string{"PATH", "HOME", "APP_*"},
+									Deny:  []string{"LD_*", "PYTHONPATH"},
+									Set:   map[string]string{"NODE_OPTIONS": ""},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "environment invalid pattern",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Environment: &v1alpha1.EnvironmentPolicy{
+									Deny: []string{"LD_*_PATH"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "invalid environment variable pattern",
+		},
+		{
+			name: "environment reserved variable",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								Environment: &v1alpha1.EnvironmentPolicy{
+									Set: map[string]string{seal.DomainEnvVar: "1:stack:abcd"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "environment variable is reserved to seal",
+		},
+		{
+			name: "invalid sha256 digest",
+			profile: &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+						"app": {
+							"/usr/bin/app": {
+								ReadOnly: []string{"/etc"},
+								SHA256:   "sha256:abcd",
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "must be a lowercase hex encoded sha256 digest",
+		},
 	}
 
 	for _, tt := range tests {
@@ -455,3 +794,84 @@ func TestLandlockProfileCustomValidator_ValidateDelete(t *testing.T) {
 	_, err := validator.ValidateDelete(context.Background(), profile)
 	require.NoError(t, err)
 }
+
+func TestLandlockProfileCustomValidator_UpdateStrategy(t *testing.T) {
+	validator := &LandlockProfileCustomValidator{
+		logger: logr.Discard(),
+	}
+
+	tests := []struct {
+		name     string
+		strategy *v1alpha1.UpdateStrategy
+		errMsg   string
+	}{
+		{
+			name:     "immediate",
+			strategy: &v1alpha1.UpdateStrategy{Type: v1alpha1.ImmediateUpdateStrategyType},
+		},
+		{
+			name: "canary with percentage",
+			strategy: &v1alpha1.UpdateStrategy{
+				Type:   v1alpha1.CanaryUpdateStrategyType,
+				Canary: &v1alpha1.CanaryStrategy{Percentage: 10},
+			},
+		},
+		{
+			name:     "canary without configuration",
+			strategy: &v1alpha1.UpdateStrategy{Type: v1alpha1.CanaryUpdateStrategyType},
+			errMsg:   "spec.updateStrategy.canary: Required value",
+		},
+		{
+			name: "canary selecting no pod",
+			strategy: &v1alpha1.UpdateStrategy{
+				Type:   v1alpha1.CanaryUpdateStrategyType,
+				Canary: &v1alpha1.CanaryStrategy{MinReadyPods: 1},
+			},
+			errMsg: "either percentage or selector must be set",
+		},
+		{
+			name: "canary with invalid selector",
+			strategy: &v1alpha1.UpdateStrategy{
+				Type: v1alpha1.CanaryUpdateStrategyType,
+				Canary: &v1alpha1.CanaryStrategy{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"track": "not valid"}},
+				},
+			},
+			errMsg: "spec.updateStrategy.canary.selector.matchLabels",
+		},
+		{
+			name: "canary deadline shorter than the analysis",
+			strategy: &v1alpha1.UpdateStrategy{
+				Type: v1alpha1.CanaryUpdateStrategyType,
+				Canary: &v1alpha1.CanaryStrategy{
+					Percentage:       10,
+					AnalysisPeriod:   &metav1.Duration{Duration: 10 * time.Minute},
+					ProgressDeadline: &metav1.Duration{Duration: 5 * time.Minute},
+				},
+			},
+			errMsg: "must not be shorter than analysisPeriod",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := &v1alpha1.LandlockProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-profile",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.LandlockProfileSpec{
+					UpdateStrategy: tt.strategy,
+				},
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), profile)
+			if tt.errMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+)
+
+// SetupLandlockProfileRevisionWebhookWithManager registers the webhook for
+// LandlockProfileRevision in the manager.
+func SetupLandlockProfileRevisionWebhookWithManager(mgr ctrl.Manager) error {
+	err := ctrl.NewWebhookManagedBy(mgr, &v1alpha1.LandlockProfileRevision{}).
+		WithValidator(&LandlockProfileRevisionCustomValidator{
+			logger: mgr.GetLogger().WithName("landlockprofilerevision_validator"),
+		}).
+		Complete()
+	if err != nil {
+		return fmt.Errorf("failed to setup LandlockProfileRevision webhook: %w", err)
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-podlock-kubewarden-io-v1alpha1-landlockprofilerevision,mutating=false,failurePolicy=fail,sideEffects=None,groups=podlock.kubewarden.io,resources=landlockprofilerevisions,verbs=update,versions=v1alpha1,name=vlandlockprofilerevision.podlock.kubewarden.io,admissionReviewVersions=v1
+
+// LandlockProfileRevisionCustomValidator ensures revisions are immutable.
+// Only their metadata can be changed.
+type LandlockProfileRevisionCustomValidator struct {
+	logger logr.Logger
+}
+
+var _ admission.Validator[*v1alpha1.LandlockProfileRevision] = &LandlockProfileRevisionCustomValidator{}
+
+// ValidateCreate implements admission.Validator so a webhook will be registered for the type LandlockProfileRevision.
+func (v *LandlockProfileRevisionCustomValidator) ValidateCreate(
+	_ context.Context,
+	_ *v1alpha1.LandlockProfileRevision,
+) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateUpdate implements admission.Validator so a webhook will be registered for the type LandlockProfileRevision.
+func (v *LandlockProfileRevisionCustomValidator) ValidateUpdate(
+	_ context.Context,
+	oldObj, newObj *v1alpha1.LandlockProfileRevision,
+) (admission.Warnings, error) {
+	v.logger.Info("Validation for LandlockProfileRevision upon update", "name", newObj.GetName())
+
+	var allErrs field.ErrorList
+	if oldObj.Revision != newObj.Revision {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("revision"), "the field is immutable"))
+	}
+	if !equality.Semantic.DeepEqual(oldObj.ProfilesByContainer, newObj.ProfilesByContainer) {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("profilesByContainer"), "the field is immutable"))
+	}
+
+	if len(allErrs) > 0 {
+		return nil, apierrors.NewInvalid(
+			v1alpha1.GroupVersion.WithKind("LandlockProfileRevision").GroupKind(),
+			newObj.Name,
+			allErrs,
+		)
+	}
+
+	return nil, nil
+}
+
+// ValidateDelete implements admission.Validator so a webhook will be registered for the type LandlockProfileRevision.
+func (v *LandlockProfileRevisionCustomValidator) ValidateDelete(
+	_ context.Context,
+	_ *v1alpha1.LandlockProfileRevision,
+) (admission.Warnings, error) {
+	return nil, nil
+}
@@ -0,0 +1,84 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flavio/podlock/api/v1alpha1"
+)
+
+func TestLandlockProfileRevisionCustomValidator_ValidateUpdate(t *testing.T) {
+	validator := &LandlockProfileRevisionCustomValidator{
+		logger: logr.Discard(),
+	}
+
+	oldRevision := &v1alpha1.LandlockProfileRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx-1",
+			Namespace: "default",
+		},
+		Revision: 1,
+		ProfilesByContainer: map[string]v1alpha1.ProfileByBinary{
+			"nginx": {
+				"/usr/sbin/nginx": {
+					ReadOnly: []string{"/etc/nginx"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		update  func(*v1alpha1.LandlockProfileRevision)
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "metadata changed",
+			update: func(revision *v1alpha1.LandlockProfileRevision) {
+				revision.Labels = map[string]string{"team": "web"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "revision changed",
+			update: func(revision *v1alpha1.LandlockProfileRevision) {
+				revision.Revision = 2
+			},
+			wantErr: true,
+			errMsg:  "revision: Forbidden: the field is immutable",
+		},
+		{
+			name: "profiles changed",
+			update: func(revision *v1alpha1.LandlockProfileRevision) {
+				revision.ProfilesByContainer["nginx"]["/usr/sbin/nginx"] = v1alpha1.Profile{
+					ReadOnly: []string{"/"},
+				}
+			},
+			wantErr: true,
+			errMsg:  "profilesByContainer: Forbidden: the field is immutable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newRevision := oldRevision.DeepCopy()
+			tt.update(newRevision)
+
+			warnings, err := validator.ValidateUpdate(context.Background(), oldRevision, newRevision)
+			assert.Nil(t, warnings)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
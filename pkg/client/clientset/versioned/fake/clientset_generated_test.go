@@ -0,0 +1,79 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/pkg/client/informers/externalversions"
+)
+
+func newLandlockProfile(name string) *podlockv1alpha1.LandlockProfile {
+	return &podlockv1alpha1.LandlockProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: podlockv1alpha1.LandlockProfileSpec{
+			ProfilesByContainer: map[string]podlockv1alpha1.ProfileByBinary{
+				"nginx": {
+					"/usr/sbin/nginx": {ReadOnly: []string{"/etc/nginx"}},
+				},
+			},
+		},
+	}
+}
+
+func TestTypedClient(t *testing.T) {
+	ctx := context.Background()
+	client := NewSimpleClientset(newLandlockProfile("existing"))
+	profiles := client.PodlockV1alpha1().LandlockProfiles("default")
+
+	existing, err := profiles.Get(ctx, "existing", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/etc/nginx"},
+		existing.Spec.ProfilesByContainer["nginx"]["/usr/sbin/nginx"].ReadOnly)
+
+	_, err = profiles.Create(ctx, newLandlockProfile("created"), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	existing.Status.Conditions = []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue}}
+	_, err = profiles.UpdateStatus(ctx, existing, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	list, err := profiles.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, list.Items, 2)
+
+	require.NoError(t, profiles.Delete(ctx, "created", metav1.DeleteOptions{}))
+	list, err = profiles.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, list.Items, 1)
+	assert.Len(t, list.Items[0].Status.Conditions, 1)
+}
+
+func TestInformer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := NewSimpleClientset(newLandlockProfile("existing"))
+	factory := externalversions.NewSharedInformerFactory(client, time.Minute)
+	lister := factory.Podlock().V1alpha1().LandlockProfiles().Lister()
+
+	factory.StartWithContext(ctx)
+	require.NoError(t, factory.WaitForCacheSyncWithContext(ctx).AsError())
+
+	profile, err := lister.LandlockProfiles("default").Get("existing")
+	require.NoError(t, err)
+	assert.Equal(t, "existing", profile.Name)
+
+	profiles, err := lister.List(labels.Everything())
+	require.NoError(t, err)
+	assert.Len(t, profiles, 1)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	apiv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// LandlockProfileLister helps list LandlockProfiles.
+// All objects returned here must be treated as read-only.
+type LandlockProfileLister interface {
+	// List lists all LandlockProfiles in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*apiv1alpha1.LandlockProfile, err error)
+	// LandlockProfiles returns an object that can list and get LandlockProfiles.
+	LandlockProfiles(namespace string) LandlockProfileNamespaceLister
+	LandlockProfileListerExpansion
+}
+
+// landlockProfileLister implements the LandlockProfileLister interface.
+type landlockProfileLister struct {
+	listers.ResourceIndexer[*apiv1alpha1.LandlockProfile]
+}
+
+// NewLandlockProfileLister returns a new LandlockProfileLister.
+func NewLandlockProfileLister(indexer cache.Indexer) LandlockProfileLister {
+	return &landlockProfileLister{listers.New[*apiv1alpha1.LandlockProfile](indexer, apiv1alpha1.Resource("landlockprofile"))}
+}
+
+// LandlockProfiles returns an object that can list and get LandlockProfiles.
+func (s *landlockProfileLister) LandlockProfiles(namespace string) LandlockProfileNamespaceLister {
+	return landlockProfileNamespaceLister{listers.NewNamespaced[*apiv1alpha1.LandlockProfile](s.ResourceIndexer, namespace)}
+}
+
+// LandlockProfileNamespaceLister helps list and get LandlockProfiles.
+// All objects returned here must be treated as read-only.
+type LandlockProfileNamespaceLister interface {
+	// List lists all LandlockProfiles in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*apiv1alpha1.LandlockProfile, err error)
+	// Get retrieves the LandlockProfile from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*apiv1alpha1.LandlockProfile, error)
+	LandlockProfileNamespaceListerExpansion
+}
+
+// landlockProfileNamespaceLister implements the LandlockProfileNamespaceLister
+// interface.
+type landlockProfileNamespaceLister struct {
+	listers.ResourceIndexer[*apiv1alpha1.LandlockProfile]
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	apiv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// LandlockProfileRevisionLister helps list LandlockProfileRevisions.
+// All objects returned here must be treated as read-only.
+type LandlockProfileRevisionLister interface {
+	// List lists all LandlockProfileRevisions in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*apiv1alpha1.LandlockProfileRevision, err error)
+	// LandlockProfileRevisions returns an object that can list and get LandlockProfileRevisions.
+	LandlockProfileRevisions(namespace string) LandlockProfileRevisionNamespaceLister
+	LandlockProfileRevisionListerExpansion
+}
+
+// landlockProfileRevisionLister implements the LandlockProfileRevisionLister interface.
+type landlockProfileRevisionLister struct {
+	listers.ResourceIndexer[*apiv1alpha1.LandlockProfileRevision]
+}
+
+// NewLandlockProfileRevisionLister returns a new LandlockProfileRevisionLister.
+func NewLandlockProfileRevisionLister(indexer cache.Indexer) LandlockProfileRevisionLister {
+	return &landlockProfileRevisionLister{listers.New[*apiv1alpha1.LandlockProfileRevision](indexer, apiv1alpha1.Resource("landlockprofilerevision"))}
+}
+
+// LandlockProfileRevisions returns an object that can list and get LandlockProfileRevisions.
+func (s *landlockProfileRevisionLister) LandlockProfileRevisions(namespace string) LandlockProfileRevisionNamespaceLister {
+	return landlockProfileRevisionNamespaceLister{listers.NewNamespaced[*apiv1alpha1.LandlockProfileRevision](s.ResourceIndexer, namespace)}
+}
+
+// LandlockProfileRevisionNamespaceLister helps list and get LandlockProfileRevisions.
+// All objects returned here must be treated as read-only.
+type LandlockProfileRevisionNamespaceLister interface {
+	// List lists all LandlockProfileRevisions in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*apiv1alpha1.LandlockProfileRevision, err error)
+	// Get retrieves the LandlockProfileRevision from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*apiv1alpha1.LandlockProfileRevision, error)
+	LandlockProfileRevisionNamespaceListerExpansion
+}
+
+// landlockProfileRevisionNamespaceLister implements the LandlockProfileRevisionNamespaceLister
+// interface.
+type landlockProfileRevisionNamespaceLister struct {
+	listers.ResourceIndexer[*apiv1alpha1.LandlockProfileRevision]
+}
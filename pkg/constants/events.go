@@ -0,0 +1,32 @@
+package constants
+
+const (
+	// ProfileUnavailableEventReason is the reason of the Events reporting a
+	// container started without its LandlockProfile, because the profile
+	// could not be retrieved and the failure policy allows it.
+	ProfileUnavailableEventReason = "LandlockProfileUnavailable"
+
+	// ProfileAppliedEventReason is the reason of the Events reporting a
+	// container sandboxed with its LandlockProfile.
+	ProfileAppliedEventReason = "LandlockProfileApplied"
+
+	// ProfileEntryMissingEventReason is the reason of the Events reporting a
+	// container started without Landlock sandbox, because the LandlockProfile
+	// of its pod has no entry for it.
+	ProfileEntryMissingEventReason = "LandlockProfileEntryMissing"
+
+	// NamespaceExcludedEventReason is the reason of the Events reporting a
+	// container started without Landlock sandbox, because the namespace of
+	// its pod is excluded by the configuration of the NRI plugin.
+	NamespaceExcludedEventReason = "LandlockNamespaceExcluded"
+
+	// ProfileLookupFailedEventReason is the reason of the Events reporting a
+	// container not created, because its LandlockProfile could not be
+	// retrieved and the failure policy denies it.
+	ProfileLookupFailedEventReason = "LandlockProfileLookupFailed"
+
+	// SwapVerificationFailedEventReason is the reason of the Events reporting
+	// a container whose binaries have not been swapped with seal, hence
+	// running without Landlock sandbox.
+	SwapVerificationFailedEventReason = "LandlockSwapVerificationFailed"
+)
@@ -6,5 +6,41 @@ const (
 	LandlockVersionNodeLabelKey = "podlock.kubewarden.io/landlock-version"
 
 	// PodProfileLabel is the label used by pods to enable PodLock NRI plugin.
+	// Its value is the name of the LandlockProfile, optionally followed by
+	// ProfileRevisionSeparator and the number of the revision to pin.
 	PodProfileLabel = "podlock.kubewarden.io/profile"
+
+	// ProfileRevisionSeparator separates the name of the LandlockProfile from
+	// the revision number inside of the PodProfileLabel. It cannot be part of
+	// the name of a Kubernetes object.
+	ProfileRevisionSeparator = "_"
+
+	// PodProfileRevisionAnnotation is the pod annotation used to pin the
+	// revision of the LandlockProfile applied to the pod, as an alternative
+	// to adding the revision to the PodProfileLabel.
+	PodProfileRevisionAnnotation = "podlock.kubewarden.io/profile-revision"
+
+	// RevisionProfileLabel is the label set on each LandlockProfileRevision,
+	// holding the name of its LandlockProfile, truncated to 63 characters.
+	RevisionProfileLabel = "podlock.kubewarden.io/landlock-profile"
+
+	// RollbackAnnotation is set on a LandlockProfile to restore the profiles
+	// of one of its revisions. The annotation is removed by the controller
+	// once the rollback is done.
+	RollbackAnnotation = "podlock.kubewarden.io/rollback-to"
+
+	// AppliedProfileAnnotationPrefix is the prefix of the pod annotations
+	// used by the NRI plugin to record the version of the profile applied to
+	// each container. The name of the container follows the prefix.
+	AppliedProfileAnnotationPrefix = "applied.podlock.kubewarden.io/"
+
+	// RolloutAnnotation is set by the controller on the pod template of the
+	// workloads it restarts because their profile changed. Its value
+	// identifies the version of the profile, in the form "<uid>/<generation>".
+	RolloutAnnotation = "podlock.kubewarden.io/rollout"
+
+	// NamespaceFailurePolicyLabel is set on a namespace to override the
+	// failure policy of the NRI plugin for its pods. The policy tells what
+	// happens to a container whose LandlockProfile cannot be retrieved.
+	NamespaceFailurePolicyLabel = "podlock.kubewarden.io/failure-policy"
 )
@@ -0,0 +1,23 @@
+// Package seal allows Go applications to sandbox themselves using the same
+// profiles enforced by PodLock.
+//
+// A profile is loaded with LoadProfile or LoadProfileFile, or built directly
+// using the podlockv1alpha1.Profile type. Apply enforces it on the current
+// process: the Landlock rules, the process hardening settings, the
+// environment policy and the seccomp filter defined inside of the profile are
+// applied to all the threads of the process and are inherited by all the
+// processes it starts. None of them can be lifted afterwards.
+//
+// The seccomp filter of a profile used with Apply can only deny system
+// calls: an allow list would also confine the Go runtime of the application,
+// hence it is rejected.
+//
+// # Stability
+//
+// The exported API of this package follows semantic versioning: it is not
+// changed in a backwards incompatible way within a major version of PodLock.
+// New fields can be added to Options; their zero value always preserves the
+// previous behavior. The profile type is the one of the LandlockProfile
+// resource, hence it evolves together with the PodLock API version it belongs
+// to.
+package seal
@@ -0,0 +1,42 @@
+package seal_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/flavio/podlock/pkg/seal"
+)
+
+func ExampleLoadProfile() {
+	profile, err := seal.LoadProfile(strings.NewReader(`
+readOnly:
+- /etc
+readExec:
+- /usr
+readWrite:
+- /tmp
+`))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(profile.ReadOnly, profile.ReadExec, profile.ReadWrite)
+	// Output: [/etc] [/usr] [/tmp]
+}
+
+func ExampleApply() {
+	profile, err := seal.LoadProfileFile("/etc/myapp/podlock-profile.yaml")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Grant access to the libraries linked to the current executable, then
+	// restrict the process before handling any untrusted input
+	if err = seal.Apply(context.Background(), profile, seal.Options{
+		AddLinkedLibraries: true,
+	}); err != nil {
+		log.Fatalf("cannot sandbox the process: %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package seal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/landlock-lsm/go-landlock/landlock"
+	"sigs.k8s.io/yaml"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
+)
+
+// NestingPolicy defines what Apply does when the process is already running
+// inside of a PodLock domain, e.g. because it has been started by seal.
+type NestingPolicy string
+
+const (
+	// NestingPolicyStack always applies a new Landlock domain on top of the
+	// existing one. This is the default behavior.
+	NestingPolicyStack NestingPolicy = NestingPolicy(seal.NestingPolicyStack)
+
+	// NestingPolicyFail makes Apply fail when the process is already running
+	// inside of a PodLock domain.
+	NestingPolicyFail NestingPolicy = NestingPolicy(seal.NestingPolicyFail)
+)
+
+//nolint:gochecknoglobals // errors are compared using errors.Is
+var (
+	// ErrDigestMismatch is returned when the digest of the binary does not
+	// match the one defined inside of the profile.
+	ErrDigestMismatch = seal.ErrDigestMismatch
+
+	// ErrNestingRefused is returned when the nesting policy does not allow to
+	// apply the profile inside of an existing PodLock domain.
+	ErrNestingRefused = seal.ErrNestingRefused
+
+	// ErrTooManyDomains is returned when applying the profile would exceed the
+	// maximum number of nested Landlock domains allowed by the kernel.
+	ErrTooManyDomains = seal.ErrTooManyDomains
+
+	// ErrBoundingSetNotDropped is returned when the process runs as root
+	// without CAP_SETPCAP, hence the capabilities dropped by the profile
+	// cannot be removed from its bounding set.
+	ErrBoundingSetNotDropped = seal.ErrBoundingSetNotDropped
+
+	// ErrSeccompAllowList is returned when the seccomp policy of the profile
+	// is an allow list. The filter would apply to the whole lifetime of the
+	// calling application, including the system calls of the Go runtime,
+	// hence only deny lists are supported.
+	ErrSeccompAllowList = errors.New("seccomp allow lists are not supported by Apply")
+)
+
+// Options tune how a profile is applied. The zero value is ready to use.
+type Options struct {
+	// BinaryPath is the binary the profile is applied to. It is granted read
+	// and execute access and, when the profile has a sha256 digest, it is
+	// the file being verified. Defaults to the executable of the current
+	// process.
+	BinaryPath string
+
+	// AddLinkedLibraries grants read and execute access to the shared
+	// libraries linked to BinaryPath.
+	AddLinkedLibraries bool
+
+	// NestingPolicy defaults to NestingPolicyStack.
+	NestingPolicy NestingPolicy
+
+	// RulesetCacheDir is the directory where the compiled rules are cached.
+	// The rules are computed on every call when empty.
+	RulesetCacheDir string
+
+	// Logger receives the messages about the profile being applied.
+	// Nothing is logged when nil.
+	Logger *slog.Logger
+}
+
+// LoadProfile decodes a profile written either in YAML or in JSON.
+// Unknown fields are reported as errors.
+func LoadProfile(r io.Reader) (*podlockv1alpha1.Profile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read profile: %w", err)
+	}
+
+	profile := &podlockv1alpha1.Profile{}
+	if err = yaml.UnmarshalStrict(data, profile); err != nil {
+		return nil, fmt.Errorf("cannot parse profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// LoadProfileFile decodes the profile stored inside of the given file.
+func LoadProfileFile(path string) (*podlockv1alpha1.Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read profile '%s': %w", path, err)
+	}
+
+	profile, err := LoadProfile(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("'%s': %w", path, err)
+	}
+
+	return profile, nil
+}
+
+// Rules returns the Landlock rules Apply would enforce for the given profile,
+// including the ones granting access to the binary and to its linked
+// libraries.
+func Rules(ctx context.Context, profile *podlockv1alpha1.Profile, opts Options) ([]landlock.Rule, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint, err := seal.ProfileFingerprint(profile, opts.BinaryPath, opts.AddLinkedLibraries)
+	if err != nil {
+		return nil, err
+	}
+
+	return opts.rules(ctx, profile, fingerprint)
+}
+
+// Apply enforces the profile on the current process. The restrictions apply
+// to all the threads of the process and cannot be lifted.
+//
+// The steps are performed in the same order used by seal: the binary digest
+// is verified, then the Landlock rules are enforced, the process hardening
+// settings are applied, the environment policy is applied to the environment
+// of the process and the seccomp filter is loaded last.
+//
+// Profiles with a seccomp allow list are rejected with ErrSeccompAllowList
+// before any restriction is applied.
+//
+// When Apply fails the process might be partially restricted, the caller
+// should terminate it instead of carrying on.
+func Apply(ctx context.Context, profile *podlockv1alpha1.Profile, opts Options) error {
+	if profile.Seccomp != nil && len(profile.Seccomp.Allow) > 0 {
+		return ErrSeccompAllowList
+	}
+
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return err
+	}
+
+	if profile.SHA256 != "" {
+		binary, verifyErr := seal.OpenVerifiedBinary(opts.BinaryPath, profile.SHA256)
+		if verifyErr != nil {
+			return verifyErr
+		}
+		binary.Close()
+	}
+
+	domain, err := opts.enterDomain(ctx, profile)
+	if err != nil {
+		return err
+	}
+
+	if err = seal.ApplyProcessHardening(profile, opts.Logger); err != nil {
+		return fmt.Errorf("cannot apply process hardening: %w", err)
+	}
+
+	env, removedEnv := seal.ApplyEnvironmentPolicy(os.Environ(), profile.Environment)
+	if len(removedEnv) > 0 {
+		opts.Logger.InfoContext(ctx, "environment variables removed by the profile", slog.Any("variables", removedEnv))
+	}
+	if err = replaceEnvironment(env); err != nil {
+		return err
+	}
+	if err = os.Setenv(seal.DomainEnvVar, domain.String()); err != nil {
+		return fmt.Errorf("cannot set %s: %w", seal.DomainEnvVar, err)
+	}
+
+	// The seccomp filter is loaded last, since it could deny the system calls
+	// performed by the previous steps
+	if profile.Seccomp != nil {
+		if err = seal.LoadSeccompFilter(profile.Seccomp, opts.Logger); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// withDefaults returns a copy of the options where the unset fields have
+// their default value.
+func (o Options) withDefaults() (Options, error) {
+	if o.BinaryPath == "" {
+		executable, err := os.Executable()
+		if err != nil {
+			return o, fmt.Errorf("cannot find the executable of the current process: %w", err)
+		}
+		o.BinaryPath = executable
+	}
+
+	policy, err := seal.ParseNestingPolicy(string(o.NestingPolicy))
+	if err != nil {
+		return o, err
+	}
+	o.NestingPolicy = NestingPolicy(policy)
+
+	if o.Logger == nil {
+		o.Logger = slog.New(slog.DiscardHandler)
+	}
+
+	return o, nil
+}
+
+func (o Options) rules(ctx context.Context, profile *podlockv1alpha1.Profile, fingerprint string) ([]landlock.Rule, error) {
+	var cache *seal.RulesetCache
+	if o.RulesetCacheDir != "" {
+		cache = &seal.RulesetCache{Dir: o.RulesetCacheDir}
+	}
+
+	rules, err := seal.LandlockRulesFromCache(
+		ctx,
+		cache,
+		profile,
+		o.BinaryPath,
+		o.AddLinkedLibraries,
+		fingerprint,
+		o.Logger,
+		seal.DiscoverLinkedLibraries,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not build Landlock rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// enterDomain enforces the Landlock rules of the profile, taking into account
+// the PodLock domain the process might already be running inside of.
+// It returns the domain the process is running inside of afterwards.
+func (o Options) enterDomain(ctx context.Context, profile *podlockv1alpha1.Profile) (*seal.Domain, error) {
+	current, err := seal.ParseDomain(os.Getenv(seal.DomainEnvVar))
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint, err := seal.ProfileFingerprint(profile, o.BinaryPath, o.AddLinkedLibraries)
+	if err != nil {
+		return nil, err
+	}
+
+	domain, err := seal.DecideNesting(current, fingerprint, seal.NestingPolicy(o.NestingPolicy))
+	if err != nil {
+		return nil, fmt.Errorf("cannot nest PodLock domain (depth %d, nesting policy %s): %w",
+			current.Depth, o.NestingPolicy, err)
+	}
+
+	rules, err := o.rules(ctx, profile, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = landlock.V3.RestrictPaths(rules...); err != nil {
+		return nil, fmt.Errorf("could not enable Landlock: %w", err)
+	}
+	o.Logger.InfoContext(ctx, "landlock profile applied", slog.Int("depth", domain.Depth))
+
+	return domain, nil
+}
+
+// replaceEnvironment replaces the environment of the process with the given
+// one.
+func replaceEnvironment(env []string) error {
+	os.Clearenv()
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("cannot set environment variable %s: %w", key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+package seal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	ll "github.com/landlock-lsm/go-landlock/landlock/syscall"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	podlockv1alpha1 "github.com/flavio/podlock/api/v1alpha1"
+	"github.com/flavio/podlock/internal/seal"
+)
+
+// applyChildEnvVar makes the test binary behave as the child process of
+// TestApply, the directory allowed by the profile is its value.
+const applyChildEnvVar = "PODLOCK_TEST_APPLY_CHILD"
+
+// noSetpcapChildEnvVar makes the test binary behave as the child process of
+// TestApplyWithoutSetpcap.
+const noSetpcapChildEnvVar = "PODLOCK_TEST_NO_SETPCAP_CHILD"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(noSetpcapChildEnvVar) != "" {
+		if err := noSetpcapChild(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if allowedDir := os.Getenv(applyChildEnvVar); allowedDir != "" {
+		if err := applyChild(allowedDir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestLoadProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    *podlockv1alpha1.Profile
+		wantErr bool
+	}{
+		{
+			name: "yaml",
+			input: `
+readOnly:
+- /etc
+readExec:
+- /usr/bin
+environment:
+  set:
+    FOO: bar
+`,
+			want: &podlockv1alpha1.Profile{
+				ReadOnly: []string{"/etc"},
+				ReadExec: []string{"/usr/bin"},
+				Environment: &podlockv1alpha1.EnvironmentPolicy{
+					Set: map[string]string{"FOO": "bar"},
+				},
+			},
+		},
+		{
+			name:  "json",
+			input: `{"readWrite": ["/tmp"], "sha256": "abc"}`,
+			want: &podlockv1alpha1.Profile{
+				ReadWrite: []string{"/tmp"},
+				SHA256:    "abc",
+			},
+		},
+		{
+			name:    "unknown field",
+			input:   "readOnlyPaths:\n- /etc\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid document",
+			input:   "readOnly: [",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadProfile(strings.NewReader(tt.input))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadProfileFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("readOnly:\n- /etc\n"), 0o600))
+
+	profile, err := LoadProfileFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/etc"}, profile.ReadOnly)
+
+	_, err = LoadProfileFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRules(t *testing.T) {
+	tmpDir := t.TempDir()
+	binary := filepath.Join(tmpDir, "binary")
+	require.NoError(t, os.WriteFile(binary, []byte("data"), 0o600))
+
+	profile := &podlockv1alpha1.Profile{
+		ReadOnly: []string{tmpDir},
+	}
+
+	rules, err := Rules(context.Background(), profile, Options{BinaryPath: binary})
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Contains(t, fmt.Sprint(rules[0]), tmpDir)
+	assert.Contains(t, fmt.Sprint(rules[1]), binary)
+
+	_, err = Rules(context.Background(), profile, Options{BinaryPath: binary, NestingPolicy: "unknown"})
+	require.Error(t, err)
+}
+
+func TestRulesUsesCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	profile := &podlockv1alpha1.Profile{
+		ReadOnly: []string{tmpDir},
+	}
+	opts := Options{RulesetCacheDir: cacheDir}
+
+	rules, err := Rules(context.Background(), profile, opts)
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	cachedRules, err := Rules(context.Background(), profile, opts)
+	require.NoError(t, err)
+	assert.Equal(t, rules, cachedRules)
+}
+
+func TestApplyDigestMismatch(t *testing.T) {
+	profile := &podlockv1alpha1.Profile{
+		SHA256: strings.Repeat("0", 64),
+	}
+
+	err := Apply(context.Background(), profile, Options{})
+	require.ErrorIs(t, err, ErrDigestMismatch)
+}
+
+func TestApplyNestingRefused(t *testing.T) {
+	t.Setenv(seal.DomainEnvVar, "1:stack:fingerprint")
+
+	err := Apply(context.Background(), &podlockv1alpha1.Profile{}, Options{NestingPolicy: NestingPolicyFail})
+	require.ErrorIs(t, err, ErrNestingRefused)
+}
+
+func TestApplySeccompAllowList(t *testing.T) {
+	profile := &podlockv1alpha1.Profile{
+		Seccomp: &podlockv1alpha1.Seccomp{Allow: []string{"read"}},
+	}
+
+	err := Apply(context.Background(), profile, Options{})
+	require.ErrorIs(t, err, ErrSeccompAllowList)
+}
+
+func TestApply(t *testing.T) {
+	if abi, err := ll.LandlockGetABIVersion(); err != nil || abi < 3 {
+		t.Skip("Landlock ABI v3 is not available")
+	}
+
+	allowedDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(allowedDir, "allowed"), []byte("data"), 0o600))
+
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	// The profile is applied inside of a child process, since it cannot be
+	// lifted
+	cmd := exec.Command(executable)
+	cmd.Env = append(os.Environ(), applyChildEnvVar+"="+allowedDir, "REMOVED_BY_PROFILE=1")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+// applyChild applies a profile to the current process and checks it is
+// enforced on all its threads.
+func applyChild(allowedDir string) error {
+	profile := &podlockv1alpha1.Profile{
+		ReadOnly: []string{allowedDir, "/proc"},
+		Capabilities: &podlockv1alpha1.Capabilities{
+			Drop: []string{"CAP_NET_RAW"},
+		},
+		Environment: &podlockv1alpha1.EnvironmentPolicy{
+			Deny: []string{"REMOVED_BY_PROFILE"},
+			Set:  map[string]string{"SET_BY_PROFILE": "value"},
+		},
+	}
+
+	// The restrictions are checked from a thread that exists before the
+	// profile is applied by a different one
+	runtime.LockOSThread()
+	start := make(chan struct{})
+	result := make(chan error)
+	go func() {
+		runtime.LockOSThread()
+		<-start
+		result <- checkRestricted()
+	}()
+
+	if err := Apply(context.Background(), profile, Options{}); err != nil {
+		return fmt.Errorf("cannot apply profile: %w", err)
+	}
+
+	if _, err := os.ReadFile(filepath.Join(allowedDir, "allowed")); err != nil {
+		return fmt.Errorf("cannot read allowed file: %w", err)
+	}
+
+	if os.Getenv("REMOVED_BY_PROFILE") != "" || os.Getenv("SET_BY_PROFILE") != "value" {
+		return errors.New("environment policy not applied")
+	}
+	if os.Getenv(seal.DomainEnvVar) == "" {
+		return errors.New("PodLock domain not set")
+	}
+
+	close(start)
+	return <-result
+}
+
+func checkRestricted() error {
+	if _, err := os.ReadFile("/etc/hostname"); !errors.Is(err, syscall.EACCES) {
+		return fmt.Errorf("reading a path outside of the profile: expected EACCES, got %v", err)
+	}
+
+	return checkNetRawDropped()
+}
+
+func checkNetRawDropped() error {
+	status, err := os.ReadFile("/proc/thread-self/status")
+	if err != nil {
+		return fmt.Errorf("cannot read thread status: %w", err)
+	}
+	for line := range strings.Lines(string(status)) {
+		value, found := strings.CutPrefix(line, "CapEff:")
+		if !found {
+			continue
+		}
+		capEff, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err != nil {
+			return fmt.Errorf("cannot parse effective capabilities: %w", err)
+		}
+		if capEff&(1<<13) != 0 {
+			return errors.New("CAP_NET_RAW not dropped")
+		}
+	}
+
+	return nil
+}
+
+func TestApplyWithoutSetpcap(t *testing.T) {
+	if abi, err := ll.LandlockGetABIVersion(); err != nil || abi < 3 {
+		t.Skip("Landlock ABI v3 is not available")
+	}
+
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	cmd := exec.Command(executable)
+	cmd.Env = append(os.Environ(), noSetpcapChildEnvVar+"=1")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+// noSetpcapChild applies a profile dropping capabilities to a process
+// without CAP_SETPCAP. Only the processes not running as root are allowed to
+// keep the capabilities in their bounding set.
+func noSetpcapChild() error {
+	dropSetpcap := &podlockv1alpha1.Profile{
+		Capabilities: &podlockv1alpha1.Capabilities{Drop: []string{"CAP_SETPCAP"}},
+	}
+	if err := seal.ApplyProcessHardening(dropSetpcap, slog.New(slog.DiscardHandler)); err != nil {
+		return fmt.Errorf("cannot drop CAP_SETPCAP: %w", err)
+	}
+
+	profile := &podlockv1alpha1.Profile{
+		ReadOnly: []string{"/proc"},
+		Capabilities: &podlockv1alpha1.Capabilities{
+			Drop: []string{"CAP_NET_RAW"},
+		},
+	}
+	err := Apply(context.Background(), profile, Options{})
+	if os.Geteuid() == 0 {
+		if !errors.Is(err, ErrBoundingSetNotDropped) {
+			return fmt.Errorf("applying the profile as root: expected ErrBoundingSetNotDropped, got %v", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot apply profile: %w", err)
+	}
+
+	return checkNetRawDropped()
+}